@@ -0,0 +1,137 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"time"
+
+	"go.aporeto.io/elemental"
+)
+
+// An AuditRecord describes a successful write operation.
+type AuditRecord struct {
+
+	// Time is the time the operation completed.
+	Time time.Time
+
+	// Operation is the performed operation.
+	Operation elemental.Operation
+
+	// Identity is the identity of the affected objects.
+	Identity elemental.Identity
+
+	// IDs contains the identifiers of the affected objects.
+	// It is empty for a DeleteMany.
+	IDs []string
+
+	// Namespace is the namespace of the operation, if any.
+	Namespace string
+
+	// ClientIP is the IP of the client of the operation, if any.
+	ClientIP string
+
+	// Context is the manipulate.Context given to the operation.
+	// It can be nil.
+	Context Context
+}
+
+// An AuditHook is called after a successful write operation.
+type AuditHook func(record AuditRecord)
+
+type auditedManipulator struct {
+	Manipulator
+	hook AuditHook
+}
+
+// NewAuditedManipulator returns a Manipulator that calls the given hook
+// after every successful Create, Update, Delete and DeleteMany performed
+// by the given Manipulator.
+//
+// The hook is called synchronously, and is not called if the operation
+// failed. Reads are not affected.
+func NewAuditedManipulator(manipulator Manipulator, hook AuditHook) Manipulator {
+
+	if manipulator == nil {
+		panic("manipulator must not be nil")
+	}
+
+	if hook == nil {
+		panic("hook must not be nil")
+	}
+
+	return &auditedManipulator{
+		Manipulator: manipulator,
+		hook:        hook,
+	}
+}
+
+func (m *auditedManipulator) Create(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.Manipulator.Create(mctx, object); err != nil {
+		return err
+	}
+
+	m.audit(mctx, elemental.OperationCreate, object.Identity(), object.Identifier())
+
+	return nil
+}
+
+func (m *auditedManipulator) Update(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.Manipulator.Update(mctx, object); err != nil {
+		return err
+	}
+
+	m.audit(mctx, elemental.OperationUpdate, object.Identity(), object.Identifier())
+
+	return nil
+}
+
+func (m *auditedManipulator) Delete(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.Manipulator.Delete(mctx, object); err != nil {
+		return err
+	}
+
+	m.audit(mctx, elemental.OperationDelete, object.Identity(), object.Identifier())
+
+	return nil
+}
+
+func (m *auditedManipulator) DeleteMany(mctx Context, identity elemental.Identity) error {
+
+	if err := m.Manipulator.DeleteMany(mctx, identity); err != nil {
+		return err
+	}
+
+	m.audit(mctx, elemental.OperationDelete, identity)
+
+	return nil
+}
+
+func (m *auditedManipulator) audit(mctx Context, operation elemental.Operation, identity elemental.Identity, ids ...string) {
+
+	record := AuditRecord{
+		Time:      time.Now(),
+		Operation: operation,
+		Identity:  identity,
+		IDs:       ids,
+		Context:   mctx,
+	}
+
+	if mctx != nil {
+		record.Namespace = mctx.Namespace()
+		record.ClientIP = mctx.ClientIP()
+	}
+
+	m.hook(record)
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+func TestAuditedManipulator(t *testing.T) {
+
+	Convey("Given I have an audited manipulator on a working backend", t, func() {
+
+		var records []AuditRecord
+
+		m := NewAuditedManipulator(&failingManipulator{}, func(r AuditRecord) { records = append(records, r) })
+
+		mctx := NewContext(
+			context.Background(),
+			ContextOptionNamespace("/a"),
+			ContextOptionClientIP("10.0.0.1"),
+		)
+
+		Convey("When I perform write operations", func() {
+
+			obj := testmodel.NewList()
+			obj.ID = "x"
+
+			So(m.Create(mctx, obj), ShouldBeNil)
+			So(m.Update(mctx, obj), ShouldBeNil)
+			So(m.Delete(mctx, obj), ShouldBeNil)
+			So(m.DeleteMany(mctx, testmodel.ListIdentity), ShouldBeNil)
+
+			Convey("Then the hook should be called for each operation", func() {
+				So(len(records), ShouldEqual, 4)
+
+				So(records[0].Operation, ShouldEqual, elemental.OperationCreate)
+				So(records[1].Operation, ShouldEqual, elemental.OperationUpdate)
+				So(records[2].Operation, ShouldEqual, elemental.OperationDelete)
+				So(records[3].Operation, ShouldEqual, elemental.OperationDelete)
+
+				for i, r := range records {
+					So(r.Identity, ShouldResemble, testmodel.ListIdentity)
+					So(r.Namespace, ShouldEqual, "/a")
+					So(r.ClientIP, ShouldEqual, "10.0.0.1")
+					So(r.Context, ShouldEqual, mctx)
+					So(r.Time.IsZero(), ShouldBeFalse)
+					if i < 3 {
+						So(r.IDs, ShouldResemble, []string{"x"})
+					} else {
+						So(r.IDs, ShouldBeEmpty)
+					}
+				}
+			})
+		})
+
+		Convey("When I perform write operations without context", func() {
+
+			So(m.Create(nil, testmodel.NewList()), ShouldBeNil)
+
+			Convey("Then the hook should be called without namespace", func() {
+				So(len(records), ShouldEqual, 1)
+				So(records[0].Namespace, ShouldEqual, "")
+				So(records[0].Context, ShouldBeNil)
+			})
+		})
+
+		Convey("When I perform read operations", func() {
+
+			So(m.Retrieve(mctx, testmodel.NewList()), ShouldBeNil)
+			So(m.RetrieveMany(mctx, &testmodel.ListsList{}), ShouldBeNil)
+			_, err := m.Count(mctx, testmodel.ListIdentity)
+			So(err, ShouldBeNil)
+
+			Convey("Then the hook should not be called", func() {
+				So(len(records), ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given I have an audited manipulator on a failing backend", t, func() {
+
+		var called int
+
+		fm := &failingManipulator{err: ErrConstraintViolation{Err: errors.New("nope")}}
+		m := NewAuditedManipulator(fm, func(AuditRecord) { called++ })
+
+		Convey("When I perform write operations", func() {
+
+			errCreate := m.Create(nil, testmodel.NewList())
+			errUpdate := m.Update(nil, testmodel.NewList())
+			errDelete := m.Delete(nil, testmodel.NewList())
+			errDeleteMany := m.DeleteMany(nil, testmodel.ListIdentity)
+
+			Convey("Then the errors should be returned and the hook not called", func() {
+				So(errCreate, ShouldEqual, fm.err)
+				So(errUpdate, ShouldEqual, fm.err)
+				So(errDelete, ShouldEqual, fm.err)
+				So(errDeleteMany, ShouldEqual, fm.err)
+				So(called, ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given I create an audited manipulator with invalid arguments", t, func() {
+		So(func() { NewAuditedManipulator(nil, func(AuditRecord) {}) }, ShouldPanicWith, "manipulator must not be nil")
+		So(func() { NewAuditedManipulator(&failingManipulator{}, nil) }, ShouldPanicWith, "hook must not be nil")
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"errors"
+
+	"go.aporeto.io/elemental"
+)
+
+// A BestEffortReporter is called when a best effort write
+// failed because the backend could not be reached.
+type BestEffortReporter func(operation elemental.Operation, identity elemental.Identity, err error)
+
+type bestEffortManipulator struct {
+	Manipulator
+	reporter BestEffortReporter
+}
+
+// NewBestEffortManipulator returns a Manipulator that performs the write
+// operations of the given Manipulator on a best effort basis.
+//
+// When a Create, Update, Delete or DeleteMany fails with an ErrCannotCommunicate
+// once all retries have been exhausted, the error is passed to the given
+// reporter and the operation returns nil. All other errors are returned as usual,
+// and reads are not affected.
+//
+// This means the data of such writes are silently lost. It must only be used
+// for data the application can afford to lose, like telemetry or metrics.
+func NewBestEffortManipulator(manipulator Manipulator, reporter BestEffortReporter) Manipulator {
+
+	if manipulator == nil {
+		panic("manipulator must not be nil")
+	}
+
+	if reporter == nil {
+		panic("reporter must not be nil")
+	}
+
+	return &bestEffortManipulator{
+		Manipulator: manipulator,
+		reporter:    reporter,
+	}
+}
+
+func (m *bestEffortManipulator) Create(mctx Context, object elemental.Identifiable) error {
+	return m.handle(elemental.OperationCreate, object.Identity(), m.Manipulator.Create(mctx, object))
+}
+
+func (m *bestEffortManipulator) Update(mctx Context, object elemental.Identifiable) error {
+	return m.handle(elemental.OperationUpdate, object.Identity(), m.Manipulator.Update(mctx, object))
+}
+
+func (m *bestEffortManipulator) Delete(mctx Context, object elemental.Identifiable) error {
+	return m.handle(elemental.OperationDelete, object.Identity(), m.Manipulator.Delete(mctx, object))
+}
+
+func (m *bestEffortManipulator) DeleteMany(mctx Context, identity elemental.Identity) error {
+	return m.handle(elemental.OperationDelete, identity, m.Manipulator.DeleteMany(mctx, identity))
+}
+
+func (m *bestEffortManipulator) handle(operation elemental.Operation, identity elemental.Identity, err error) error {
+
+	if err == nil || !errors.As(err, &ErrCannotCommunicate{}) {
+		return err
+	}
+
+	m.reporter(operation, identity, err)
+
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+type failingManipulator struct {
+	err error
+}
+
+func (m *failingManipulator) RetrieveMany(Context, elemental.Identifiables) error { return m.err }
+func (m *failingManipulator) Retrieve(Context, elemental.Identifiable) error      { return m.err }
+func (m *failingManipulator) Create(Context, elemental.Identifiable) error        { return m.err }
+func (m *failingManipulator) Update(Context, elemental.Identifiable) error        { return m.err }
+func (m *failingManipulator) Delete(Context, elemental.Identifiable) error        { return m.err }
+func (m *failingManipulator) DeleteMany(Context, elemental.Identity) error        { return m.err }
+func (m *failingManipulator) Count(Context, elemental.Identity) (int, error)      { return 0, m.err }
+
+func TestBestEffortManipulator(t *testing.T) {
+
+	Convey("Given I have a best effort manipulator on a backend that cannot be reached", t, func() {
+
+		var reported []error
+		var operations []elemental.Operation
+
+		fm := &failingManipulator{err: fmt.Errorf("after retries: %w", ErrCannotCommunicate{Err: errors.New("boom")})}
+		m := NewBestEffortManipulator(fm, func(op elemental.Operation, identity elemental.Identity, err error) {
+			So(identity, ShouldResemble, testmodel.ListIdentity)
+			operations = append(operations, op)
+			reported = append(reported, err)
+		})
+
+		Convey("When I perform write operations", func() {
+
+			errCreate := m.Create(nil, testmodel.NewList())
+			errUpdate := m.Update(nil, testmodel.NewList())
+			errDelete := m.Delete(nil, testmodel.NewList())
+			errDeleteMany := m.DeleteMany(nil, testmodel.ListIdentity)
+
+			Convey("Then the errors should be swallowed", func() {
+				So(errCreate, ShouldBeNil)
+				So(errUpdate, ShouldBeNil)
+				So(errDelete, ShouldBeNil)
+				So(errDeleteMany, ShouldBeNil)
+			})
+
+			Convey("Then the errors should be reported", func() {
+				So(len(reported), ShouldEqual, 4)
+				So(reported[0], ShouldEqual, fm.err)
+				So(operations, ShouldResemble, []elemental.Operation{
+					elemental.OperationCreate,
+					elemental.OperationUpdate,
+					elemental.OperationDelete,
+					elemental.OperationDelete,
+				})
+			})
+		})
+
+		Convey("When I perform read operations", func() {
+
+			errRetrieve := m.Retrieve(nil, testmodel.NewList())
+			errRetrieveMany := m.RetrieveMany(nil, &testmodel.ListsList{})
+			_, errCount := m.Count(nil, testmodel.ListIdentity)
+
+			Convey("Then the errors should be returned", func() {
+				So(errRetrieve, ShouldEqual, fm.err)
+				So(errRetrieveMany, ShouldEqual, fm.err)
+				So(errCount, ShouldEqual, fm.err)
+				So(len(reported), ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given I have a best effort manipulator on a backend returning other errors", t, func() {
+
+		var reported int
+
+		fm := &failingManipulator{err: ErrConstraintViolation{Err: errors.New("nope")}}
+		m := NewBestEffortManipulator(fm, func(elemental.Operation, elemental.Identity, error) { reported++ })
+
+		Convey("When I create an object", func() {
+
+			err := m.Create(nil, testmodel.NewList())
+
+			Convey("Then the error should be returned", func() {
+				So(err, ShouldEqual, fm.err)
+				So(reported, ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given I create a best effort manipulator with invalid arguments", t, func() {
+		So(func() { NewBestEffortManipulator(nil, func(elemental.Operation, elemental.Identity, error) {}) }, ShouldPanicWith, "manipulator must not be nil")
+		So(func() { NewBestEffortManipulator(&failingManipulator{}, nil) }, ShouldPanicWith, "reporter must not be nil")
+	})
+}
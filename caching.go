@@ -0,0 +1,339 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/copystructure"
+	"go.aporeto.io/elemental"
+)
+
+const defaultCachingMaxEntries = 1024
+
+type cachingConfig struct {
+	maxEntries int
+}
+
+// A CachingOption can be given to NewCachingManipulator to alter its behavior.
+type CachingOption func(*cachingConfig)
+
+// CachingOptionMaxEntries sets the maximum number of results the
+// cache holds. When full, the least recently used result is evicted.
+// The default is 1024.
+func CachingOptionMaxEntries(n int) CachingOption {
+
+	if n <= 0 {
+		panic("max entries must be positive")
+	}
+
+	return func(c *cachingConfig) {
+		c.maxEntries = n
+	}
+}
+
+type cacheEntry struct {
+	key      string
+	identity string
+	value    interface{}
+	next     string
+	count    int
+	expireAt time.Time
+}
+
+type cachingManipulator struct {
+	Manipulator
+	ttl time.Duration
+	cfg cachingConfig
+
+	sync.Mutex
+	entries     map[string]*list.Element
+	lru         *list.List
+	generations map[string]uint64
+}
+
+// NewCachingManipulator returns a Manipulator that caches the results
+// of Retrieve and RetrieveMany for the given ttl.
+//
+// Results are cached per identity, identifier, query parameters (namespace,
+// filter, pagination, ordering, fields, parent etc), version and credentials,
+// so callers using different credentials never share results. Every Create, Update,
+// Delete and DeleteMany invalidates all the cached results of the affected
+// identity, whether it succeeded or not. A context using ContextOptionBypassCache
+// always reaches the given Manipulator, and refreshes the cache with the result.
+//
+// Only writes going through the returned Manipulator invalidate the cache: changes
+// made by other clients of the backend are only seen once the results expire.
+func NewCachingManipulator(manipulator Manipulator, ttl time.Duration, options ...CachingOption) Manipulator {
+
+	if manipulator == nil {
+		panic("manipulator must not be nil")
+	}
+
+	if ttl <= 0 {
+		panic("ttl must be positive")
+	}
+
+	cfg := cachingConfig{
+		maxEntries: defaultCachingMaxEntries,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	return &cachingManipulator{
+		Manipulator: manipulator,
+		ttl:         ttl,
+		cfg:         cfg,
+		entries:     map[string]*list.Element{},
+		lru:         list.New(),
+		generations: map[string]uint64{},
+	}
+}
+
+func (m *cachingManipulator) RetrieveMany(mctx Context, dest elemental.Identifiables) error {
+
+	identity := dest.Identity()
+	key := cacheKey(mctx, identity, "")
+
+	if mctx == nil || !mctx.BypassCache() {
+		if e, ok := m.get(key); ok {
+			if err := restoreCached(dest, e.value); err == nil {
+				if mctx != nil {
+					if e.next != "" {
+						mctx.SetNext(e.next)
+					}
+					mctx.SetCount(e.count)
+				}
+				return nil
+			}
+		}
+	}
+
+	gen := m.generation(identity)
+
+	if err := m.Manipulator.RetrieveMany(mctx, dest); err != nil {
+		return err
+	}
+
+	var next string
+	var count int
+	if mctx != nil {
+		next = mctx.Next()
+		count = mctx.Count()
+	}
+
+	m.set(key, identity, gen, dest, next, count)
+
+	return nil
+}
+
+func (m *cachingManipulator) Retrieve(mctx Context, object elemental.Identifiable) error {
+
+	identity := object.Identity()
+	key := cacheKey(mctx, identity, object.Identifier())
+
+	if mctx == nil || !mctx.BypassCache() {
+		if e, ok := m.get(key); ok {
+			if err := restoreCached(object, e.value); err == nil {
+				return nil
+			}
+		}
+	}
+
+	gen := m.generation(identity)
+
+	if err := m.Manipulator.Retrieve(mctx, object); err != nil {
+		return err
+	}
+
+	m.set(key, identity, gen, object, "", 0)
+
+	return nil
+}
+
+func (m *cachingManipulator) Create(mctx Context, object elemental.Identifiable) error {
+	defer m.invalidate(object.Identity())
+	return m.Manipulator.Create(mctx, object)
+}
+
+func (m *cachingManipulator) Update(mctx Context, object elemental.Identifiable) error {
+	defer m.invalidate(object.Identity())
+	return m.Manipulator.Update(mctx, object)
+}
+
+func (m *cachingManipulator) Delete(mctx Context, object elemental.Identifiable) error {
+	defer m.invalidate(object.Identity())
+	return m.Manipulator.Delete(mctx, object)
+}
+
+func (m *cachingManipulator) DeleteMany(mctx Context, identity elemental.Identity) error {
+	defer m.invalidate(identity)
+	return m.Manipulator.DeleteMany(mctx, identity)
+}
+
+func (m *cachingManipulator) get(key string) (*cacheEntry, bool) {
+
+	m.Lock()
+	defer m.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := elem.Value.(*cacheEntry)
+	if time.Now().After(e.expireAt) {
+		m.remove(elem)
+		return nil, false
+	}
+
+	m.lru.MoveToFront(elem)
+
+	return e, true
+}
+
+func (m *cachingManipulator) set(key string, identity elemental.Identity, gen uint64, value interface{}, next string, count int) {
+
+	copied, err := copystructure.Copy(value)
+	if err != nil {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	// A write happened while we were reading, so
+	// the value may already be stale.
+	if m.generations[identity.Name] != gen {
+		return
+	}
+
+	if elem, ok := m.entries[key]; ok {
+		m.remove(elem)
+	}
+
+	m.entries[key] = m.lru.PushFront(&cacheEntry{
+		key:      key,
+		identity: identity.Name,
+		value:    copied,
+		next:     next,
+		count:    count,
+		expireAt: time.Now().Add(m.ttl),
+	})
+
+	for m.lru.Len() > m.cfg.maxEntries {
+		m.remove(m.lru.Back())
+	}
+}
+
+func (m *cachingManipulator) generation(identity elemental.Identity) uint64 {
+
+	m.Lock()
+	defer m.Unlock()
+
+	return m.generations[identity.Name]
+}
+
+func (m *cachingManipulator) invalidate(identity elemental.Identity) {
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.generations[identity.Name]++
+
+	for elem := m.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*cacheEntry).identity == identity.Name {
+			m.remove(elem)
+		}
+		elem = next
+	}
+}
+
+func (m *cachingManipulator) remove(elem *list.Element) {
+	delete(m.entries, elem.Value.(*cacheEntry).key)
+	m.lru.Remove(elem)
+}
+
+// restoreCached copies the cached value into dest.
+func restoreCached(dest interface{}, cached interface{}) error {
+
+	copied, err := copystructure.Copy(cached)
+	if err != nil {
+		return err
+	}
+
+	dv := reflect.ValueOf(dest)
+	cv := reflect.ValueOf(copied)
+	if dv.Kind() != reflect.Ptr || cv.Type() != dv.Type() {
+		return fmt.Errorf("cannot restore %T into %T", cached, dest)
+	}
+
+	dv.Elem().Set(cv.Elem())
+
+	return nil
+}
+
+// cacheKey returns the key identifying the result of a
+// retrieve operation for the given context.
+func cacheKey(mctx Context, identity elemental.Identity, id string) string {
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s|%s", identity.Name, id)
+
+	if mctx == nil {
+		return b.String()
+	}
+
+	// The credentials are hashed so they
+	// never appear in clear in the keys.
+	username, password := mctx.Credentials()
+	credentials := sha256.Sum256([]byte(username + "\x00" + password))
+
+	fmt.Fprintf(
+		&b,
+		"|%x|%d|%s|%t|%d|%d|%s|%d|%s|%s|%s|%s|%s",
+		credentials,
+		mctx.Version(),
+		mctx.Namespace(),
+		mctx.Recursive(),
+		mctx.Page(),
+		mctx.PageSize(),
+		mctx.After(),
+		mctx.Limit(),
+		strings.Join(mctx.Order(), ","),
+		strings.Join(mctx.Fields(), ","),
+		mctx.ReadConsistency(),
+		mctx.Parameters().Encode(),
+		mctx.TransactionID(),
+	)
+
+	// Filters having the same meaning must share the same entries,
+	// whatever the order in which they have been built.
+	if f := CanonicalFilterString(mctx.Filter()); f != "" {
+		fmt.Fprintf(&b, "|%s", f)
+	}
+
+	if p := mctx.Parent(); p != nil {
+		fmt.Fprintf(&b, "|%s/%s", p.Identity().Name, p.Identifier())
+	}
+
+	return b.String()
+}
@@ -0,0 +1,301 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+type countingManipulator struct {
+	failingManipulator
+
+	sync.Mutex
+	reads int
+}
+
+func (m *countingManipulator) read() int {
+	m.Lock()
+	defer m.Unlock()
+	m.reads++
+	return m.reads
+}
+
+func (m *countingManipulator) count() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.reads
+}
+
+func (m *countingManipulator) Retrieve(_ Context, object elemental.Identifiable) error {
+	object.(*testmodel.List).Name = fmt.Sprintf("read-%d", m.read())
+	return nil
+}
+
+func (m *countingManipulator) RetrieveMany(mctx Context, dest elemental.Identifiables) error {
+	*(dest.(*testmodel.ListsList)) = testmodel.ListsList{
+		&testmodel.List{ID: "1", Name: fmt.Sprintf("read-%d", m.read())},
+	}
+	if mctx != nil {
+		mctx.SetNext("1")
+		mctx.SetCount(1)
+	}
+	return nil
+}
+
+func TestNewCachingManipulator(t *testing.T) {
+
+	Convey("Calling NewCachingManipulator with invalid arguments should panic", t, func() {
+		So(func() { NewCachingManipulator(nil, time.Second) }, ShouldPanicWith, "manipulator must not be nil")
+		So(func() { NewCachingManipulator(&countingManipulator{}, 0) }, ShouldPanicWith, "ttl must be positive")
+		So(func() { CachingOptionMaxEntries(0) }, ShouldPanicWith, "max entries must be positive")
+	})
+}
+
+func TestCachingManipulator(t *testing.T) {
+
+	Convey("Given I have a caching manipulator", t, func() {
+
+		cm := &countingManipulator{}
+		m := NewCachingManipulator(cm, time.Hour)
+
+		Convey("When I retrieve the same object twice", func() {
+
+			o1 := &testmodel.List{ID: "1"}
+			o2 := &testmodel.List{ID: "1"}
+			err1 := m.Retrieve(nil, o1)
+			err2 := m.Retrieve(nil, o2)
+
+			Convey("Then the second retrieve should be a hit", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldBeNil)
+				So(o1.Name, ShouldEqual, "read-1")
+				So(o2.Name, ShouldEqual, "read-1")
+				So(cm.count(), ShouldEqual, 1)
+			})
+
+			Convey("Then modifying the result should not modify the cache", func() {
+				o1.Name = "modified"
+				o3 := &testmodel.List{ID: "1"}
+				So(m.Retrieve(nil, o3), ShouldBeNil)
+				So(o3.Name, ShouldEqual, "read-1")
+			})
+		})
+
+		Convey("When I retrieve different objects", func() {
+
+			o1 := &testmodel.List{ID: "1"}
+			o2 := &testmodel.List{ID: "2"}
+			So(m.Retrieve(nil, o1), ShouldBeNil)
+			So(m.Retrieve(nil, o2), ShouldBeNil)
+
+			Convey("Then both should be misses", func() {
+				So(o2.Name, ShouldEqual, "read-2")
+				So(cm.count(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I retrieve many with different filters", func() {
+
+			f1 := elemental.NewFilterComposer().WithKey("name").Equals("a").Done()
+			f2 := elemental.NewFilterComposer().WithKey("name").Equals("b").Done()
+
+			l1 := testmodel.ListsList{}
+			l2 := testmodel.ListsList{}
+			l3 := testmodel.ListsList{}
+			mctx := NewContext(context.Background(), ContextOptionFilter(f1))
+			So(m.RetrieveMany(mctx, &l1), ShouldBeNil)
+			So(m.RetrieveMany(NewContext(context.Background(), ContextOptionFilter(f1)), &l2), ShouldBeNil)
+			So(m.RetrieveMany(NewContext(context.Background(), ContextOptionFilter(f2)), &l3), ShouldBeNil)
+
+			Convey("Then only the same filter should be a hit", func() {
+				So(l1[0].Name, ShouldEqual, "read-1")
+				So(l2[0].Name, ShouldEqual, "read-1")
+				So(l3[0].Name, ShouldEqual, "read-2")
+				So(cm.count(), ShouldEqual, 2)
+			})
+
+			Convey("Then the next marker and the count should be restored", func() {
+				mctx := NewContext(context.Background(), ContextOptionFilter(f1))
+				So(m.RetrieveMany(mctx, &testmodel.ListsList{}), ShouldBeNil)
+				So(mctx.Next(), ShouldEqual, "1")
+				So(mctx.Count(), ShouldEqual, 1)
+				So(cm.count(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I retrieve many with equivalent filters built in different orders", func() {
+
+			f1 := elemental.NewFilterComposer().WithKey("name").Equals("a").WithKey("tags").In("x", "y").Done()
+			f2 := elemental.NewFilterComposer().WithKey("tags").In("y", "x").WithKey("name").Equals("a").Done()
+
+			l1 := testmodel.ListsList{}
+			l2 := testmodel.ListsList{}
+			So(m.RetrieveMany(NewContext(context.Background(), ContextOptionFilter(f1)), &l1), ShouldBeNil)
+			So(m.RetrieveMany(NewContext(context.Background(), ContextOptionFilter(f2)), &l2), ShouldBeNil)
+
+			Convey("Then the second should be a hit", func() {
+				So(l2[0].Name, ShouldEqual, l1[0].Name)
+				So(cm.count(), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I retrieve the same object with different credentials", func() {
+
+			o1 := &testmodel.List{ID: "1"}
+			o2 := &testmodel.List{ID: "1"}
+			o3 := &testmodel.List{ID: "1"}
+			So(m.Retrieve(NewContext(context.Background(), ContextOptionCredentials("alice", "a")), o1), ShouldBeNil)
+			So(m.Retrieve(NewContext(context.Background(), ContextOptionCredentials("bob", "b")), o2), ShouldBeNil)
+			So(m.Retrieve(NewContext(context.Background(), ContextOptionCredentials("alice", "a")), o3), ShouldBeNil)
+
+			Convey("Then only the same credentials should be a hit", func() {
+				So(o1.Name, ShouldEqual, "read-1")
+				So(o2.Name, ShouldEqual, "read-2")
+				So(o3.Name, ShouldEqual, "read-1")
+				So(cm.count(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I retrieve the same object with different versions", func() {
+
+			o1 := &testmodel.List{ID: "1"}
+			o2 := &testmodel.List{ID: "1"}
+			So(m.Retrieve(NewContext(context.Background(), ContextOptionVersion(1)), o1), ShouldBeNil)
+			So(m.Retrieve(NewContext(context.Background(), ContextOptionVersion(2)), o2), ShouldBeNil)
+
+			Convey("Then both should be misses", func() {
+				So(o2.Name, ShouldEqual, "read-2")
+				So(cm.count(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I write an object of the cached identity", func() {
+
+			So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+			So(m.RetrieveMany(nil, &testmodel.ListsList{}), ShouldBeNil)
+
+			So(m.Update(nil, &testmodel.List{ID: "2"}), ShouldBeNil)
+
+			o := &testmodel.List{ID: "1"}
+			l := testmodel.ListsList{}
+			So(m.Retrieve(nil, o), ShouldBeNil)
+			So(m.RetrieveMany(nil, &l), ShouldBeNil)
+
+			Convey("Then the cache should have been invalidated", func() {
+				So(o.Name, ShouldEqual, "read-3")
+				So(l[0].Name, ShouldEqual, "read-4")
+				So(cm.count(), ShouldEqual, 4)
+			})
+		})
+
+		Convey("When I write an object of another identity", func() {
+
+			So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+			So(m.Delete(nil, testmodel.NewTask()), ShouldBeNil)
+			So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+
+			Convey("Then the cache should still be used", func() {
+				So(cm.count(), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I retrieve with a context bypassing the cache", func() {
+
+			So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+			o := &testmodel.List{ID: "1"}
+			So(m.Retrieve(NewContext(context.Background(), ContextOptionBypassCache(true)), o), ShouldBeNil)
+
+			Convey("Then the backend should be used and the cache refreshed", func() {
+				So(o.Name, ShouldEqual, "read-2")
+				o2 := &testmodel.List{ID: "1"}
+				So(m.Retrieve(nil, o2), ShouldBeNil)
+				So(o2.Name, ShouldEqual, "read-2")
+				So(cm.count(), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given I have a caching manipulator with a short ttl", t, func() {
+
+		cm := &countingManipulator{}
+		m := NewCachingManipulator(cm, 10*time.Millisecond)
+
+		Convey("When I retrieve the same object after the ttl", func() {
+
+			So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+			time.Sleep(20 * time.Millisecond)
+			So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+
+			Convey("Then the second retrieve should be a miss", func() {
+				So(cm.count(), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given I have a caching manipulator with a small size", t, func() {
+
+		cm := &countingManipulator{}
+		m := NewCachingManipulator(cm, time.Hour, CachingOptionMaxEntries(2))
+
+		Convey("When I retrieve more objects than it can hold", func() {
+
+			So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+			So(m.Retrieve(nil, &testmodel.List{ID: "2"}), ShouldBeNil)
+			So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+			So(m.Retrieve(nil, &testmodel.List{ID: "3"}), ShouldBeNil)
+
+			Convey("Then the least recently used should have been evicted", func() {
+				So(m.Retrieve(nil, &testmodel.List{ID: "1"}), ShouldBeNil)
+				So(cm.count(), ShouldEqual, 3)
+				So(m.Retrieve(nil, &testmodel.List{ID: "2"}), ShouldBeNil)
+				So(cm.count(), ShouldEqual, 4)
+			})
+		})
+	})
+
+	Convey("Given I have a caching manipulator used concurrently", t, func() {
+
+		cm := &countingManipulator{}
+		m := NewCachingManipulator(cm, time.Hour, CachingOptionMaxEntries(8))
+
+		Convey("When many goroutines read and write", func() {
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 50; j++ {
+						_ = m.Retrieve(nil, &testmodel.List{ID: fmt.Sprintf("%d", j%10)})
+						_ = m.RetrieveMany(nil, &testmodel.ListsList{})
+						if j%10 == i%10 {
+							_ = m.Update(nil, &testmodel.List{ID: "x"})
+						}
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("Then nothing should have raced", func() {
+				So(cm.count(), ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mitchellh/copystructure"
+	"go.aporeto.io/elemental"
+)
+
+type compositeConfig struct {
+	writeBoth bool
+}
+
+// A CompositeOption can be given to NewCompositeManipulator to alter its behavior.
+type CompositeOption func(*compositeConfig)
+
+// CompositeOptionWriteBoth makes the write operations go to the
+// secondary manipulator too, once they succeeded on the primary.
+func CompositeOptionWriteBoth() CompositeOption {
+	return func(c *compositeConfig) {
+		c.writeBoth = true
+	}
+}
+
+type compositeManipulator struct {
+	primary   Manipulator
+	secondary Manipulator
+	cfg       compositeConfig
+}
+
+// NewCompositeManipulator returns a Manipulator that performs the read operations
+// against the primary manipulator, and falls back to the secondary one when the
+// primary fails with an ErrCannotCommunicate. This is useful to serve reads
+// from a cache when the main backend is unreachable.
+//
+// Writes are only applied to the primary, unless CompositeOptionWriteBoth is
+// given. In that case, they are applied to the secondary once they succeeded on
+// the primary, and the error of the secondary is returned. The secondary is
+// given a copy of the object, so the caller keeps what the primary returned.
+func NewCompositeManipulator(primary Manipulator, secondary Manipulator, options ...CompositeOption) Manipulator {
+
+	if primary == nil {
+		panic("primary must not be nil")
+	}
+
+	if secondary == nil {
+		panic("secondary must not be nil")
+	}
+
+	cfg := compositeConfig{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	return &compositeManipulator{
+		primary:   primary,
+		secondary: secondary,
+		cfg:       cfg,
+	}
+}
+
+func (m *compositeManipulator) RetrieveMany(mctx Context, dest elemental.Identifiables) error {
+
+	err := m.primary.RetrieveMany(mctx, dest)
+	if !shouldFallback(err) {
+		return err
+	}
+
+	return m.secondary.RetrieveMany(mctx, dest)
+}
+
+func (m *compositeManipulator) Retrieve(mctx Context, object elemental.Identifiable) error {
+
+	err := m.primary.Retrieve(mctx, object)
+	if !shouldFallback(err) {
+		return err
+	}
+
+	return m.secondary.Retrieve(mctx, object)
+}
+
+func (m *compositeManipulator) Count(mctx Context, identity elemental.Identity) (int, error) {
+
+	n, err := m.primary.Count(mctx, identity)
+	if !shouldFallback(err) {
+		return n, err
+	}
+
+	return m.secondary.Count(mctx, identity)
+}
+
+func (m *compositeManipulator) Create(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.primary.Create(mctx, object); err != nil || !m.cfg.writeBoth {
+		return err
+	}
+
+	copied, err := copyIdentifiable(object)
+	if err != nil {
+		return err
+	}
+
+	return m.secondary.Create(mctx, copied)
+}
+
+func (m *compositeManipulator) Update(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.primary.Update(mctx, object); err != nil || !m.cfg.writeBoth {
+		return err
+	}
+
+	copied, err := copyIdentifiable(object)
+	if err != nil {
+		return err
+	}
+
+	return m.secondary.Update(mctx, copied)
+}
+
+func (m *compositeManipulator) Delete(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.primary.Delete(mctx, object); err != nil || !m.cfg.writeBoth {
+		return err
+	}
+
+	copied, err := copyIdentifiable(object)
+	if err != nil {
+		return err
+	}
+
+	return m.secondary.Delete(mctx, copied)
+}
+
+func (m *compositeManipulator) DeleteMany(mctx Context, identity elemental.Identity) error {
+
+	if err := m.primary.DeleteMany(mctx, identity); err != nil || !m.cfg.writeBoth {
+		return err
+	}
+
+	return m.secondary.DeleteMany(mctx, identity)
+}
+
+// copyIdentifiable returns a deep copy of the given object.
+func copyIdentifiable(object elemental.Identifiable) (elemental.Identifiable, error) {
+
+	if object == nil {
+		return nil, nil
+	}
+
+	copied, err := copystructure.Copy(object)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy object: %w", err)
+	}
+
+	return copied.(elemental.Identifiable), nil
+}
+
+func shouldFallback(err error) bool {
+	return err != nil && errors.As(err, &ErrCannotCommunicate{})
+}
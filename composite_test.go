@@ -0,0 +1,138 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+func TestNewCompositeManipulator(t *testing.T) {
+
+	Convey("Calling NewCompositeManipulator with invalid arguments should panic", t, func() {
+		So(func() { NewCompositeManipulator(nil, &failingManipulator{}) }, ShouldPanicWith, "primary must not be nil")
+		So(func() { NewCompositeManipulator(&failingManipulator{}, nil) }, ShouldPanicWith, "secondary must not be nil")
+	})
+}
+
+func TestCompositeManipulator(t *testing.T) {
+
+	Convey("Given I have a composite manipulator with an unreachable primary", t, func() {
+
+		primary := newRecordingManipulator(fmt.Errorf("after retries: %w", ErrCannotCommunicate{Err: errors.New("boom")}))
+		secondary := newRecordingManipulator(nil)
+		m := NewCompositeManipulator(primary, secondary)
+
+		Convey("When I perform read operations", func() {
+
+			errRetrieve := m.Retrieve(nil, testmodel.NewList())
+			errRetrieveMany := m.RetrieveMany(nil, &testmodel.ListsList{})
+			_, errCount := m.Count(nil, testmodel.ListIdentity)
+
+			Convey("Then they should be served by the secondary", func() {
+				So(errRetrieve, ShouldBeNil)
+				So(errRetrieveMany, ShouldBeNil)
+				So(errCount, ShouldBeNil)
+			})
+		})
+
+		Convey("When I perform write operations", func() {
+
+			err := m.Create(nil, testmodel.NewList())
+
+			Convey("Then the error should be returned", func() {
+				So(err, ShouldEqual, primary.err)
+			})
+
+			Convey("Then the secondary should not have been written", func() {
+				So(primary.operations(), ShouldResemble, []elemental.Operation{elemental.OperationCreate})
+				So(secondary.operations(), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given I have a composite manipulator with a primary returning other errors", t, func() {
+
+		primary := newRecordingManipulator(ErrObjectNotFound{Err: errors.New("nope")})
+		secondary := newRecordingManipulator(errors.New("should not be used"))
+		m := NewCompositeManipulator(primary, secondary)
+
+		Convey("When I retrieve an object", func() {
+
+			err := m.Retrieve(nil, testmodel.NewList())
+
+			Convey("Then the error of the primary should be returned", func() {
+				So(err, ShouldEqual, primary.err)
+			})
+		})
+	})
+
+	Convey("Given I have a composite manipulator writing to both", t, func() {
+
+		primary := newRecordingManipulator(nil)
+		secondary := newRecordingManipulator(nil)
+		m := NewCompositeManipulator(primary, secondary, CompositeOptionWriteBoth())
+
+		Convey("When I perform write operations", func() {
+
+			So(m.Create(nil, testmodel.NewList()), ShouldBeNil)
+			So(m.Update(nil, testmodel.NewList()), ShouldBeNil)
+			So(m.Delete(nil, testmodel.NewList()), ShouldBeNil)
+			So(m.DeleteMany(nil, testmodel.ListIdentity), ShouldBeNil)
+
+			Convey("Then both should have been written", func() {
+				expected := []elemental.Operation{
+					elemental.OperationCreate,
+					elemental.OperationUpdate,
+					elemental.OperationDelete,
+					elemental.OperationDelete,
+				}
+				So(primary.operations(), ShouldResemble, expected)
+				So(secondary.operations(), ShouldResemble, expected)
+			})
+		})
+
+		Convey("When a write fails on the primary", func() {
+
+			primary.err = errors.New("boom")
+			err := m.Update(nil, testmodel.NewList())
+
+			Convey("Then the secondary should not have been written", func() {
+				So(err, ShouldEqual, primary.err)
+				So(secondary.operations(), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given I have a composite manipulator writing to both with an identifying secondary", t, func() {
+
+		primary := &identifyingManipulator{id: "primary"}
+		secondary := &identifyingManipulator{id: "secondary"}
+		m := NewCompositeManipulator(primary, secondary, CompositeOptionWriteBoth())
+
+		Convey("When I create an object", func() {
+
+			list := testmodel.NewList()
+			err := m.Create(nil, list)
+
+			Convey("Then the object should keep the identifier of the primary", func() {
+				So(err, ShouldBeNil)
+				So(list.ID, ShouldEqual, "primary")
+			})
+		})
+	})
+}
@@ -94,6 +94,7 @@ type Context interface {
 	Order() []string
 	Context() context.Context
 	Derive(...ContextOption) Context
+	Clone() Context
 	Fields() []string
 	ReadConsistency() ReadConsistency
 	WriteConsistency() WriteConsistency
@@ -102,11 +103,13 @@ type Context interface {
 	ClientIP() string
 	RetryFunc() RetryFunc
 	RetryRatio() int64
+	BypassCache() bool
 
 	fmt.Stringer
 }
 
 type mcontext struct {
+	bypassCache          bool
 	clientIP             string
 	countTotal           int
 	createFinalizer      FinalizerFunc
@@ -140,6 +143,10 @@ type mcontext struct {
 }
 
 // NewContext creates a context with the given ContextOption.
+// The options are applied in order, so a fully configured context
+// can be built in one call:
+//
+//	NewContext(ctx, ContextOptionFilter(f), ContextOptionPage(1, 50))
 func NewContext(ctx context.Context, options ...ContextOption) Context {
 
 	if ctx == nil {
@@ -163,7 +170,8 @@ func NewContext(ctx context.Context, options ...ContextOption) Context {
 
 // Derive creates a copy of the context but updates the values of the given options.
 // Values that are parts of a response like Count or Messages or IdempotencyKey
-// are reset for the derived context.
+// are reset for the derived context. The filter is deep copied so the
+// derived context can modify it without affecting the original.
 func (c *mcontext) Derive(options ...ContextOption) Context {
 
 	var opaqueCopy map[string]interface{}
@@ -183,13 +191,14 @@ func (c *mcontext) Derive(options ...ContextOption) Context {
 	}
 
 	copy := &mcontext{
+		bypassCache:          c.bypassCache,
 		clientIP:             c.clientIP,
 		createFinalizer:      c.createFinalizer,
 		ctx:                  c.ctx,
 		externalTrackingID:   c.externalTrackingID,
 		externalTrackingType: c.externalTrackingType,
 		fields:               append([]string{}, c.fields...),
-		filter:               c.filter,
+		filter:               CopyFilter(c.filter),
 		namespace:            c.namespace,
 		order:                append([]string{}, c.order...),
 		overrideProtection:   c.overrideProtection,
@@ -218,6 +227,64 @@ func (c *mcontext) Derive(options ...ContextOption) Context {
 	return copy
 }
 
+// Clone returns a fully independent copy of the context.
+// Unlike Derive, the values that are part of a response, like Count,
+// Messages, Next or IdempotencyKey, are kept. The filter, the slices and the
+// maps are deep copied so mutating the clone never affects the original.
+// The parent, the opaque values and the functions are shared.
+func (c *mcontext) Clone() Context {
+
+	var opaqueCopy map[string]interface{}
+	if c.opaque != nil {
+		opaqueCopy = make(map[string]interface{}, len(c.opaque))
+		for k, v := range c.opaque {
+			opaqueCopy[k] = v
+		}
+	}
+
+	var paramsCopy url.Values
+	if c.parameters != nil {
+		paramsCopy = make(url.Values, len(c.parameters))
+		for k, v := range c.parameters {
+			paramsCopy[k] = append([]string(nil), v...)
+		}
+	}
+
+	return &mcontext{
+		bypassCache:          c.bypassCache,
+		clientIP:             c.clientIP,
+		countTotal:           c.countTotal,
+		createFinalizer:      c.createFinalizer,
+		ctx:                  c.ctx,
+		externalTrackingID:   c.externalTrackingID,
+		externalTrackingType: c.externalTrackingType,
+		fields:               append([]string(nil), c.fields...),
+		filter:               CopyFilter(c.filter),
+		idempotencyKey:       c.idempotencyKey,
+		messages:             append([]string(nil), c.messages...),
+		namespace:            c.namespace,
+		order:                append([]string(nil), c.order...),
+		overrideProtection:   c.overrideProtection,
+		page:                 c.page,
+		pageSize:             c.pageSize,
+		after:                c.after,
+		limit:                c.limit,
+		next:                 c.next,
+		parameters:           paramsCopy,
+		parent:               c.parent,
+		password:             c.password,
+		readConsistency:      c.readConsistency,
+		recursive:            c.recursive,
+		retryFunc:            c.retryFunc,
+		retryRatio:           c.retryRatio,
+		transactionID:        c.transactionID,
+		username:             c.username,
+		version:              c.version,
+		writeConsistency:     c.writeConsistency,
+		opaque:               opaqueCopy,
+	}
+}
+
 // Count returns the count
 func (c *mcontext) Count() int { return c.countTotal }
 
@@ -318,6 +385,10 @@ func (c *mcontext) RetryRatio() int64 { return c.retryRatio }
 // returned by the manipulate operation.
 func (c *mcontext) RetryFunc() RetryFunc { return c.retryFunc }
 
+// BypassCache returns true if the operation should not be
+// served from a cache.
+func (c *mcontext) BypassCache() bool { return c.bypassCache }
+
 // Opaque returns the context opaque data.
 func (c *mcontext) Opaque() map[string]interface{} { return c.opaque }
 
@@ -332,3 +403,27 @@ func (c *mcontext) String() string {
 
 	return fmt.Sprintf("<Context page:%d pagesize:%d filter:%v version:%d>", c.page, c.pageSize, c.filter, c.version)
 }
+
+// ValidatePagination checks that the pagination parameters of the given
+// Context are consistent. A page requires a positive page size, and neither
+// can be negative. A page size without a page is valid and designates the
+// first page. It returns an ErrInvalidQuery if the parameters are invalid.
+func ValidatePagination(mctx Context) error {
+
+	if mctx == nil {
+		return nil
+	}
+
+	page, pageSize := mctx.Page(), mctx.PageSize()
+
+	switch {
+	case page < 0:
+		return ErrInvalidQuery{Err: fmt.Errorf("page must not be negative: %d", page)}
+	case pageSize < 0:
+		return ErrInvalidQuery{Err: fmt.Errorf("page size must not be negative: %d", pageSize)}
+	case page > 0 && pageSize == 0:
+		return ErrInvalidQuery{Err: fmt.Errorf("page %d requires a positive page size", page)}
+	}
+
+	return nil
+}
@@ -102,6 +102,27 @@ func TestMethodNewContextWithTransactionID(t *testing.T) {
 	})
 }
 
+func TestMethodNewContextWithOptions(t *testing.T) {
+
+	Convey("Given I create a new context with several options", t, func() {
+
+		filter := elemental.NewFilterComposer().WithKey("name").Equals("a").Done()
+		mctx := NewContext(
+			context.Background(),
+			ContextOptionFilter(filter),
+			ContextOptionPage(1, 50),
+			ContextOptionOrder("name"),
+		)
+
+		Convey("Then all the options should be applied", func() {
+			So(mctx.Filter(), ShouldEqual, filter)
+			So(mctx.Page(), ShouldEqual, 1)
+			So(mctx.PageSize(), ShouldEqual, 50)
+			So(mctx.Order(), ShouldResemble, []string{"name"})
+		})
+	})
+}
+
 func TestMethodString(t *testing.T) {
 
 	Convey("Given I create a new context and calle the method string", t, func() {
@@ -148,6 +169,7 @@ func TestContext_Derive(t *testing.T) {
 			clientIP:             "1.1.1.1",
 			retryRatio:           12,
 			opaque:               map[string]interface{}{"a": "b"},
+			bypassCache:          true,
 		}
 
 		mctx.SetCount(3)
@@ -175,6 +197,7 @@ func TestContext_Derive(t *testing.T) {
 				So(copy.Fields(), ShouldResemble, mctx.fields)
 				So(copy.Fields(), ShouldNotEqual, mctx.fields)
 				So(copy.Filter().String(), ShouldEqual, `k == "v"`)
+				So(copy.Filter(), ShouldNotEqual, mctx.filter)
 				So(copy.Finalizer(), ShouldEqual, mctx.createFinalizer)
 				So(copy.Namespace(), ShouldEqual, mctx.namespace)
 				So(copy.Order(), ShouldResemble, mctx.order)
@@ -197,6 +220,7 @@ func TestContext_Derive(t *testing.T) {
 				So(copy.WriteConsistency(), ShouldEqual, mctx.writeConsistency)
 				So(copy.Context(), ShouldEqual, mctx.ctx)
 				So(copy.RetryRatio(), ShouldEqual, mctx.retryRatio)
+				So(copy.BypassCache(), ShouldEqual, mctx.bypassCache)
 				So(copy.Opaque(), ShouldResemble, mctx.opaque)
 				So(copy.Opaque(), ShouldNotEqual, mctx.opaque)
 			})
@@ -242,9 +266,129 @@ func TestContext_Derive(t *testing.T) {
 				So(copy.WriteConsistency(), ShouldEqual, mctx.writeConsistency)
 				So(copy.Context(), ShouldEqual, mctx.ctx)
 				So(copy.RetryRatio(), ShouldEqual, mctx.retryRatio)
+				So(copy.BypassCache(), ShouldEqual, mctx.bypassCache)
 				So(copy.Opaque(), ShouldResemble, mctx.opaque)
 				So(copy.Opaque(), ShouldNotEqual, mctx.opaque)
 			})
 		})
 	})
 }
+
+func TestContext_Clone(t *testing.T) {
+
+	Convey("Given I have a context", t, func() {
+
+		mctx := NewContext(
+			context.Background(),
+			ContextOptionFilter(
+				elemental.NewFilterComposer().
+					WithKey("k").Equals("v").
+					WithKey("l").In("a", "b").
+					Or(
+						elemental.NewFilterComposer().WithKey("m").Equals("n").Done(),
+					).
+					Done(),
+			),
+			ContextOptionOrder("a", "b"),
+			ContextOptionFields([]string{"a", "b"}),
+			ContextOptionParameters(url.Values{"a": []string{"b"}}),
+			ContextOptionNamespace("/ns"),
+		).(*mcontext)
+
+		mctx.SetCount(3)
+		mctx.SetNext("next")
+		mctx.SetMessages([]string{"hello"})
+		mctx.SetIdempotencyKey("ikey")
+
+		Convey("When I clone it", func() {
+
+			clone := mctx.Clone().(*mcontext)
+
+			Convey("Then the clone should resemble the original", func() {
+				So(clone, ShouldNotEqual, mctx)
+				So(clone.Filter(), ShouldNotEqual, mctx.Filter())
+				So(clone.Filter().String(), ShouldEqual, mctx.Filter().String())
+				So(clone.Order(), ShouldResemble, mctx.Order())
+				So(clone.Fields(), ShouldResemble, mctx.Fields())
+				So(clone.Parameters(), ShouldResemble, mctx.Parameters())
+				So(clone.Namespace(), ShouldEqual, "/ns")
+				So(clone.Count(), ShouldEqual, 3)
+				So(clone.Next(), ShouldEqual, "next")
+				So(clone.Messages(), ShouldResemble, []string{"hello"})
+				So(clone.IdempotencyKey(), ShouldEqual, "ikey")
+				So(clone.String(), ShouldEqual, mctx.String())
+			})
+
+			Convey("When I mutate the filter of the clone", func() {
+
+				original := mctx.Filter().String()
+
+				clone.Filter().Values()[0][0] = "changed"
+				clone.Filter().Values()[1][1] = "changed"
+				clone.Filter().OrFilters()[2][0].Values()[0][0] = "changed"
+				clone.Filter().WithKey("x").Equals("y")
+
+				Convey("Then the original filter should be unchanged", func() {
+					So(mctx.Filter().String(), ShouldEqual, original)
+					So(clone.Filter().String(), ShouldNotEqual, mctx.Filter().String())
+				})
+			})
+
+			Convey("When I mutate the slices and maps of the clone", func() {
+
+				clone.Order()[0] = "changed"
+				clone.Fields()[0] = "changed"
+				clone.Messages()[0] = "changed"
+				clone.Parameters()["a"][0] = "changed"
+				clone.Parameters().Set("new", "value")
+
+				Convey("Then the original should be unchanged", func() {
+					So(mctx.Order(), ShouldResemble, []string{"a", "b"})
+					So(mctx.Fields(), ShouldResemble, []string{"a", "b"})
+					So(mctx.Messages(), ShouldResemble, []string{"hello"})
+					So(mctx.Parameters(), ShouldResemble, url.Values{"a": []string{"b"}})
+				})
+			})
+		})
+	})
+}
+
+func TestValidatePagination(t *testing.T) {
+
+	Convey("Given I have a context", t, func() {
+
+		Convey("When it has no pagination", func() {
+			So(ValidatePagination(NewContext(context.Background())), ShouldBeNil)
+		})
+
+		Convey("When it is nil", func() {
+			So(ValidatePagination(nil), ShouldBeNil)
+		})
+
+		Convey("When it has a page and a page size", func() {
+			So(ValidatePagination(NewContext(context.Background(), ContextOptionPage(2, 10))), ShouldBeNil)
+		})
+
+		Convey("When it only has a page size", func() {
+			So(ValidatePagination(NewContext(context.Background(), ContextOptionPage(0, 10))), ShouldBeNil)
+		})
+
+		Convey("When it has a page without page size", func() {
+			err := ValidatePagination(NewContext(context.Background(), ContextOptionPage(2, 0)))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Query invalid: page 2 requires a positive page size")
+		})
+
+		Convey("When it has a negative page", func() {
+			err := ValidatePagination(NewContext(context.Background(), ContextOptionPage(-1, 10)))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Query invalid: page must not be negative: -1")
+		})
+
+		Convey("When it has a negative page size", func() {
+			err := ValidatePagination(NewContext(context.Background(), ContextOptionPage(1, -10)))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Query invalid: page size must not be negative: -10")
+		})
+	})
+}
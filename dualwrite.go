@@ -0,0 +1,229 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/copystructure"
+	"go.aporeto.io/elemental"
+)
+
+// A DualWriteReporter is called when a write
+// to the secondary manipulator failed.
+type DualWriteReporter func(operation elemental.Operation, identity elemental.Identity, err error)
+
+type dualWriteConfig struct {
+	async        bool
+	asyncTimeout time.Duration
+	strict       bool
+	reporter     DualWriteReporter
+}
+
+// A DualWriteOption can be given to NewDualWriteManipulator to alter its behavior.
+type DualWriteOption func(*dualWriteConfig)
+
+// DualWriteOptionAsync makes the writes to the secondary manipulator
+// asynchronous. The operation returns as soon as the primary manipulator
+// is done, and the write to the secondary happens in the background, on a
+// copy of the object, with the given timeout.
+//
+// It cannot be used with DualWriteOptionStrict.
+func DualWriteOptionAsync(timeout time.Duration) DualWriteOption {
+
+	if timeout <= 0 {
+		panic("timeout must be positive")
+	}
+
+	return func(c *dualWriteConfig) {
+		c.async = true
+		c.asyncTimeout = timeout
+	}
+}
+
+// DualWriteOptionStrict makes the operation return the error
+// of the secondary manipulator when it fails. By default, such
+// errors are only passed to the reporter.
+func DualWriteOptionStrict() DualWriteOption {
+	return func(c *dualWriteConfig) {
+		c.strict = true
+	}
+}
+
+// DualWriteOptionReporter sets the function that will be
+// called when a write to the secondary manipulator fails.
+func DualWriteOptionReporter(reporter DualWriteReporter) DualWriteOption {
+	return func(c *dualWriteConfig) {
+		c.reporter = reporter
+	}
+}
+
+type dualWriteManipulator struct {
+	Manipulator
+	secondary Manipulator
+	cfg       dualWriteConfig
+}
+
+// NewDualWriteManipulator returns a Manipulator that applies the write operations
+// to both the primary and the secondary manipulators. This is useful during a
+// migration to warm up a new backend before cutting over to it.
+//
+// Reads are only performed against the primary. Writes are first applied to the
+// primary, and only mirrored to the secondary if they succeeded. By default the
+// secondary is written synchronously and its errors are ignored. Use DualWriteOptionAsync,
+// DualWriteOptionStrict and DualWriteOptionReporter to change this.
+//
+// The secondary is always given a copy of the object. Note that backends
+// generating their own identifiers on Create will end up with different
+// identifiers for the same object. The given object keeps the one assigned
+// by the primary.
+func NewDualWriteManipulator(primary Manipulator, secondary Manipulator, options ...DualWriteOption) Manipulator {
+
+	if primary == nil {
+		panic("primary must not be nil")
+	}
+
+	if secondary == nil {
+		panic("secondary must not be nil")
+	}
+
+	cfg := dualWriteConfig{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	if cfg.async && cfg.strict {
+		panic("DualWriteOptionStrict cannot be used with DualWriteOptionAsync")
+	}
+
+	return &dualWriteManipulator{
+		Manipulator: primary,
+		secondary:   secondary,
+		cfg:         cfg,
+	}
+}
+
+func (m *dualWriteManipulator) Create(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.Manipulator.Create(mctx, object); err != nil {
+		return err
+	}
+
+	return m.mirror(mctx, elemental.OperationCreate, object.Identity(), object, m.secondary.Create)
+}
+
+func (m *dualWriteManipulator) Update(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.Manipulator.Update(mctx, object); err != nil {
+		return err
+	}
+
+	return m.mirror(mctx, elemental.OperationUpdate, object.Identity(), object, m.secondary.Update)
+}
+
+func (m *dualWriteManipulator) Delete(mctx Context, object elemental.Identifiable) error {
+
+	if err := m.Manipulator.Delete(mctx, object); err != nil {
+		return err
+	}
+
+	return m.mirror(mctx, elemental.OperationDelete, object.Identity(), object, m.secondary.Delete)
+}
+
+func (m *dualWriteManipulator) DeleteMany(mctx Context, identity elemental.Identity) error {
+
+	if err := m.Manipulator.DeleteMany(mctx, identity); err != nil {
+		return err
+	}
+
+	return m.mirror(mctx, elemental.OperationDelete, identity, nil, func(mctx Context, _ elemental.Identifiable) error {
+		return m.secondary.DeleteMany(mctx, identity)
+	})
+}
+
+func (m *dualWriteManipulator) mirror(
+	mctx Context,
+	operation elemental.Operation,
+	identity elemental.Identity,
+	object elemental.Identifiable,
+	write func(Context, elemental.Identifiable) error,
+) error {
+
+	// The secondary gets its own copy of the object, so it cannot
+	// change what the primary wrote, like the identifier it assigned.
+	// In async mode, the caller is also free to modify the object as
+	// soon as we return.
+	if object != nil {
+		copied, err := copystructure.Copy(object)
+		if err != nil {
+			return m.handle(operation, identity, fmt.Errorf("unable to copy object: %w", err))
+		}
+		object = copied.(elemental.Identifiable)
+	}
+
+	if !m.cfg.async {
+		return m.handle(operation, identity, write(mctx, object))
+	}
+
+	// The caller is free to reuse its manipulate.Context,
+	// so it must be derived before we return.
+	var derived *mcontext
+	if mctx != nil {
+		d, ok := mctx.Derive().(*mcontext)
+		if !ok {
+			return m.handle(operation, identity, fmt.Errorf("unable to mirror asynchronously using a context of type %T", mctx))
+		}
+		derived = d
+	}
+
+	go func() {
+
+		ctx, cancel := context.WithTimeout(context.Background(), m.cfg.asyncTimeout)
+		defer cancel()
+
+		// The caller's context will most likely be canceled
+		// before we are done, so we only keep its options.
+		sctx := NewContext(ctx)
+		if derived != nil {
+			derived.ctx = ctx
+			sctx = derived
+		}
+
+		_ = m.handle(operation, identity, write(sctx, object))
+	}()
+
+	return nil
+}
+
+func (m *dualWriteManipulator) handle(operation elemental.Operation, identity elemental.Identity, err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	m.report(operation, identity, err)
+
+	if m.cfg.strict {
+		return err
+	}
+
+	return nil
+}
+
+func (m *dualWriteManipulator) report(operation elemental.Operation, identity elemental.Identity, err error) {
+
+	if m.cfg.reporter != nil {
+		m.cfg.reporter(operation, identity, err)
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+type recordingManipulator struct {
+	failingManipulator
+	done chan struct{}
+
+	sync.Mutex
+	writes []elemental.Operation
+	ctxs   []context.Context
+}
+
+func newRecordingManipulator(err error) *recordingManipulator {
+	return &recordingManipulator{
+		failingManipulator: failingManipulator{err: err},
+		done:               make(chan struct{}, 10),
+	}
+}
+
+func (m *recordingManipulator) record(mctx Context, op elemental.Operation) error {
+
+	m.Lock()
+	m.writes = append(m.writes, op)
+	if mctx != nil {
+		m.ctxs = append(m.ctxs, mctx.Context())
+	}
+	m.Unlock()
+
+	m.done <- struct{}{}
+
+	return m.err
+}
+
+func (m *recordingManipulator) operations() []elemental.Operation {
+	m.Lock()
+	defer m.Unlock()
+	return append([]elemental.Operation{}, m.writes...)
+}
+
+func (m *recordingManipulator) Create(mctx Context, _ elemental.Identifiable) error {
+	return m.record(mctx, elemental.OperationCreate)
+}
+
+func (m *recordingManipulator) Update(mctx Context, _ elemental.Identifiable) error {
+	return m.record(mctx, elemental.OperationUpdate)
+}
+
+func (m *recordingManipulator) Delete(mctx Context, _ elemental.Identifiable) error {
+	return m.record(mctx, elemental.OperationDelete)
+}
+
+func (m *recordingManipulator) DeleteMany(mctx Context, _ elemental.Identity) error {
+	return m.record(mctx, elemental.OperationDelete)
+}
+
+// An identifyingManipulator sets its identifier on the objects it creates.
+type identifyingManipulator struct {
+	failingManipulator
+	id string
+}
+
+func (m *identifyingManipulator) Create(_ Context, object elemental.Identifiable) error {
+	object.SetIdentifier(m.id)
+	return nil
+}
+
+// A foreignContext is a Context that is not derived as an *mcontext.
+type foreignContext struct {
+	Context
+}
+
+func (c foreignContext) Derive(...ContextOption) Context { return c }
+
+func TestNewDualWriteManipulator(t *testing.T) {
+
+	Convey("Calling NewDualWriteManipulator with a nil primary should panic", t, func() {
+		So(func() { NewDualWriteManipulator(nil, newRecordingManipulator(nil)) }, ShouldPanicWith, "primary must not be nil")
+	})
+
+	Convey("Calling NewDualWriteManipulator with a nil secondary should panic", t, func() {
+		So(func() { NewDualWriteManipulator(newRecordingManipulator(nil), nil) }, ShouldPanicWith, "secondary must not be nil")
+	})
+
+	Convey("Calling NewDualWriteManipulator with strict and async should panic", t, func() {
+		So(
+			func() {
+				NewDualWriteManipulator(
+					newRecordingManipulator(nil),
+					newRecordingManipulator(nil),
+					DualWriteOptionStrict(),
+					DualWriteOptionAsync(time.Second),
+				)
+			},
+			ShouldPanicWith,
+			"DualWriteOptionStrict cannot be used with DualWriteOptionAsync",
+		)
+	})
+
+	Convey("Calling DualWriteOptionAsync with a non positive timeout should panic", t, func() {
+		So(func() { DualWriteOptionAsync(0) }, ShouldPanicWith, "timeout must be positive")
+	})
+}
+
+func TestDualWriteManipulator_Sync(t *testing.T) {
+
+	allWrites := []elemental.Operation{
+		elemental.OperationCreate,
+		elemental.OperationUpdate,
+		elemental.OperationDelete,
+		elemental.OperationDelete,
+	}
+
+	write := func(m Manipulator) []error {
+		return []error{
+			m.Create(nil, testmodel.NewList()),
+			m.Update(nil, testmodel.NewList()),
+			m.Delete(nil, testmodel.NewList()),
+			m.DeleteMany(nil, testmodel.ListIdentity),
+		}
+	}
+
+	Convey("Given I have a dual write manipulator with working backends", t, func() {
+
+		primary := newRecordingManipulator(nil)
+		secondary := newRecordingManipulator(nil)
+		m := NewDualWriteManipulator(primary, secondary)
+
+		Convey("When I perform write operations", func() {
+
+			errs := write(m)
+
+			Convey("Then both backends should have been written", func() {
+				So(errs, ShouldResemble, []error{nil, nil, nil, nil})
+				So(primary.operations(), ShouldResemble, allWrites)
+				So(secondary.operations(), ShouldResemble, allWrites)
+			})
+		})
+
+		Convey("When I perform read operations", func() {
+
+			_ = m.Retrieve(nil, testmodel.NewList())
+			_ = m.RetrieveMany(nil, &testmodel.ListsList{})
+			_, _ = m.Count(nil, testmodel.ListIdentity)
+
+			Convey("Then the secondary should not have been used", func() {
+				So(secondary.operations(), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given I have a dual write manipulator with backends assigning identifiers", t, func() {
+
+		primary := &identifyingManipulator{id: "primary"}
+		secondary := &identifyingManipulator{id: "secondary"}
+		m := NewDualWriteManipulator(primary, secondary)
+
+		Convey("When I create an object", func() {
+
+			list := testmodel.NewList()
+			err := m.Create(nil, list)
+
+			Convey("Then the object should keep the identifier of the primary", func() {
+				So(err, ShouldBeNil)
+				So(list.ID, ShouldEqual, "primary")
+			})
+		})
+	})
+
+	Convey("Given I have a dual write manipulator with a failing primary", t, func() {
+
+		primary := newRecordingManipulator(errors.New("boom"))
+		secondary := newRecordingManipulator(nil)
+		m := NewDualWriteManipulator(primary, secondary)
+
+		Convey("When I perform write operations", func() {
+
+			errs := write(m)
+
+			Convey("Then the errors should be returned", func() {
+				for _, err := range errs {
+					So(err, ShouldEqual, primary.err)
+				}
+			})
+
+			Convey("Then the secondary should not have been written", func() {
+				So(secondary.operations(), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given I have a dual write manipulator with a failing secondary", t, func() {
+
+		var reported []error
+		primary := newRecordingManipulator(nil)
+		secondary := newRecordingManipulator(errors.New("boom"))
+		reporter := func(op elemental.Operation, identity elemental.Identity, err error) {
+			So(identity, ShouldResemble, testmodel.ListIdentity)
+			reported = append(reported, err)
+		}
+
+		Convey("When I perform write operations", func() {
+
+			m := NewDualWriteManipulator(primary, secondary, DualWriteOptionReporter(reporter))
+			errs := write(m)
+
+			Convey("Then the errors should be ignored", func() {
+				So(errs, ShouldResemble, []error{nil, nil, nil, nil})
+			})
+
+			Convey("Then the errors should be reported", func() {
+				So(reported, ShouldResemble, []error{secondary.err, secondary.err, secondary.err, secondary.err})
+			})
+		})
+
+		Convey("When I perform write operations in strict mode", func() {
+
+			m := NewDualWriteManipulator(primary, secondary, DualWriteOptionReporter(reporter), DualWriteOptionStrict())
+			errs := write(m)
+
+			Convey("Then the errors should be returned", func() {
+				So(errs, ShouldResemble, []error{secondary.err, secondary.err, secondary.err, secondary.err})
+			})
+
+			Convey("Then the errors should be reported", func() {
+				So(len(reported), ShouldEqual, 4)
+			})
+
+			Convey("Then the primary should have been written", func() {
+				So(primary.operations(), ShouldResemble, allWrites)
+			})
+		})
+	})
+}
+
+func TestDualWriteManipulator_Async(t *testing.T) {
+
+	Convey("Given I have an async dual write manipulator with a failing secondary", t, func() {
+
+		reported := make(chan error, 10)
+		primary := newRecordingManipulator(nil)
+		secondary := newRecordingManipulator(errors.New("boom"))
+
+		m := NewDualWriteManipulator(
+			primary,
+			secondary,
+			DualWriteOptionAsync(time.Minute),
+			DualWriteOptionReporter(func(op elemental.Operation, identity elemental.Identity, err error) {
+				reported <- err
+			}),
+		)
+
+		Convey("When I create an object with a context that gets canceled", func() {
+
+			ctx, cancel := context.WithCancel(context.Background())
+			list := testmodel.NewList()
+			err := m.Create(NewContext(ctx), list)
+			cancel()
+
+			Convey("Then the error should be ignored", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the secondary should be written and the error reported", func() {
+
+				select {
+				case err := <-reported:
+					So(err, ShouldEqual, secondary.err)
+				case <-time.After(3 * time.Second):
+					panic("secondary write not reported in time")
+				}
+
+				So(primary.operations(), ShouldResemble, []elemental.Operation{elemental.OperationCreate})
+				So(secondary.operations(), ShouldResemble, []elemental.Operation{elemental.OperationCreate})
+
+				secondary.Lock()
+				sctx := secondary.ctxs[0]
+				secondary.Unlock()
+
+				So(sctx, ShouldNotEqual, ctx)
+				_, hasDeadline := sctx.Deadline()
+				So(hasDeadline, ShouldBeTrue)
+			})
+		})
+
+		Convey("When I create an object with a context that cannot be derived", func() {
+
+			err := m.Create(foreignContext{NewContext(context.Background())}, testmodel.NewList())
+
+			Convey("Then the error should be reported", func() {
+
+				So(err, ShouldBeNil)
+
+				select {
+				case err := <-reported:
+					So(err.Error(), ShouldStartWith, "unable to mirror asynchronously using a context of type")
+				case <-time.After(3 * time.Second):
+					panic("error not reported in time")
+				}
+
+				So(secondary.operations(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I delete many objects without context", func() {
+
+			err := m.DeleteMany(nil, testmodel.ListIdentity)
+
+			Convey("Then the secondary should be written", func() {
+
+				So(err, ShouldBeNil)
+
+				select {
+				case <-secondary.done:
+				case <-time.After(3 * time.Second):
+					panic("secondary not written in time")
+				}
+
+				So(secondary.operations(), ShouldResemble, []elemental.Operation{elemental.OperationDelete})
+			})
+		})
+	})
+}
@@ -11,7 +11,11 @@
 
 package manipulate
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 // ErrInvalidQuery represents an error due to an invalid query.
 type ErrInvalidQuery struct {
@@ -25,6 +29,12 @@ func (err ErrInvalidQuery) Unwrap() error {
 	return err.Err
 }
 
+// Is returns true if the target is an ErrInvalidQuery.
+func (err ErrInvalidQuery) Is(target error) bool {
+	_, ok := target.(ErrInvalidQuery)
+	return ok
+}
+
 func (err ErrInvalidQuery) Error() string {
 	return fmt.Sprintf("Query invalid: %s", err.Err)
 }
@@ -43,6 +53,12 @@ func NewErrCannotUnmarshal(message string) ErrCannotUnmarshal {
 // Unwrap unwraps the internal error.
 func (e ErrCannotUnmarshal) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrCannotUnmarshal.
+func (e ErrCannotUnmarshal) Is(target error) bool {
+	_, ok := target.(ErrCannotUnmarshal)
+	return ok
+}
+
 func (e ErrCannotUnmarshal) Error() string { return "Unable to unmarshal data: " + e.Err.Error() }
 
 // IsCannotUnmarshalError returns true if the given error is am ErrCannotUnmarshal.
@@ -65,6 +81,12 @@ func NewErrCannotMarshal(message string) ErrCannotMarshal {
 // Unwrap unwraps the internal error.
 func (e ErrCannotMarshal) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrCannotMarshal.
+func (e ErrCannotMarshal) Is(target error) bool {
+	_, ok := target.(ErrCannotMarshal)
+	return ok
+}
+
 func (e ErrCannotMarshal) Error() string { return "Unable to marshal data: " + e.Err.Error() }
 
 // IsCannotMarshalError returns true if the given error is am ErrCannotMarshal.
@@ -87,6 +109,12 @@ func NewErrObjectNotFound(message string) ErrObjectNotFound {
 // Unwrap unwraps the internal error.
 func (e ErrObjectNotFound) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrObjectNotFound.
+func (e ErrObjectNotFound) Is(target error) bool {
+	_, ok := target.(ErrObjectNotFound)
+	return ok
+}
+
 func (e ErrObjectNotFound) Error() string { return "Object not found: " + e.Err.Error() }
 
 // IsObjectNotFoundError returns true if the given error is am ErrObjectNotFound.
@@ -109,6 +137,12 @@ func NewErrMultipleObjectsFound(message string) ErrMultipleObjectsFound {
 // Unwrap unwraps the internal error.
 func (e ErrMultipleObjectsFound) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrMultipleObjectsFound.
+func (e ErrMultipleObjectsFound) Is(target error) bool {
+	_, ok := target.(ErrMultipleObjectsFound)
+	return ok
+}
+
 func (e ErrMultipleObjectsFound) Error() string { return "Multiple objects found: " + e.Err.Error() }
 
 // IsMultipleObjectsFoundError returns true if the given error is am ErrMultipleObjectsFound.
@@ -131,6 +165,12 @@ func NewErrCannotBuildQuery(message string) ErrCannotBuildQuery {
 // Unwrap unwraps the internal error.
 func (e ErrCannotBuildQuery) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrCannotBuildQuery.
+func (e ErrCannotBuildQuery) Is(target error) bool {
+	_, ok := target.(ErrCannotBuildQuery)
+	return ok
+}
+
 func (e ErrCannotBuildQuery) Error() string { return "Unable to build query: " + e.Err.Error() }
 
 // IsCannotBuildQueryError returns true if the given error is am ErrCannotBuildQuery.
@@ -153,6 +193,12 @@ func NewErrCannotExecuteQuery(message string) ErrCannotExecuteQuery {
 // Unwrap unwraps the internal error.
 func (e ErrCannotExecuteQuery) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrCannotExecuteQuery.
+func (e ErrCannotExecuteQuery) Is(target error) bool {
+	_, ok := target.(ErrCannotExecuteQuery)
+	return ok
+}
+
 func (e ErrCannotExecuteQuery) Error() string { return "Unable to execute query: " + e.Err.Error() }
 
 // IsCannotExecuteQueryError returns true if the given error is am ErrCannotExecuteQuery.
@@ -175,6 +221,12 @@ func NewErrCannotCommit(message string) ErrCannotCommit {
 // Unwrap unwraps the internal error.
 func (e ErrCannotCommit) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrCannotCommit.
+func (e ErrCannotCommit) Is(target error) bool {
+	_, ok := target.(ErrCannotCommit)
+	return ok
+}
+
 func (e ErrCannotCommit) Error() string { return "Unable to commit transaction: " + e.Err.Error() }
 
 // IsCannotCommitError returns true if the given error is am ErrCannotCommit.
@@ -197,6 +249,12 @@ func NewErrNotImplemented(message string) ErrNotImplemented {
 // Unwrap unwraps the internal error.
 func (e ErrNotImplemented) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrNotImplemented.
+func (e ErrNotImplemented) Is(target error) bool {
+	_, ok := target.(ErrNotImplemented)
+	return ok
+}
+
 func (e ErrNotImplemented) Error() string { return "Not implemented: " + e.Err.Error() }
 
 // IsNotImplementedError returns true if the given error is am ErrNotImplemented.
@@ -219,6 +277,12 @@ func NewErrCannotCommunicate(message string) ErrCannotCommunicate {
 // Unwrap unwraps the internal error.
 func (e ErrCannotCommunicate) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrCannotCommunicate.
+func (e ErrCannotCommunicate) Is(target error) bool {
+	_, ok := target.(ErrCannotCommunicate)
+	return ok
+}
+
 func (e ErrCannotCommunicate) Error() string { return "Cannot communicate: " + e.Err.Error() }
 
 // IsCannotCommunicateError returns true if the given error is am ErrCannotCommunicate.
@@ -241,6 +305,12 @@ func NewErrLocked(message string) ErrLocked {
 // Unwrap unwraps the internal error.
 func (e ErrLocked) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrLocked.
+func (e ErrLocked) Is(target error) bool {
+	_, ok := target.(ErrLocked)
+	return ok
+}
+
 func (e ErrLocked) Error() string { return "Cannot communicate: " + e.Err.Error() }
 
 // IsLockedError returns true if the given error is am ErrLocked.
@@ -263,6 +333,12 @@ func NewErrTransactionNotFound(message string) ErrTransactionNotFound {
 // Unwrap unwraps the internal error.
 func (e ErrTransactionNotFound) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrTransactionNotFound.
+func (e ErrTransactionNotFound) Is(target error) bool {
+	_, ok := target.(ErrTransactionNotFound)
+	return ok
+}
+
 func (e ErrTransactionNotFound) Error() string { return "Transaction not found: " + e.Err.Error() }
 
 // IsTransactionNotFoundError returns true if the given error is am ErrTransactionNotFound.
@@ -285,6 +361,12 @@ func NewErrConstraintViolation(message string) ErrConstraintViolation {
 // Unwrap unwraps the internal error.
 func (e ErrConstraintViolation) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrConstraintViolation.
+func (e ErrConstraintViolation) Is(target error) bool {
+	_, ok := target.(ErrConstraintViolation)
+	return ok
+}
+
 func (e ErrConstraintViolation) Error() string { return "Constraint violation: " + e.Err.Error() }
 
 // IsConstraintViolationError returns true if the given error is am ErrConstraintViolation.
@@ -307,6 +389,12 @@ func NewErrDisconnected(message string) ErrDisconnected {
 // Unwrap unwraps the internal error.
 func (e ErrDisconnected) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrDisconnected.
+func (e ErrDisconnected) Is(target error) bool {
+	_, ok := target.(ErrDisconnected)
+	return ok
+}
+
 func (e ErrDisconnected) Error() string { return "Disconnected: " + e.Err.Error() }
 
 // IsDisconnectedError returns true if the given error is am ErrDisconnected.
@@ -329,6 +417,12 @@ func NewErrTooManyRequests(message string) ErrTooManyRequests {
 // Unwrap unwraps the internal error.
 func (e ErrTooManyRequests) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrTooManyRequests.
+func (e ErrTooManyRequests) Is(target error) bool {
+	_, ok := target.(ErrTooManyRequests)
+	return ok
+}
+
 func (e ErrTooManyRequests) Error() string { return "Too many requests: " + e.Err.Error() }
 
 // IsTooManyRequestsError returns true if the given error is am ErrTooManyRequests.
@@ -351,6 +445,12 @@ func NewErrTLS(message string) ErrTLS {
 // Unwrap unwraps the internal error.
 func (e ErrTLS) Unwrap() error { return e.Err }
 
+// Is returns true if the target is an ErrTLS.
+func (e ErrTLS) Is(target error) bool {
+	_, ok := target.(ErrTLS)
+	return ok
+}
+
 func (e ErrTLS) Error() string { return "TLS error: " + e.Err.Error() }
 
 // IsTLSError returns true if the given error is am ErrTLS.
@@ -358,3 +458,69 @@ func IsTLSError(err error) bool {
 	_, ok := err.(ErrTLS)
 	return ok
 }
+
+// ErrReadOnly represents the error returned when a write
+// operation is performed on a read only manipulator.
+type ErrReadOnly struct{ Err error }
+
+// Unwrap unwraps the internal error.
+func (e ErrReadOnly) Unwrap() error { return e.Err }
+
+// Is returns true if the target is an ErrReadOnly.
+func (e ErrReadOnly) Is(target error) bool {
+	_, ok := target.(ErrReadOnly)
+	return ok
+}
+
+func (e ErrReadOnly) Error() string { return "Read only: " + e.Err.Error() }
+
+// IsReadOnlyError returns true if the given error is am ErrReadOnly.
+func IsReadOnlyError(err error) bool {
+	_, ok := err.(ErrReadOnly)
+	return ok
+}
+
+// HTTPStatus returns the conventional HTTP status code
+// for the given error. Wrapped errors are inspected
+// using errors.As. It returns http.StatusOK if err is nil
+// and http.StatusInternalServerError if the error
+// does not belong to any known category.
+func HTTPStatus(err error) int {
+
+	if err == nil {
+		return http.StatusOK
+	}
+
+	switch {
+	case errors.As(err, &ErrInvalidQuery{}),
+		errors.As(err, &ErrCannotBuildQuery{}):
+		return http.StatusBadRequest
+
+	case errors.As(err, &ErrObjectNotFound{}),
+		errors.As(err, &ErrTransactionNotFound{}):
+		return http.StatusNotFound
+
+	case errors.As(err, &ErrConstraintViolation{}),
+		errors.As(err, &ErrMultipleObjectsFound{}):
+		return http.StatusConflict
+
+	case errors.As(err, &ErrReadOnly{}):
+		return http.StatusForbidden
+
+	case errors.As(err, &ErrLocked{}):
+		return http.StatusLocked
+
+	case errors.As(err, &ErrTooManyRequests{}):
+		return http.StatusTooManyRequests
+
+	case errors.As(err, &ErrNotImplemented{}):
+		return http.StatusNotImplemented
+
+	case errors.As(err, &ErrCannotCommunicate{}),
+		errors.As(err, &ErrDisconnected{}):
+		return http.StatusServiceUnavailable
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
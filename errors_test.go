@@ -14,6 +14,7 @@ package manipulate
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -38,6 +39,12 @@ func genericErrorTest(
 			So(verifierFunc(err), ShouldBeTrue)
 		})
 
+		Convey("Then errors.Is should match the error type", func() {
+			So(errors.Is(err, makeFactory(nil)), ShouldBeTrue)
+			So(errors.Is(fmt.Errorf("wrapped: %w", err), makeFactory(errors.New("other"))), ShouldBeTrue)
+			So(errors.Is(oerr, makeFactory(nil)), ShouldBeFalse)
+		})
+
 		olderr := makeFactoryOld("this is a an error")
 		Convey("Then the old error should be correct", func() {
 			So(olderr.Error(), ShouldEqual, errorPrefix+"this is a an error")
@@ -167,4 +174,73 @@ func TestThing_Function(t *testing.T) {
 		func(msg string) error { return NewErrTLS(msg) },
 		IsTLSError,
 	)
+
+	genericErrorTest(
+		t,
+		"Read only: ",
+		func(err error) error { return ErrReadOnly{Err: err} },
+		func(msg string) error { return ErrReadOnly{Err: errors.New(msg)} },
+		IsReadOnlyError,
+	)
+}
+
+func TestErrorsIs(t *testing.T) {
+
+	Convey("Given I have a wrapped ErrInvalidQuery", t, func() {
+
+		err := fmt.Errorf("wrapped: %w", ErrInvalidQuery{DueToFilter: true, Err: fmt.Errorf("boom")})
+
+		Convey("Then errors.Is should match its type only", func() {
+			So(errors.Is(err, ErrInvalidQuery{}), ShouldBeTrue)
+			So(errors.Is(err, ErrObjectNotFound{}), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given I have a wrapped ErrObjectNotFound", t, func() {
+
+		err := fmt.Errorf("wrapped: %w", ErrObjectNotFound{Err: fmt.Errorf("boom")})
+
+		Convey("Then errors.Is should match its type only", func() {
+			So(errors.Is(err, ErrObjectNotFound{}), ShouldBeTrue)
+			So(errors.Is(err, ErrCannotCommunicate{}), ShouldBeFalse)
+		})
+	})
+}
+
+func TestHTTPStatus(t *testing.T) {
+
+	Convey("Given I have various errors", t, func() {
+
+		e := fmt.Errorf("boom")
+
+		tests := []struct {
+			err    error
+			status int
+		}{
+			{nil, http.StatusOK},
+			{ErrInvalidQuery{Err: e}, http.StatusBadRequest},
+			{ErrCannotBuildQuery{Err: e}, http.StatusBadRequest},
+			{ErrObjectNotFound{Err: e}, http.StatusNotFound},
+			{ErrTransactionNotFound{Err: e}, http.StatusNotFound},
+			{ErrConstraintViolation{Err: e}, http.StatusConflict},
+			{ErrMultipleObjectsFound{Err: e}, http.StatusConflict},
+			{ErrReadOnly{Err: e}, http.StatusForbidden},
+			{ErrLocked{Err: e}, http.StatusLocked},
+			{ErrTooManyRequests{Err: e}, http.StatusTooManyRequests},
+			{ErrNotImplemented{Err: e}, http.StatusNotImplemented},
+			{ErrCannotCommunicate{Err: e}, http.StatusServiceUnavailable},
+			{ErrDisconnected{Err: e}, http.StatusServiceUnavailable},
+			{ErrCannotExecuteQuery{Err: e}, http.StatusInternalServerError},
+			{ErrCannotUnmarshal{Err: e}, http.StatusInternalServerError},
+			{ErrTLS{Err: e}, http.StatusInternalServerError},
+			{e, http.StatusInternalServerError},
+			{fmt.Errorf("wrapped: %w", ErrObjectNotFound{Err: e}), http.StatusNotFound},
+		}
+
+		Convey("Then HTTPStatus should return the correct status codes", func() {
+			for _, tt := range tests {
+				So(HTTPStatus(tt.err), ShouldEqual, tt.status)
+			}
+		})
+	})
 }
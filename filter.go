@@ -13,6 +13,9 @@ package manipulate
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"go.aporeto.io/elemental"
 )
@@ -60,3 +63,478 @@ func NewFilterParser(input string) *FilterParser {
 	fmt.Println("DEPRECATED: manipulate.NewFilterParser is deprecated and aliased to elemental.NewFilterParser")
 	return elemental.NewFilterParser(input)
 }
+
+// ValidateFilter verifies that all the keys of the given filter, including
+// the ones of its sub filters, are attributes known by the given spec and that
+// the comparators used are compatible with the types of the attributes.
+// For keys like "a.b", only the root attribute "a" is verified.
+//
+// It returns an ErrInvalidQuery listing all the problems found,
+// or nil if the filter is valid. A nil filter is always valid.
+func ValidateFilter(f *Filter, spec elemental.AttributeSpecifiable) error {
+
+	if spec == nil {
+		panic("spec must not be nil")
+	}
+
+	if f == nil {
+		return nil
+	}
+
+	problems := validateFilter(f, spec, nil, map[string]struct{}{})
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return ErrInvalidQuery{
+		DueToFilter: true,
+		Err:         fmt.Errorf("invalid filter: %s", strings.Join(problems, ", ")),
+	}
+}
+
+func validateFilter(f *Filter, spec elemental.AttributeSpecifiable, problems []string, seen map[string]struct{}) []string {
+
+	for i, operator := range f.Operators() {
+
+		switch operator {
+
+		case elemental.AndOperator:
+
+			key := f.Keys()[i]
+			problem := validateCondition(key, f.Comparators()[i], spec)
+			if problem == "" {
+				continue
+			}
+
+			if _, ok := seen[problem]; ok {
+				continue
+			}
+			seen[problem] = struct{}{}
+
+			problems = append(problems, problem)
+
+		case elemental.AndFilterOperator:
+			for _, sub := range f.AndFilters()[i] {
+				problems = validateFilter(sub, spec, problems, seen)
+			}
+
+		case elemental.OrFilterOperator:
+			for _, sub := range f.OrFilters()[i] {
+				problems = validateFilter(sub, spec, problems, seen)
+			}
+		}
+	}
+
+	return problems
+}
+
+func validateCondition(key string, comparator elemental.FilterComparator, spec elemental.AttributeSpecifiable) string {
+
+	root := strings.SplitN(key, ".", 2)[0]
+
+	attrSpec := spec.SpecificationForAttribute(root)
+	if attrSpec.Name == "" {
+		return fmt.Sprintf("unknown attribute '%s'", root)
+	}
+
+	// The type of nested keys is not known.
+	if root != key {
+		return ""
+	}
+
+	switch comparator {
+
+	case elemental.GreaterComparator,
+		elemental.GreaterOrEqualComparator,
+		elemental.LesserComparator,
+		elemental.LesserOrEqualComparator:
+
+		switch attrSpec.Type {
+		case "integer", "float", "time", "string":
+		default:
+			return fmt.Sprintf("attribute '%s' of type %s cannot be ordered", key, attrSpec.Type)
+		}
+
+	case elemental.MatchComparator:
+
+		switch attrSpec.Type {
+		case "string", "enum", "list":
+		default:
+			return fmt.Sprintf("attribute '%s' of type %s cannot be matched", key, attrSpec.Type)
+		}
+	}
+
+	return ""
+}
+
+// CopyFilter returns a deep copy of the given filter.
+// The keys, the values slices and the sub filters are copied
+// so the copy can be modified without affecting the original.
+// A nil filter is copied as nil. If the filter uses a comparator
+// that cannot be copied, the original filter is returned as is.
+func CopyFilter(f *Filter) *Filter {
+
+	c, ok := copyFilter(f)
+	if !ok {
+		return f
+	}
+
+	return c
+}
+
+func copyFilter(f *Filter) (*Filter, bool) {
+
+	if f == nil {
+		return nil, true
+	}
+
+	var c elemental.FilterKeyComposer = elemental.NewFilter()
+
+	for i, operator := range f.Operators() {
+
+		switch operator {
+
+		case elemental.AndOperator:
+			kc, ok := copyCondition(c.WithKey(f.Keys()[i]), f.Comparators()[i], f.Values()[i])
+			if !ok {
+				return nil, false
+			}
+			c = kc
+
+		case elemental.AndFilterOperator:
+			subs, ok := copyFilters(f.AndFilters()[i])
+			if !ok {
+				return nil, false
+			}
+			c = c.And(subs...)
+
+		case elemental.OrFilterOperator:
+			subs, ok := copyFilters(f.OrFilters()[i])
+			if !ok {
+				return nil, false
+			}
+			c = c.Or(subs...)
+		}
+	}
+
+	return c.Done(), true
+}
+
+func copyFilters(filters []*Filter) ([]*Filter, bool) {
+
+	out := make([]*Filter, len(filters))
+
+	for i, f := range filters {
+		c, ok := copyFilter(f)
+		if !ok {
+			return nil, false
+		}
+		out[i] = c
+	}
+
+	return out, true
+}
+
+// copyCondition applies the given condition to the given composer.
+// It returns false if the comparator is unknown or if it is missing
+// its value.
+func copyCondition(c elemental.FilterValueComposer, comparator elemental.FilterComparator, values []interface{}) (elemental.FilterKeyComposer, bool) {
+
+	values = append([]interface{}(nil), values...)
+
+	switch comparator {
+	case elemental.EqualComparator,
+		elemental.NotEqualComparator,
+		elemental.GreaterComparator,
+		elemental.GreaterOrEqualComparator,
+		elemental.LesserComparator,
+		elemental.LesserOrEqualComparator:
+
+		if len(values) == 0 {
+			return nil, false
+		}
+	}
+
+	switch comparator {
+	case elemental.EqualComparator:
+		return c.Equals(values[0]), true
+	case elemental.NotEqualComparator:
+		return c.NotEquals(values[0]), true
+	case elemental.GreaterComparator:
+		return c.GreaterThan(values[0]), true
+	case elemental.GreaterOrEqualComparator:
+		return c.GreaterOrEqualThan(values[0]), true
+	case elemental.LesserComparator:
+		return c.LesserThan(values[0]), true
+	case elemental.LesserOrEqualComparator:
+		return c.LesserOrEqualThan(values[0]), true
+	case elemental.InComparator:
+		return c.In(values...), true
+	case elemental.NotInComparator:
+		return c.NotIn(values...), true
+	case elemental.ContainComparator:
+		return c.Contains(values...), true
+	case elemental.NotContainComparator:
+		return c.NotContains(values...), true
+	case elemental.MatchComparator:
+		return c.Matches(values...), true
+	case elemental.ExistsComparator:
+		return c.Exists(), true
+	case elemental.NotExistsComparator:
+		return c.NotExists(), true
+	default:
+		return nil, false
+	}
+}
+
+// CanonicalFilterString returns a deterministic representation of the given
+// filter, so that filters built in different orders but having the same
+// meaning get the same representation. This makes it usable as a cache key.
+//
+// The canonical form is built as follows:
+//   - each condition is rendered like the String method of the filter does,
+//     with the values of In, NotIn, Contains, NotContains and Matches sorted;
+//   - the conditions and the sub filters of And are flattened, sorted and
+//     joined by " and ", without duplicates;
+//   - the sub filters of Or are rendered canonically, sorted, put in
+//     parentheses and joined by " or ", the whole being put in parentheses.
+//     An Or with a single sub filter is treated like an And, and an Or
+//     with an empty sub filter, that matches everything, is left out.
+//
+// A nil filter is represented as an empty string. The canonical form is
+// meant to be compared, and is not guaranteed to be parsable.
+func CanonicalFilterString(f *Filter) string {
+
+	if f == nil {
+		return ""
+	}
+
+	return strings.Join(canonicalParts(f), " and ")
+}
+
+// FiltersEqual returns true if the given filters have the same meaning,
+// regardless of the order in which their conditions, their sub filters and
+// the operands of their Or were added. Two filters are equal when they
+// have the same canonical form, as returned by CanonicalFilterString.
+// A nil filter is equal to an empty one.
+func FiltersEqual(a *Filter, b *Filter) bool {
+	return CanonicalFilterString(a) == CanonicalFilterString(b)
+}
+
+// canonicalParts returns the sorted and deduplicated canonical
+// representations of the conditions joined by and in the given filter.
+func canonicalParts(f *Filter) []string {
+
+	var parts []string
+
+	for i, operator := range f.Operators() {
+
+		switch operator {
+
+		case elemental.AndOperator:
+			parts = append(parts, canonicalCondition(f.Keys()[i], f.Comparators()[i], f.Values()[i]))
+
+		case elemental.AndFilterOperator:
+			for _, sub := range f.AndFilters()[i] {
+				parts = append(parts, canonicalParts(sub)...)
+			}
+
+		case elemental.OrFilterOperator:
+
+			var subs []string
+			var last *Filter
+			var all bool
+			for _, sub := range f.OrFilters()[i] {
+				s := CanonicalFilterString(sub)
+				if s == "" {
+					// An empty operand matches everything,
+					// and so does the whole Or.
+					all = true
+					break
+				}
+				subs = append(subs, s)
+				last = sub
+			}
+			if all {
+				continue
+			}
+			subs = sortUnique(subs)
+
+			switch len(subs) {
+			case 0:
+			case 1:
+				parts = append(parts, canonicalParts(last)...)
+			default:
+				parts = append(parts, "(("+strings.Join(subs, ") or (")+"))")
+			}
+		}
+	}
+
+	return sortUnique(parts)
+}
+
+func canonicalCondition(key string, comparator elemental.FilterComparator, values []interface{}) string {
+
+	switch comparator {
+	case elemental.InComparator,
+		elemental.NotInComparator,
+		elemental.ContainComparator,
+		elemental.NotContainComparator,
+		elemental.MatchComparator:
+
+		values = append([]interface{}(nil), values...)
+		sort.SliceStable(values, func(i, j int) bool {
+			return fmt.Sprintf("%T %v", values[i], values[i]) < fmt.Sprintf("%T %v", values[j], values[j])
+		})
+	}
+
+	c, ok := copyCondition(elemental.NewFilter().WithKey(key), comparator, values)
+	if !ok {
+		return fmt.Sprintf("%s %v %v", key, comparator, values)
+	}
+
+	return c.Done().String()
+}
+
+// sortUnique sorts the given strings and removes the duplicates.
+func sortUnique(items []string) []string {
+
+	sort.Strings(items)
+
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if len(out) > 0 && out[len(out)-1] == item {
+			continue
+		}
+		out = append(out, item)
+	}
+
+	return out
+}
+
+// DescribeFilter returns a human readable description of the given filter,
+// like "namespace is /acme and role is one of admin, owner".
+// Nested filters are put in parentheses when needed to keep
+// the description unambiguous. A nil filter is described as an empty string.
+//
+// The description is meant to be displayed to users and must not be parsed.
+// Use the String method of the filter to get its machine readable representation.
+func DescribeFilter(f *Filter) string {
+
+	if f == nil {
+		return ""
+	}
+
+	desc, _ := describeFilter(f, true)
+
+	return desc
+}
+
+// describeFilter returns the description of the given filter
+// and the number of conditions joined by "and" it is made of.
+func describeFilter(f *Filter, standalone bool) (string, int) {
+
+	operators := f.Operators()
+	parts := make([]string, 0, len(operators))
+
+	for i, operator := range operators {
+
+		switch operator {
+
+		case elemental.AndOperator:
+			parts = append(parts, describeCondition(f.Keys()[i], f.Comparators()[i], f.Values()[i]))
+
+		case elemental.AndFilterOperator:
+			for _, sub := range f.AndFilters()[i] {
+				if desc, _ := describeFilter(sub, false); desc != "" {
+					parts = append(parts, desc)
+				}
+			}
+
+		case elemental.OrFilterOperator:
+
+			descs := make([]string, 0, len(f.OrFilters()[i]))
+			for _, sub := range f.OrFilters()[i] {
+				desc, n := describeFilter(sub, false)
+				if desc == "" {
+					continue
+				}
+				if n > 1 {
+					desc = "(" + desc + ")"
+				}
+				descs = append(descs, desc)
+			}
+
+			desc := strings.Join(descs, " or ")
+			if len(descs) > 1 && !(standalone && len(operators) == 1) {
+				desc = "(" + desc + ")"
+			}
+
+			if desc != "" {
+				parts = append(parts, desc)
+			}
+		}
+	}
+
+	return strings.Join(parts, " and "), len(parts)
+}
+
+func describeCondition(key string, comparator elemental.FilterComparator, values []interface{}) string {
+
+	switch comparator {
+	case elemental.EqualComparator:
+		return fmt.Sprintf("%s is %s", key, describeValues(values[:1], ""))
+	case elemental.NotEqualComparator:
+		return fmt.Sprintf("%s is not %s", key, describeValues(values[:1], ""))
+	case elemental.GreaterComparator:
+		return fmt.Sprintf("%s is greater than %s", key, describeValues(values[:1], ""))
+	case elemental.GreaterOrEqualComparator:
+		return fmt.Sprintf("%s is at least %s", key, describeValues(values[:1], ""))
+	case elemental.LesserComparator:
+		return fmt.Sprintf("%s is less than %s", key, describeValues(values[:1], ""))
+	case elemental.LesserOrEqualComparator:
+		return fmt.Sprintf("%s is at most %s", key, describeValues(values[:1], ""))
+	case elemental.InComparator:
+		return fmt.Sprintf("%s is one of %s", key, describeValues(values, ", "))
+	case elemental.NotInComparator:
+		return fmt.Sprintf("%s is none of %s", key, describeValues(values, ", "))
+	case elemental.ContainComparator:
+		return fmt.Sprintf("%s contains %s", key, describeValues(values, " or "))
+	case elemental.NotContainComparator:
+		return fmt.Sprintf("%s does not contain %s", key, describeValues(values, " or "))
+	case elemental.MatchComparator:
+		return fmt.Sprintf("%s matches %s", key, describeValues(values, " or "))
+	case elemental.ExistsComparator:
+		return fmt.Sprintf("%s exists", key)
+	case elemental.NotExistsComparator:
+		return fmt.Sprintf("%s does not exist", key)
+	default:
+		return fmt.Sprintf("%s %v %s", key, comparator, describeValues(values, ", "))
+	}
+}
+
+func describeValues(values []interface{}, sep string) string {
+
+	descs := make([]string, len(values))
+
+	for i, value := range values {
+
+		switch v := value.(type) {
+
+		case time.Time:
+			descs[i] = v.Format(time.RFC3339)
+
+		case time.Duration:
+			if v < 0 {
+				descs[i] = fmt.Sprintf("%s ago", -v)
+			} else {
+				descs[i] = fmt.Sprintf("%s from now", v)
+			}
+
+		default:
+			descs[i] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	return strings.Join(descs, sep)
+}
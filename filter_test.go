@@ -13,6 +13,7 @@ package manipulate
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/elemental"
@@ -41,3 +42,407 @@ func TestNewFilter(t *testing.T) {
 		So(f, ShouldHaveSameTypeAs, elemental.NewFilterParser("a == a"))
 	})
 }
+
+func TestDescribeFilter(t *testing.T) {
+
+	Convey("Describing a nil filter should work", t, func() {
+		So(DescribeFilter(nil), ShouldEqual, "")
+	})
+
+	Convey("Describing a simple filter should work", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("namespace").Equals("/acme").
+			WithKey("role").In("admin", "owner").
+			Done()
+		So(DescribeFilter(f), ShouldEqual, "namespace is /acme and role is one of admin, owner")
+	})
+
+	Convey("Describing all the comparators should work", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("a").NotEquals("x").
+			WithKey("b").GreaterThan(1).
+			WithKey("c").GreaterOrEqualThan(2).
+			WithKey("d").LesserThan(3).
+			WithKey("e").LesserOrEqualThan(4).
+			WithKey("f").NotIn("x", "y").
+			WithKey("g").Contains("x", "y").
+			WithKey("h").NotContains("x").
+			WithKey("i").Matches("^x").
+			WithKey("j").Exists().
+			WithKey("k").NotExists().
+			WithKey("l").LesserThan(-time.Hour).
+			Done()
+		So(DescribeFilter(f), ShouldEqual, ""+
+			"a is not x and "+
+			"b is greater than 1 and "+
+			"c is at least 2 and "+
+			"d is less than 3 and "+
+			"e is at most 4 and "+
+			"f is none of x, y and "+
+			"g contains x or y and "+
+			"h does not contain x and "+
+			"i matches ^x and "+
+			"j exists and "+
+			"k does not exist and "+
+			"l is less than 1h0m0s ago",
+		)
+	})
+
+	Convey("Describing a top level or filter should work", t, func() {
+		f := elemental.NewFilterComposer().Or(
+			elemental.NewFilterComposer().WithKey("a").Equals("1").Done(),
+			elemental.NewFilterComposer().WithKey("b").Equals("2").Done(),
+		).Done()
+		So(DescribeFilter(f), ShouldEqual, "a is 1 or b is 2")
+	})
+
+	Convey("Describing nested filters should work", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("namespace").Equals("/acme").
+			Or(
+				elemental.NewFilterComposer().
+					WithKey("role").Equals("admin").
+					WithKey("enabled").Equals(true).
+					Done(),
+				elemental.NewFilterComposer().
+					And(
+						elemental.NewFilterComposer().WithKey("role").Equals("owner").Done(),
+						elemental.NewFilterComposer().WithKey("protected").Equals(false).Done(),
+					).
+					Done(),
+				elemental.NewFilterComposer().WithKey("name").Matches("^root").Done(),
+			).
+			Done()
+		So(DescribeFilter(f), ShouldEqual,
+			"namespace is /acme and ((role is admin and enabled is true) or (role is owner and protected is false) or name matches ^root)",
+		)
+	})
+}
+
+func TestCanonicalFilterString(t *testing.T) {
+
+	Convey("The canonical string of a nil filter should be empty", t, func() {
+		So(CanonicalFilterString(nil), ShouldEqual, "")
+	})
+
+	Convey("The canonical string of a single condition should be its string", t, func() {
+		f := elemental.NewFilterComposer().WithKey("a").Equals("1").Done()
+		So(CanonicalFilterString(f), ShouldEqual, f.String())
+	})
+
+	Convey("Conditions built in different orders should have the same canonical string", t, func() {
+		f1 := elemental.NewFilterComposer().
+			WithKey("a").Equals("1").
+			WithKey("b").In("x", "y").
+			Done()
+		f2 := elemental.NewFilterComposer().
+			WithKey("b").In("y", "x").
+			WithKey("a").Equals("1").
+			Done()
+		So(CanonicalFilterString(f1), ShouldEqual, CanonicalFilterString(f2))
+	})
+
+	Convey("Nested filters built in different orders should have the same canonical string", t, func() {
+		f1 := elemental.NewFilterComposer().
+			WithKey("namespace").Equals("/acme").
+			Or(
+				elemental.NewFilterComposer().WithKey("role").Equals("admin").WithKey("enabled").Equals(true).Done(),
+				elemental.NewFilterComposer().WithKey("name").Matches("^root", "^admin").Done(),
+			).
+			Done()
+		f2 := elemental.NewFilterComposer().
+			Or(
+				elemental.NewFilterComposer().WithKey("name").Matches("^admin", "^root").Done(),
+				elemental.NewFilterComposer().WithKey("enabled").Equals(true).WithKey("role").Equals("admin").Done(),
+			).
+			And(
+				elemental.NewFilterComposer().WithKey("namespace").Equals("/acme").Done(),
+			).
+			Done()
+		So(CanonicalFilterString(f1), ShouldEqual, CanonicalFilterString(f2))
+	})
+
+	Convey("An or with a single sub filter should have the same canonical string as the sub filter", t, func() {
+		f1 := elemental.NewFilterComposer().Or(
+			elemental.NewFilterComposer().WithKey("a").Equals("1").Done(),
+		).Done()
+		f2 := elemental.NewFilterComposer().Or(
+			elemental.NewFilterComposer().WithKey("a").Equals("1").Done(),
+			elemental.NewFilterComposer().WithKey("a").Equals("1").Done(),
+		).Done()
+		f3 := elemental.NewFilterComposer().WithKey("a").Equals("1").Done()
+		So(CanonicalFilterString(f1), ShouldEqual, CanonicalFilterString(f3))
+		So(CanonicalFilterString(f2), ShouldEqual, CanonicalFilterString(f3))
+	})
+
+	Convey("An or with an empty sub filter should match everything", t, func() {
+		f1 := elemental.NewFilterComposer().Or(
+			elemental.NewFilter(),
+			elemental.NewFilterComposer().WithKey("a").Equals("1").Done(),
+		).Done()
+		f2 := elemental.NewFilterComposer().
+			WithKey("b").Equals("2").
+			Or(
+				elemental.NewFilterComposer().WithKey("a").Equals("1").Done(),
+				elemental.NewFilter(),
+			).
+			Done()
+		f3 := elemental.NewFilterComposer().WithKey("b").Equals("2").Done()
+		So(CanonicalFilterString(f1), ShouldEqual, "")
+		So(CanonicalFilterString(f2), ShouldEqual, CanonicalFilterString(f3))
+		So(CanonicalFilterString(f1), ShouldNotEqual, CanonicalFilterString(elemental.NewFilterComposer().WithKey("a").Equals("1").Done()))
+	})
+
+	Convey("Different filters should have different canonical strings", t, func() {
+		f1 := elemental.NewFilterComposer().
+			WithKey("a").Equals("1").
+			WithKey("b").Equals("2").
+			Done()
+		f2 := elemental.NewFilterComposer().Or(
+			elemental.NewFilterComposer().WithKey("a").Equals("1").Done(),
+			elemental.NewFilterComposer().WithKey("b").Equals("2").Done(),
+		).Done()
+		So(CanonicalFilterString(f1), ShouldNotEqual, CanonicalFilterString(f2))
+	})
+}
+
+func TestFiltersEqual(t *testing.T) {
+
+	Convey("Nil and empty filters should be equal", t, func() {
+		So(FiltersEqual(nil, nil), ShouldBeTrue)
+		So(FiltersEqual(nil, elemental.NewFilter()), ShouldBeTrue)
+		So(FiltersEqual(nil, elemental.NewFilterComposer().WithKey("a").Equals(1).Done()), ShouldBeFalse)
+	})
+
+	Convey("An or with an empty sub filter should be equal to an empty filter", t, func() {
+		f := elemental.NewFilterComposer().Or(
+			elemental.NewFilterComposer().WithKey("a").Equals(1).Done(),
+			elemental.NewFilter(),
+		).Done()
+		So(FiltersEqual(f, nil), ShouldBeTrue)
+		So(FiltersEqual(f, elemental.NewFilterComposer().WithKey("a").Equals(1).Done()), ShouldBeFalse)
+	})
+
+	Convey("Filters with conditions in different orders should be equal", t, func() {
+		f1 := elemental.NewFilterComposer().
+			WithKey("a").Equals(1).
+			WithKey("b").Contains("x", "y").
+			Done()
+		f2 := elemental.NewFilterComposer().
+			WithKey("b").Contains("y", "x").
+			WithKey("a").Equals(1).
+			Done()
+		So(FiltersEqual(f1, f2), ShouldBeTrue)
+	})
+
+	Convey("Filters with nested operands in different orders should be equal", t, func() {
+		f1 := elemental.NewFilterComposer().Or(
+			elemental.NewFilterComposer().And(
+				elemental.NewFilterComposer().WithKey("a").Equals(1).Done(),
+				elemental.NewFilterComposer().Or(
+					elemental.NewFilterComposer().WithKey("b").Equals(2).Done(),
+					elemental.NewFilterComposer().WithKey("c").Equals(3).Done(),
+				).Done(),
+			).Done(),
+			elemental.NewFilterComposer().WithKey("d").Exists().Done(),
+		).Done()
+		f2 := elemental.NewFilterComposer().Or(
+			elemental.NewFilterComposer().WithKey("d").Exists().Done(),
+			elemental.NewFilterComposer().And(
+				elemental.NewFilterComposer().Or(
+					elemental.NewFilterComposer().WithKey("c").Equals(3).Done(),
+					elemental.NewFilterComposer().WithKey("b").Equals(2).Done(),
+				).Done(),
+				elemental.NewFilterComposer().WithKey("a").Equals(1).Done(),
+			).Done(),
+		).Done()
+		So(FiltersEqual(f1, f2), ShouldBeTrue)
+	})
+
+	Convey("Filters with different values should not be equal", t, func() {
+		f1 := elemental.NewFilterComposer().WithKey("a").Equals(1).Done()
+		f2 := elemental.NewFilterComposer().WithKey("a").Equals(2).Done()
+		So(FiltersEqual(f1, f2), ShouldBeFalse)
+	})
+
+	Convey("Filters with different comparators should not be equal", t, func() {
+		f1 := elemental.NewFilterComposer().WithKey("a").Equals(1).Done()
+		f2 := elemental.NewFilterComposer().WithKey("a").NotEquals(1).Done()
+		So(FiltersEqual(f1, f2), ShouldBeFalse)
+	})
+
+	Convey("Filters with nested operands moved across levels should not be equal", t, func() {
+		f1 := elemental.NewFilterComposer().
+			WithKey("a").Equals(1).
+			Or(
+				elemental.NewFilterComposer().WithKey("b").Equals(2).Done(),
+				elemental.NewFilterComposer().WithKey("c").Equals(3).Done(),
+			).
+			Done()
+		f2 := elemental.NewFilterComposer().
+			WithKey("b").Equals(2).
+			Or(
+				elemental.NewFilterComposer().WithKey("a").Equals(1).Done(),
+				elemental.NewFilterComposer().WithKey("c").Equals(3).Done(),
+			).
+			Done()
+		So(FiltersEqual(f1, f2), ShouldBeFalse)
+	})
+}
+
+func TestCopyFilter(t *testing.T) {
+
+	Convey("Copying a nil filter should work", t, func() {
+		So(CopyFilter(nil), ShouldBeNil)
+	})
+
+	Convey("Copying a filter with all the comparators should work", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("a").Equals("x").
+			WithKey("b").NotEquals("x").
+			WithKey("c").GreaterThan(1).
+			WithKey("d").GreaterOrEqualThan(2).
+			WithKey("e").LesserThan(3).
+			WithKey("f").LesserOrEqualThan(4).
+			WithKey("g").In("x", "y").
+			WithKey("h").NotIn("x", "y").
+			WithKey("i").Contains("x", "y").
+			WithKey("j").NotContains("x").
+			WithKey("k").Matches("^x").
+			WithKey("l").Exists().
+			WithKey("m").NotExists().
+			Done()
+		c := CopyFilter(f)
+		So(c, ShouldNotEqual, f)
+		So(c.String(), ShouldEqual, f.String())
+	})
+
+	Convey("Copying nested filters should work", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("namespace").Equals("/acme").
+			Or(
+				elemental.NewFilterComposer().WithKey("role").In("admin", "owner").Done(),
+				elemental.NewFilterComposer().
+					And(
+						elemental.NewFilterComposer().WithKey("protected").Equals(false).Done(),
+					).
+					Done(),
+			).
+			Done()
+		c := CopyFilter(f)
+		So(c.String(), ShouldEqual, f.String())
+		So(c.OrFilters()[1][0], ShouldNotEqual, f.OrFilters()[1][0])
+		So(c.OrFilters()[1][1].AndFilters()[0][0], ShouldNotEqual, f.OrFilters()[1][1].AndFilters()[0][0])
+	})
+
+	Convey("Mutating the values of a copy should not affect the original", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("role").In("admin", "owner").
+			Or(
+				elemental.NewFilterComposer().WithKey("name").Equals("a").Done(),
+			).
+			Done()
+		original := f.String()
+
+		c := CopyFilter(f)
+		c.Values()[0][1] = "changed"
+		c.OrFilters()[1][0].Values()[0][0] = "changed"
+		c.WithKey("other").Equals("value")
+
+		So(f.String(), ShouldEqual, original)
+		So(c.String(), ShouldNotEqual, original)
+	})
+}
+
+func Test_copyCondition(t *testing.T) {
+
+	Convey("Copying a condition with an unknown comparator should fail", t, func() {
+		_, ok := copyCondition(elemental.NewFilter().WithKey("a"), elemental.FilterComparator(1000), []interface{}{"x"})
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Copying a condition missing its value should fail", t, func() {
+		_, ok := copyCondition(elemental.NewFilter().WithKey("a"), elemental.EqualComparator, nil)
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Copying a valid condition should work", t, func() {
+		c, ok := copyCondition(elemental.NewFilter().WithKey("a"), elemental.EqualComparator, []interface{}{"x"})
+		So(ok, ShouldBeTrue)
+		So(c.Done().String(), ShouldEqual, `a == "x"`)
+	})
+}
+
+type testAttributeSpecifiable map[string]elemental.AttributeSpecification
+
+func (s testAttributeSpecifiable) SpecificationForAttribute(name string) elemental.AttributeSpecification {
+	return s[name]
+}
+
+func (s testAttributeSpecifiable) AttributeSpecifications() map[string]elemental.AttributeSpecification {
+	return s
+}
+
+func (s testAttributeSpecifiable) ValueForAttribute(string) interface{} {
+	return nil
+}
+
+func TestValidateFilter(t *testing.T) {
+
+	spec := testAttributeSpecifiable{
+		"name":        {Name: "name", Type: "string"},
+		"count":       {Name: "count", Type: "integer"},
+		"enabled":     {Name: "enabled", Type: "boolean"},
+		"annotations": {Name: "annotations", Type: "external"},
+	}
+
+	Convey("Validating with a nil spec should panic", t, func() {
+		So(func() { _ = ValidateFilter(nil, nil) }, ShouldPanicWith, "spec must not be nil")
+	})
+
+	Convey("Validating a nil filter should work", t, func() {
+		So(ValidateFilter(nil, spec), ShouldBeNil)
+	})
+
+	Convey("Validating a valid filter should work", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("name").Matches("^a").
+			WithKey("count").GreaterThan(2).
+			WithKey("annotations.key").Contains("value").
+			Or(
+				elemental.NewFilterComposer().WithKey("enabled").Equals(true).Done(),
+				elemental.NewFilterComposer().WithKey("name").Exists().Done(),
+			).
+			Done()
+		So(ValidateFilter(f, spec), ShouldBeNil)
+	})
+
+	Convey("Validating a filter with unknown keys should fail", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("nmae").Equals("a").
+			WithKey("nmae").Equals("b").
+			Or(
+				elemental.NewFilterComposer().WithKey("enabled").Equals(true).Done(),
+				elemental.NewFilterComposer().
+					And(
+						elemental.NewFilterComposer().WithKey("other.key").Equals(1).Done(),
+					).
+					Done(),
+			).
+			Done()
+		err := ValidateFilter(f, spec)
+		So(err, ShouldHaveSameTypeAs, ErrInvalidQuery{})
+		So(err.(ErrInvalidQuery).DueToFilter, ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "Query invalid: invalid filter: unknown attribute 'nmae', unknown attribute 'other'")
+	})
+
+	Convey("Validating a filter with incompatible comparators should fail", t, func() {
+		f := elemental.NewFilterComposer().
+			WithKey("enabled").GreaterThan(1).
+			WithKey("count").Matches("^1").
+			Done()
+		err := ValidateFilter(f, spec)
+		So(err, ShouldHaveSameTypeAs, ErrInvalidQuery{})
+		So(err.Error(), ShouldEqual, "Query invalid: invalid filter: attribute 'enabled' of type boolean cannot be ordered, attribute 'count' of type integer cannot be matched")
+	})
+}
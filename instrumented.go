@@ -0,0 +1,169 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"go.aporeto.io/elemental"
+	"go.uber.org/zap"
+)
+
+type instrumentedConfig struct {
+	logger  *zap.Logger
+	tracing bool
+}
+
+// An InstrumentedOption can be given to NewInstrumentedManipulator to alter its behavior.
+type InstrumentedOption func(*instrumentedConfig)
+
+// InstrumentedOptionLogger sets the logger to use.
+// The default is the global zap logger.
+func InstrumentedOptionLogger(logger *zap.Logger) InstrumentedOption {
+	return func(c *instrumentedConfig) {
+		c.logger = logger
+	}
+}
+
+// InstrumentedOptionTracing enables or disables the creation
+// of an opentracing span for each operation. It is enabled by default.
+func InstrumentedOptionTracing(enabled bool) InstrumentedOption {
+	return func(c *instrumentedConfig) {
+		c.tracing = enabled
+	}
+}
+
+type instrumentedManipulator struct {
+	Manipulator
+	cfg instrumentedConfig
+}
+
+// NewInstrumentedManipulator returns a Manipulator that logs every operation
+// performed by the given Manipulator, with its identity, duration and error,
+// at the debug level.
+//
+// It also starts an opentracing span for each operation, as a child of the span
+// carried by the context.Context of the manipulate.Context, if any. The span is
+// named after the operation and the category of the identity, like
+// manipulate.retrieve-many.lists.
+func NewInstrumentedManipulator(manipulator Manipulator, options ...InstrumentedOption) Manipulator {
+
+	if manipulator == nil {
+		panic("manipulator must not be nil")
+	}
+
+	cfg := instrumentedConfig{
+		tracing: true,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	return &instrumentedManipulator{
+		Manipulator: manipulator,
+		cfg:         cfg,
+	}
+}
+
+func (m *instrumentedManipulator) RetrieveMany(mctx Context, dest elemental.Identifiables) error {
+	done := m.start(mctx, elemental.OperationRetrieveMany, dest.Identity())
+	return done(m.Manipulator.RetrieveMany(mctx, dest))
+}
+
+func (m *instrumentedManipulator) Retrieve(mctx Context, object elemental.Identifiable) error {
+	done := m.start(mctx, elemental.OperationRetrieve, object.Identity())
+	return done(m.Manipulator.Retrieve(mctx, object))
+}
+
+func (m *instrumentedManipulator) Create(mctx Context, object elemental.Identifiable) error {
+	done := m.start(mctx, elemental.OperationCreate, object.Identity())
+	return done(m.Manipulator.Create(mctx, object))
+}
+
+func (m *instrumentedManipulator) Update(mctx Context, object elemental.Identifiable) error {
+	done := m.start(mctx, elemental.OperationUpdate, object.Identity())
+	return done(m.Manipulator.Update(mctx, object))
+}
+
+func (m *instrumentedManipulator) Delete(mctx Context, object elemental.Identifiable) error {
+	done := m.start(mctx, elemental.OperationDelete, object.Identity())
+	return done(m.Manipulator.Delete(mctx, object))
+}
+
+func (m *instrumentedManipulator) DeleteMany(mctx Context, identity elemental.Identity) error {
+	done := m.start(mctx, elemental.OperationDelete, identity)
+	return done(m.Manipulator.DeleteMany(mctx, identity))
+}
+
+func (m *instrumentedManipulator) Count(mctx Context, identity elemental.Identity) (int, error) {
+	done := m.start(mctx, elemental.OperationInfo, identity)
+	n, err := m.Manipulator.Count(mctx, identity)
+	return n, done(err)
+}
+
+// start starts instrumenting the given operation. It returns
+// a function that must be called with the result of the operation
+// and that returns it unchanged.
+func (m *instrumentedManipulator) start(mctx Context, operation elemental.Operation, identity elemental.Identity) func(error) error {
+
+	start := time.Now()
+
+	var sp opentracing.Span
+	if m.cfg.tracing {
+
+		ctx := context.Background()
+		if mctx != nil {
+			ctx = mctx.Context()
+		}
+
+		sp, _ = opentracing.StartSpanFromContext(ctx, fmt.Sprintf("manipulate.%s.%s", operation, identity.Category))
+		sp.SetTag("manipulate.identity", identity.Name)
+		if mctx != nil && mctx.Namespace() != "" {
+			sp.SetTag("manipulate.context.namespace", mctx.Namespace())
+		}
+	}
+
+	return func(err error) error {
+
+		duration := time.Since(start)
+
+		if sp != nil {
+			if err != nil {
+				sp.SetTag("error", true)
+				sp.LogFields(log.Error(err))
+			}
+			sp.Finish()
+		}
+
+		logger := m.cfg.logger
+		if logger == nil {
+			logger = zap.L()
+		}
+
+		fields := []zap.Field{
+			zap.String("operation", string(operation)),
+			zap.String("identity", identity.Name),
+			zap.Duration("duration", duration),
+		}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		}
+
+		logger.Debug("Manipulator operation", fields...)
+
+		return err
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	. "github.com/smartystreets/goconvey/convey"
+	testmodel "go.aporeto.io/elemental/test/model"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestInstrumentedManipulator(t *testing.T) {
+
+	Convey("Calling NewInstrumentedManipulator with a nil manipulator should panic", t, func() {
+		So(func() { NewInstrumentedManipulator(nil) }, ShouldPanicWith, "manipulator must not be nil")
+	})
+
+	Convey("Given I have an instrumented manipulator", t, func() {
+
+		tracer := mocktracer.New()
+		opentracing.SetGlobalTracer(tracer)
+		defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+		core, logs := observer.New(zapcore.DebugLevel)
+
+		fm := &failingManipulator{}
+		m := NewInstrumentedManipulator(fm, InstrumentedOptionLogger(zap.New(core)))
+
+		Convey("When I perform all operations", func() {
+
+			mctx := NewContext(context.Background(), ContextOptionNamespace("/a"))
+
+			So(m.RetrieveMany(mctx, &testmodel.ListsList{}), ShouldBeNil)
+			So(m.Retrieve(mctx, testmodel.NewList()), ShouldBeNil)
+			So(m.Create(mctx, testmodel.NewList()), ShouldBeNil)
+			So(m.Update(mctx, testmodel.NewList()), ShouldBeNil)
+			So(m.Delete(nil, testmodel.NewList()), ShouldBeNil)
+			So(m.DeleteMany(nil, testmodel.ListIdentity), ShouldBeNil)
+			_, err := m.Count(nil, testmodel.ListIdentity)
+			So(err, ShouldBeNil)
+
+			Convey("Then a span should have been finished for each", func() {
+				spans := tracer.FinishedSpans()
+				So(len(spans), ShouldEqual, 7)
+				So(spans[0].OperationName, ShouldEqual, "manipulate.retrieve-many.lists")
+				So(spans[0].Tag("manipulate.identity"), ShouldEqual, "list")
+				So(spans[0].Tag("manipulate.context.namespace"), ShouldEqual, "/a")
+				So(spans[1].OperationName, ShouldEqual, "manipulate.retrieve.lists")
+				So(spans[2].OperationName, ShouldEqual, "manipulate.create.lists")
+				So(spans[3].OperationName, ShouldEqual, "manipulate.update.lists")
+				So(spans[4].OperationName, ShouldEqual, "manipulate.delete.lists")
+				So(spans[5].OperationName, ShouldEqual, "manipulate.delete.lists")
+				So(spans[6].OperationName, ShouldEqual, "manipulate.info.lists")
+			})
+
+			Convey("Then each operation should have been logged", func() {
+				So(logs.Len(), ShouldEqual, 7)
+				entry := logs.All()[0]
+				So(entry.Level, ShouldEqual, zapcore.DebugLevel)
+				So(entry.ContextMap()["operation"], ShouldEqual, "retrieve-many")
+				So(entry.ContextMap()["identity"], ShouldEqual, "list")
+				So(entry.ContextMap(), ShouldContainKey, "duration")
+				So(entry.ContextMap(), ShouldNotContainKey, "error")
+			})
+		})
+
+		Convey("When an operation fails", func() {
+
+			fm.err = errors.New("boom")
+			err := m.Create(nil, testmodel.NewList())
+
+			Convey("Then the error should be returned", func() {
+				So(err, ShouldEqual, fm.err)
+			})
+
+			Convey("Then the span should be marked as failed", func() {
+				spans := tracer.FinishedSpans()
+				So(len(spans), ShouldEqual, 1)
+				So(spans[0].Tag("error"), ShouldEqual, true)
+			})
+
+			Convey("Then the error should be logged", func() {
+				So(logs.Len(), ShouldEqual, 1)
+				So(logs.All()[0].ContextMap()["error"], ShouldEqual, "boom")
+			})
+		})
+	})
+
+	Convey("Given I have an instrumented manipulator without tracing", t, func() {
+
+		tracer := mocktracer.New()
+		opentracing.SetGlobalTracer(tracer)
+		defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+		m := NewInstrumentedManipulator(&failingManipulator{}, InstrumentedOptionTracing(false))
+
+		Convey("When I perform an operation", func() {
+
+			So(m.Create(nil, testmodel.NewList()), ShouldBeNil)
+
+			Convey("Then no span should have been started", func() {
+				So(len(tracer.FinishedSpans()), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -1,9 +1,18 @@
 package backoff
 
 import (
+	"math/rand"
+	"sync"
 	"time"
 )
 
+// The global source of math/rand is deterministic unless the program
+// seeds it, which would make every client wait for the same durations.
+var (
+	random     = rand.New(rand.NewSource(time.Now().UnixNano()))
+	randomLock sync.Mutex
+)
+
 // NextWithCurve computes the next backoff time for a given try number,
 // optional (non zero) hard deadline using the given backoffs curve.
 func NextWithCurve(try int, deadline time.Time, curve []time.Duration) time.Duration {
@@ -30,3 +39,19 @@ func NextWithCurve(try int, deadline time.Time, curve []time.Duration) time.Dura
 
 	return wait
 }
+
+// Jitter returns a random duration between 0 and the given one.
+// Applying it to the backoff durations spreads the retries of clients
+// that failed at the same time, instead of having all of them retrying
+// on the same schedule.
+func Jitter(d time.Duration) time.Duration {
+
+	if d <= 0 {
+		return 0
+	}
+
+	randomLock.Lock()
+	defer randomLock.Unlock()
+
+	return time.Duration(random.Int63n(int64(d) + 1))
+}
@@ -113,3 +113,28 @@ func TestNext(t *testing.T) {
 		})
 	}
 }
+
+func TestJitter(t *testing.T) {
+
+	curve := []time.Duration{1 * time.Second, 2 * time.Second}
+
+	if got := Jitter(0); got != 0 {
+		t.Errorf("Jitter(0) = %v, want 0", got)
+	}
+
+	if got := Jitter(-time.Second); got != 0 {
+		t.Errorf("Jitter(-1s) = %v, want 0", got)
+	}
+
+	wait := NextWithCurve(1, time.Time{}, curve)
+	d1 := Jitter(wait)
+	d2 := Jitter(wait)
+
+	if d1 < 0 || d1 > wait || d2 < 0 || d2 > wait {
+		t.Errorf("Jitter() = %v and %v, want values between 0 and %v", d1, d2, wait)
+	}
+
+	if d1 == d2 {
+		t.Errorf("Jitter() returned %v twice, want different values", d1)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"go.aporeto.io/elemental"
+)
+
+// A coalescer buffers events and collapses the
+// ones targeting the same object into the most recent one.
+// It is not safe for concurrent use.
+type coalescer struct {
+	pending map[string]*elemental.Event
+	keys    []string
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{
+		pending: map[string]*elemental.Event{},
+	}
+}
+
+// add buffers the given event and returns the
+// events that must be published right away.
+func (c *coalescer) add(evt *elemental.Event) []*elemental.Event {
+
+	switch evt.Type {
+	case elemental.EventCreate, elemental.EventUpdate, elemental.EventDelete:
+	default:
+		return []*elemental.Event{evt}
+	}
+
+	key := coalesceKey(evt)
+	if key == "" {
+		return []*elemental.Event{evt}
+	}
+
+	previous, ok := c.pending[key]
+	if !ok {
+		c.pending[key] = evt
+		c.keys = append(c.keys, key)
+		return nil
+	}
+
+	switch {
+
+	case previous.Type == elemental.EventDelete && evt.Type != elemental.EventDelete:
+		// The object has been recreated after being deleted.
+		// The delete must not be lost, nor be published ahead of
+		// the events received before it, so we publish everything
+		// pending now, and only buffer the new event.
+		out := c.flush()
+		c.pending[key] = evt
+		c.keys = append(c.keys, key)
+		return out
+
+	case previous.Type == elemental.EventCreate && evt.Type == elemental.EventUpdate:
+		// The subscriber never saw the creation, so we keep
+		// the create type with the most recent state.
+		merged := evt.Duplicate()
+		merged.Type = elemental.EventCreate
+		c.pending[key] = merged
+
+	default:
+		c.pending[key] = evt
+	}
+
+	return nil
+}
+
+// flush returns all the pending events in
+// the order they have been received and resets the buffer.
+func (c *coalescer) flush() []*elemental.Event {
+
+	if len(c.keys) == 0 {
+		return nil
+	}
+
+	out := make([]*elemental.Event, len(c.keys))
+	for i, k := range c.keys {
+		out[i] = c.pending[k]
+	}
+
+	c.pending = map[string]*elemental.Event{}
+	c.keys = nil
+
+	return out
+}
+
+func coalesceKey(evt *elemental.Event) string {
+
+	o := map[string]interface{}{}
+	if err := evt.Decode(&o); err != nil {
+		return ""
+	}
+
+	id, _ := o["ID"].(string)
+	if id == "" {
+		id, _ = o["id"].(string)
+	}
+
+	if id == "" {
+		return ""
+	}
+
+	return evt.Identity + "/" + id
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"testing"
+
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+func makeEvent(t elemental.EventType, id string, name string) *elemental.Event {
+	return elemental.NewEvent(t, &testmodel.List{ID: id, Name: name})
+}
+
+func Test_coalescer(t *testing.T) {
+
+	type result struct {
+		typ  elemental.EventType
+		name string
+	}
+
+	tests := []struct {
+		name      string
+		events    []*elemental.Event
+		immediate []result
+		flushed   []result
+	}{
+		{
+			"multiple updates of the same object",
+			[]*elemental.Event{
+				makeEvent(elemental.EventUpdate, "1", "a"),
+				makeEvent(elemental.EventUpdate, "1", "b"),
+				makeEvent(elemental.EventUpdate, "1", "c"),
+			},
+			nil,
+			[]result{{elemental.EventUpdate, "c"}},
+		},
+		{
+			"updates of different objects",
+			[]*elemental.Event{
+				makeEvent(elemental.EventUpdate, "1", "a"),
+				makeEvent(elemental.EventUpdate, "2", "b"),
+				makeEvent(elemental.EventUpdate, "1", "c"),
+			},
+			nil,
+			[]result{{elemental.EventUpdate, "c"}, {elemental.EventUpdate, "b"}},
+		},
+		{
+			"create followed by updates",
+			[]*elemental.Event{
+				makeEvent(elemental.EventCreate, "1", "a"),
+				makeEvent(elemental.EventUpdate, "1", "b"),
+			},
+			nil,
+			[]result{{elemental.EventCreate, "b"}},
+		},
+		{
+			"update followed by delete",
+			[]*elemental.Event{
+				makeEvent(elemental.EventUpdate, "1", "a"),
+				makeEvent(elemental.EventDelete, "1", "b"),
+			},
+			nil,
+			[]result{{elemental.EventDelete, "b"}},
+		},
+		{
+			"delete followed by update",
+			[]*elemental.Event{
+				makeEvent(elemental.EventDelete, "1", "a"),
+				makeEvent(elemental.EventCreate, "1", "b"),
+				makeEvent(elemental.EventUpdate, "1", "c"),
+			},
+			[]result{{elemental.EventDelete, "a"}},
+			[]result{{elemental.EventCreate, "c"}},
+		},
+		{
+			"delete followed by create with other pending objects",
+			[]*elemental.Event{
+				makeEvent(elemental.EventUpdate, "2", "x"),
+				makeEvent(elemental.EventDelete, "1", "a"),
+				makeEvent(elemental.EventUpdate, "3", "y"),
+				makeEvent(elemental.EventCreate, "1", "b"),
+				makeEvent(elemental.EventUpdate, "2", "z"),
+			},
+			[]result{{elemental.EventUpdate, "x"}, {elemental.EventDelete, "a"}, {elemental.EventUpdate, "y"}},
+			[]result{{elemental.EventCreate, "b"}, {elemental.EventUpdate, "z"}},
+		},
+		{
+			"event without identifier",
+			[]*elemental.Event{
+				makeEvent(elemental.EventUpdate, "", "a"),
+			},
+			[]result{{elemental.EventUpdate, "a"}},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			check := func(kind string, got []*elemental.Event, want []result) {
+
+				if len(got) != len(want) {
+					t.Fatalf("%s: got %d events, want %d", kind, len(got), len(want))
+				}
+
+				for i, evt := range got {
+					o := &testmodel.List{}
+					if err := evt.Decode(o); err != nil {
+						t.Fatalf("%s: unable to decode event: %s", kind, err)
+					}
+					if evt.Type != want[i].typ || o.Name != want[i].name {
+						t.Errorf("%s: event %d = %s %s, want %s %s", kind, i, evt.Type, o.Name, want[i].typ, want[i].name)
+					}
+				}
+			}
+
+			c := newCoalescer()
+
+			var immediate []*elemental.Event
+			for _, evt := range tt.events {
+				immediate = append(immediate, c.add(evt)...)
+			}
+
+			check("immediate", immediate, tt.immediate)
+			check("flushed", c.flush(), tt.flushed)
+
+			if out := c.flush(); len(out) != 0 {
+				t.Errorf("second flush returned %d events, want 0", len(out))
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"context"
+	"time"
+
+	"go.aporeto.io/elemental"
+)
+
+type config struct {
+	coalesceWindow time.Duration
+	reorderWindow  time.Duration
+	lagFunc        func(time.Duration, *elemental.Event)
+	pingPeriod     time.Duration
+	pongWait       time.Duration
+	filter         *elemental.Filter
+	tokenRenewer   func(context.Context) error
+}
+
+func newConfig() config {
+	return config{
+		pingPeriod: 5 * time.Second,
+		pongWait:   10 * time.Second,
+	}
+}
+
+// An Option represents an option for NewSubscriber.
+type Option func(*config)
+
+// OptionCoalesceWindow enables the coalescing of events.
+// Events received within the given window that target the same
+// object are collapsed into the most recent one. A zero window
+// disables coalescing.
+func OptionCoalesceWindow(window time.Duration) Option {
+	return func(c *config) {
+		c.coalesceWindow = window
+	}
+}
+
+// OptionReorderWindow enables the reordering of events by timestamp.
+// Events are buffered for at least the given window, and at most twice
+// that, then released sorted by timestamp. A zero window disables reordering.
+func OptionReorderWindow(window time.Duration) Option {
+	return func(c *config) {
+		c.reorderWindow = window
+	}
+}
+
+// OptionLagFunc sets a function that will be called with the lag of
+// every event received, computed as the time elapsed since the timestamp
+// of the event. Events without timestamp are ignored.
+func OptionLagFunc(f func(lag time.Duration, event *elemental.Event)) Option {
+	return func(c *config) {
+		c.lagFunc = f
+	}
+}
+
+// OptionKeepAlive sets the interval between the pings sent to the server
+// and how long to wait for a pong before considering the connection dead.
+// When it happens, the subscriber reconnects. The default is to ping every
+// 5 seconds and to wait 10 seconds for a pong.
+func OptionKeepAlive(pingPeriod time.Duration, pongWait time.Duration) Option {
+	return func(c *config) {
+		c.pingPeriod = pingPeriod
+		c.pongWait = pongWait
+	}
+}
+
+// OptionFilter sets a filter that is sent to the server when
+// connecting so it only pushes the events matching it.
+func OptionFilter(filter *elemental.Filter) Option {
+	return func(c *config) {
+		c.filter = filter
+	}
+}
+
+// OptionTokenRenewer sets a function that renews the token when the
+// server rejects it while connecting. The new token must be given to the
+// token notifier before the function returns. The subscriber then
+// reconnects right away instead of retrying with the rejected token.
+func OptionTokenRenewer(f func(context.Context) error) Option {
+	return func(c *config) {
+		c.tokenRenewer = f
+	}
+}
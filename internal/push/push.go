@@ -54,6 +54,11 @@ type subscription struct {
 	readEncoding            elemental.EncodingType
 	writeEncoding           elemental.EncodingType
 	credsInTokenKey         string
+	coalesceWindow          time.Duration
+	reorderWindow           time.Duration
+	lagFunc                 func(time.Duration, *elemental.Event)
+	queryFilter             string
+	tokenRenewer            func(context.Context) error
 }
 
 // NewSubscriber creates a new Subscription.
@@ -68,8 +73,14 @@ func NewSubscriber(
 	supportErrorEvents bool,
 	recursive bool,
 	credsInTokenKey string,
+	options ...Option,
 ) manipulate.Subscriber {
 
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
 	if headers == nil {
 		headers = http.Header{}
 	}
@@ -79,6 +90,11 @@ func NewSubscriber(
 		panic(err)
 	}
 
+	var queryFilter string
+	if cfg.filter != nil {
+		queryFilter = cfg.filter.String()
+	}
+
 	return &subscription{
 		id:                      uuid.Must(uuid.NewV4()).String(),
 		url:                     url,
@@ -97,10 +113,15 @@ func NewSubscriber(
 		readEncoding:            readEncoding,
 		writeEncoding:           writeEncoding,
 		credsInTokenKey:         credsInTokenKey,
+		coalesceWindow:          cfg.coalesceWindow,
+		reorderWindow:           cfg.reorderWindow,
+		lagFunc:                 cfg.lagFunc,
+		queryFilter:             queryFilter,
+		tokenRenewer:            cfg.tokenRenewer,
 		config: wsc.Config{
-			PongWait:     10 * time.Second,
+			PongWait:     cfg.pongWait,
 			WriteWait:    10 * time.Second,
-			PingPeriod:   5 * time.Second,
+			PingPeriod:   cfg.pingPeriod,
 			ReadChanSize: 2048,
 			TLSConfig:    tlsConfig,
 			Headers:      headers,
@@ -137,6 +158,7 @@ func (s *subscription) connect(ctx context.Context, initial bool) (err error) {
 
 	var resp *http.Response
 	var try int
+	var renewed bool
 
 	for {
 
@@ -151,9 +173,9 @@ func (s *subscription) connect(ctx context.Context, initial bool) (err error) {
 		var url string
 		switch s.credsInTokenKey {
 		case "":
-			url = makeURL(s.url, s.ns, s.getCurrentToken(), s.recursive, s.supportErrorEvents)
+			url = makeURL(s.url, s.ns, s.getCurrentToken(), s.recursive, s.supportErrorEvents, s.queryFilter)
 		default:
-			url = makeURL(s.url, s.ns, "", s.recursive, s.supportErrorEvents)
+			url = makeURL(s.url, s.ns, "", s.recursive, s.supportErrorEvents, s.queryFilter)
 			s.config.Headers.Set("Cookie", fmt.Sprintf("%s=%s", s.credsInTokenKey, s.getCurrentToken()))
 		}
 
@@ -182,6 +204,17 @@ func (s *subscription) connect(ctx context.Context, initial bool) (err error) {
 			s.errors <- decodeErrors(resp.Body, s.writeEncoding)
 		}
 
+		// If the token has been rejected, we renew it once and retry
+		// right away, instead of retrying with the rejected token.
+		if resp != nil && !renewed && s.tokenRenewer != nil &&
+			(resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+
+			renewed = true
+			if err := s.tokenRenewer(ctx); err == nil {
+				continue
+			}
+		}
+
 		select {
 		case <-time.After(nextBackoff(try)):
 		case <-ctx.Done():
@@ -197,6 +230,36 @@ func (s *subscription) listen(ctx context.Context) {
 	var isReconnection bool
 	var filterData []byte
 
+	var coalescer *coalescer
+	var coalesceTick <-chan time.Time
+	if s.coalesceWindow > 0 {
+		coalescer = newCoalescer()
+		ticker := time.NewTicker(s.coalesceWindow)
+		defer ticker.Stop()
+		coalesceTick = ticker.C
+	}
+
+	var reorderer *reorderer
+	var reorderTick <-chan time.Time
+	if s.reorderWindow > 0 {
+		reorderer = newReorderer(s.reorderWindow)
+		ticker := time.NewTicker(s.reorderWindow)
+		defer ticker.Stop()
+		reorderTick = ticker.C
+	}
+
+	deliver := func(evt *elemental.Event) {
+
+		if coalescer == nil {
+			s.publishEvent(evt)
+			return
+		}
+
+		for _, e := range coalescer.add(evt) {
+			s.publishEvent(e)
+		}
+	}
+
 	for {
 
 		if err = s.connect(ctx, !isReconnection); err != nil {
@@ -236,7 +299,30 @@ func (s *subscription) listen(ctx context.Context) {
 					continue
 				}
 
-				s.publishEvent(event)
+				if s.lagFunc != nil {
+					if lag, ok := eventLag(event, time.Now()); ok {
+						s.lagFunc(lag, event)
+					}
+				}
+
+				if reorderer != nil {
+					reorderer.add(event, time.Now())
+					continue
+				}
+
+				deliver(event)
+
+			case <-reorderTick:
+
+				for _, evt := range reorderer.flush(time.Now()) {
+					deliver(evt)
+				}
+
+			case <-coalesceTick:
+
+				for _, evt := range coalescer.flush() {
+					s.publishEvent(evt)
+				}
 
 			case err = <-s.conn.Error():
 				s.publishError(err)
@@ -0,0 +1,103 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.aporeto.io/manipulate"
+)
+
+func Test_subscriptionRenewsRejectedToken(t *testing.T) {
+
+	upgrader := websocket.Upgrader{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Query().Get("token") != "new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close() // nolint
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	var notifier func(string)
+	var notifierLock sync.Mutex
+	var renewed int32
+
+	s := NewSubscriber(
+		strings.Replace(ts.URL, "http://", "ws://", 1),
+		"/ns",
+		"old",
+		func(_ string, f func(string)) {
+			notifierLock.Lock()
+			notifier = f
+			notifierLock.Unlock()
+		},
+		func(string) {},
+		nil,
+		nil,
+		false,
+		false,
+		"",
+		OptionTokenRenewer(func(context.Context) error {
+			atomic.AddInt32(&renewed, 1)
+			notifierLock.Lock()
+			notifier("new")
+			notifierLock.Unlock()
+			return nil
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.Start(ctx, nil)
+
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case st := <-s.Status():
+			switch st {
+			case manipulate.SubscriberStatusInitialConnection:
+				if n := atomic.LoadInt32(&renewed); n != 1 {
+					t.Errorf("token renewed %d times, want 1", n)
+				}
+				return
+			case manipulate.SubscriberStatusFinalDisconnection:
+				t.Fatal("unexpected final disconnection")
+			}
+		case <-timeout:
+			t.Fatal("subscriber did not connect using the renewed token")
+		}
+	}
+}
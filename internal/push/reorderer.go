@@ -0,0 +1,81 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"sort"
+	"time"
+
+	"go.aporeto.io/elemental"
+)
+
+type pendingEvent struct {
+	evt      *elemental.Event
+	received time.Time
+}
+
+// A reorderer buffers events for a given window and releases
+// them sorted by timestamp. It is not safe for concurrent use.
+type reorderer struct {
+	window  time.Duration
+	pending []pendingEvent
+}
+
+func newReorderer(window time.Duration) *reorderer {
+	return &reorderer{
+		window: window,
+	}
+}
+
+// add buffers the given event, received at the given time.
+func (r *reorderer) add(evt *elemental.Event, now time.Time) {
+	r.pending = append(r.pending, pendingEvent{evt: evt, received: now})
+}
+
+// flush returns the events that have been buffered for at least
+// the window at the given time, along with all the buffered events
+// with an older timestamp, sorted by timestamp.
+func (r *reorderer) flush(now time.Time) []*elemental.Event {
+
+	var cutoff time.Time
+	var due bool
+
+	for _, p := range r.pending {
+		if now.Sub(p.received) >= r.window {
+			due = true
+			if p.evt.Timestamp.After(cutoff) {
+				cutoff = p.evt.Timestamp
+			}
+		}
+	}
+
+	if !due {
+		return nil
+	}
+
+	var out []*elemental.Event
+	kept := r.pending[:0]
+
+	for _, p := range r.pending {
+		if p.evt.Timestamp.After(cutoff) {
+			kept = append(kept, p)
+			continue
+		}
+		out = append(out, p.evt)
+	}
+
+	r.pending = kept
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+
+	return out
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.aporeto.io/elemental"
+)
+
+func Test_reorderer(t *testing.T) {
+
+	base := time.Now()
+
+	makeTimedEvent := func(name string, ts int) *elemental.Event {
+		evt := makeEvent(elemental.EventUpdate, name, name)
+		evt.Timestamp = base.Add(time.Duration(ts) * time.Second)
+		return evt
+	}
+
+	type received struct {
+		name string
+		ts   int
+		at   int // in milliseconds after base
+	}
+
+	tests := []struct {
+		name    string
+		events  []received
+		flushAt int // in milliseconds after base
+		flushed []string
+		kept    int
+	}{
+		{
+			"nothing due",
+			[]received{
+				{"a", 1, 0},
+				{"b", 2, 50},
+			},
+			90,
+			nil,
+			2,
+		},
+		{
+			"replayed and live events out of order",
+			[]received{
+				{"live1", 10, 0},
+				{"replay1", 5, 10},
+				{"live2", 11, 20},
+				{"replay2", 6, 30},
+			},
+			120,
+			[]string{"replay1", "replay2", "live1", "live2"},
+			0,
+		},
+		{
+			"events more recent than the due ones are kept",
+			[]received{
+				{"a", 2, 0},
+				{"b", 1, 50},
+				{"c", 3, 60},
+			},
+			100,
+			[]string{"b", "a"},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			r := newReorderer(100 * time.Millisecond)
+
+			for _, e := range tt.events {
+				r.add(makeTimedEvent(e.name, e.ts), base.Add(time.Duration(e.at)*time.Millisecond))
+			}
+
+			var got []string
+			for _, evt := range r.flush(base.Add(time.Duration(tt.flushAt) * time.Millisecond)) {
+				o := map[string]interface{}{}
+				if err := evt.Decode(&o); err != nil {
+					t.Fatalf("unable to decode event: %s", err)
+				}
+				got = append(got, o["name"].(string))
+			}
+
+			if !reflect.DeepEqual(got, tt.flushed) {
+				t.Errorf("flush() = %v, want %v", got, tt.flushed)
+			}
+
+			if len(r.pending) != tt.kept {
+				t.Errorf("pending = %d, want %d", len(r.pending), tt.kept)
+			}
+		})
+	}
+}
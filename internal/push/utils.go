@@ -45,7 +45,7 @@ func decodeErrors(r io.Reader, encoding elemental.EncodingType) error {
 	return errs
 }
 
-func makeURL(u string, namespace string, password string, recursive, supportErrorEvents bool) string {
+func makeURL(u string, namespace string, password string, recursive, supportErrorEvents bool, filter string) string {
 
 	u = strings.Replace(u, "https://", "wss://", 1)
 
@@ -65,6 +65,10 @@ func makeURL(u string, namespace string, password string, recursive, supportErro
 		args = append(args, "enableErrors=true")
 	}
 
+	if filter != "" {
+		args = append(args, fmt.Sprintf("q=%s", url.QueryEscape(filter)))
+	}
+
 	return fmt.Sprintf("%s?%s", u, strings.Join(args, "&"))
 }
 
@@ -74,3 +78,21 @@ func nextBackoff(try int) time.Duration {
 
 	return time.Duration(math.Min(math.Pow(4.0, float64(try))-1, maxBackoff)) * time.Millisecond
 }
+
+// eventLag returns the time elapsed between the timestamp of the given event
+// and now. It assumes the clocks of the server and the client are synchronized:
+// as a negative lag can only be caused by clock skew, it is reported as zero.
+// It returns false if the event has no timestamp.
+func eventLag(evt *elemental.Event, now time.Time) (time.Duration, bool) {
+
+	if evt.Timestamp.IsZero() {
+		return 0, false
+	}
+
+	lag := now.Sub(evt.Timestamp)
+	if lag < 0 {
+		lag = 0
+	}
+
+	return lag, true
+}
@@ -29,6 +29,7 @@ func Test_makeURL(t *testing.T) {
 		password      string
 		recursive     bool
 		supportErrors bool
+		filter        string
 	}
 	tests := []struct {
 		name string
@@ -43,6 +44,7 @@ func Test_makeURL(t *testing.T) {
 				"password",
 				true,
 				false,
+				"",
 			},
 			"wss://toto?namespace=%2Fns&token=password&mode=all",
 		},
@@ -54,6 +56,7 @@ func Test_makeURL(t *testing.T) {
 				"password",
 				false,
 				false,
+				"",
 			},
 			"wss://toto?namespace=%2Fns&token=password",
 		},
@@ -65,6 +68,7 @@ func Test_makeURL(t *testing.T) {
 				"",
 				true,
 				false,
+				"",
 			},
 			"wss://toto?namespace=%2Fns&mode=all",
 		},
@@ -76,6 +80,7 @@ func Test_makeURL(t *testing.T) {
 				"",
 				false,
 				false,
+				"",
 			},
 			"wss://toto?namespace=%2Fns",
 		},
@@ -87,13 +92,26 @@ func Test_makeURL(t *testing.T) {
 				"",
 				false,
 				true,
+				"",
 			},
 			"wss://toto?namespace=%2Fns&enableErrors=true",
 		},
+		{
+			"with filter",
+			args{
+				"https://toto",
+				"/ns",
+				"",
+				false,
+				false,
+				`name == "a b"`,
+			},
+			"wss://toto?namespace=%2Fns&q=name+%3D%3D+%22a+b%22",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := makeURL(tt.args.u, tt.args.namespace, tt.args.password, tt.args.recursive, tt.args.supportErrors); got != tt.want {
+			if got := makeURL(tt.args.u, tt.args.namespace, tt.args.password, tt.args.recursive, tt.args.supportErrors, tt.args.filter); got != tt.want {
 				t.Errorf("makeURL() = %v, want %v", got, tt.want)
 			}
 		})
@@ -240,3 +258,67 @@ func Test_decodeErrors(t *testing.T) {
 		})
 	}
 }
+
+func Test_eventLag(t *testing.T) {
+
+	now := time.Now()
+
+	type args struct {
+		evt *elemental.Event
+		now time.Time
+	}
+	tests := []struct {
+		name   string
+		args   args
+		want   time.Duration
+		wantOK bool
+	}{
+		{
+			"event in the past",
+			args{
+				&elemental.Event{Timestamp: now.Add(-3 * time.Second)},
+				now,
+			},
+			3 * time.Second,
+			true,
+		},
+		{
+			"event right now",
+			args{
+				&elemental.Event{Timestamp: now},
+				now,
+			},
+			0,
+			true,
+		},
+		{
+			"event in the future",
+			args{
+				&elemental.Event{Timestamp: now.Add(time.Second)},
+				now,
+			},
+			0,
+			true,
+		},
+		{
+			"event without timestamp",
+			args{
+				&elemental.Event{},
+				now,
+			},
+			0,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := eventLag(tt.args.evt, tt.args.now)
+			if got != tt.want {
+				t.Errorf("eventLag() got = %v, want %v", got, tt.want)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("eventLag() ok = %v, want %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
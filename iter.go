@@ -13,12 +13,115 @@ package manipulate
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"go.aporeto.io/elemental"
+	"golang.org/x/sync/errgroup"
 )
 
-const iterDefaultBlockSize = 1000
+const (
+	iterDefaultBlockSize = 1000
+	iterDefaultWorkers   = 4
+)
+
+// ErrIterStop can be returned by an iterator function to stop
+// the iteration. The iteration function then returns nil.
+var ErrIterStop = errors.New("stop iteration")
+
+// An IterReport holds information about how far an iteration went.
+type IterReport struct {
+
+	// Processed is the number of objects passed to the iterator function.
+	Processed int
+
+	// Blocks is the number of blocks passed to the iterator function.
+	Blocks int
+
+	// Next is the marker of the next block to retrieve. It is
+	// empty when the iteration reached the end of the data.
+	// When the iteration failed, it can be given to IterOptionStartAfter
+	// to resume right after the last successfully processed block.
+	// If no block has been processed, it is the marker given to
+	// IterOptionStartAfter.
+	Next string
+
+	// DeadlineReached is true if the iteration has been
+	// stopped because the deadline set by IterOptionDeadline passed.
+	DeadlineReached bool
+}
+
+type iterConfig struct {
+	deadline   time.Time
+	report     *IterReport
+	ordered    bool
+	progress   func(blocks int, processed int)
+	startAfter string
+}
+
+// An IterOption can be given to the iteration functions to alter their behavior.
+type IterOption func(*iterConfig)
+
+// IterOptionDeadline stops the iteration once the given wall clock deadline
+// passes. The deadline is checked before retrieving each block. When it
+// is reached, the iteration stops and returns successfully.
+// This is different from the deadline of the context, which makes the
+// iteration fail.
+func IterOptionDeadline(deadline time.Time) IterOption {
+	return func(cfg *iterConfig) {
+		cfg.deadline = deadline
+	}
+}
+
+// IterOptionReport populates the given IterReport
+// when the iteration returns, successfully or not.
+func IterOptionReport(report *IterReport) IterOption {
+
+	if report == nil {
+		panic("report must not be nil")
+	}
+
+	return func(cfg *iterConfig) {
+		cfg.report = report
+	}
+}
+
+// IterOptionStartAfter starts the iteration right after the
+// object with the given marker, instead of at the beginning of
+// the data. This is typically used with the Next marker of an
+// IterReport to resume a previous iteration.
+func IterOptionStartAfter(marker string) IterOption {
+	return func(cfg *iterConfig) {
+		cfg.startAfter = marker
+	}
+}
+
+// IterOptionProgress calls the given function after each block
+// has been passed to the iterator function, with the number of blocks
+// and the cumulative number of objects processed so far.
+// It is never called concurrently.
+func IterOptionProgress(progress func(blocks int, processed int)) IterOption {
+
+	if progress == nil {
+		panic("progress must not be nil")
+	}
+
+	return func(cfg *iterConfig) {
+		cfg.progress = progress
+	}
+}
+
+// IterOptionOrdered makes ParallelIterFunc call the iterator function
+// sequentially, in the order of the blocks. Blocks are still prefetched
+// concurrently. It has no effect on the other iteration functions, which
+// are always ordered.
+func IterOptionOrdered() IterOption {
+	return func(cfg *iterConfig) {
+		cfg.ordered = true
+	}
+}
 
 // IterFunc calls RetrieveMany on the given Manipulator, and will retrieve the data by block
 // of the given blockSize.
@@ -27,7 +130,8 @@ const iterDefaultBlockSize = 1000
 //
 // For each retrieved block, the given func will be called with the
 // current data block. If the function returns an error, the error is returned to the caller
-// of IterFunc and the iteration stops.
+// of IterFunc and the iteration stops. If the function returns ErrIterStop, the iteration
+// stops and IterFunc returns nil.
 //
 // The given context will be used if the underlying manipulator honors it. Be careful to NOT pass
 // a filter matching objects then updating the objects to not match anynmore. This would shift
@@ -39,7 +143,11 @@ const iterDefaultBlockSize = 1000
 // hold the data block. It is reset at every iteration. Do not rely on it to be filled
 // once IterFunc is complete.
 //
-// Finally, if the given blockSize is <= 0, then it will use the default that is 1000.
+// If the given blockSize is <= 0, then it will use the default that is 1000.
+//
+// Finally, IterOptions can be given to alter the iteration, like IterOptionDeadline
+// to bound its duration, IterOptionReport to know how far it went, or IterOptionProgress
+// to follow its progression.
 func IterFunc(
 	ctx context.Context,
 	manipulator Manipulator,
@@ -47,8 +155,9 @@ func IterFunc(
 	mctx Context,
 	iteratorFunc func(block elemental.Identifiables) error,
 	blockSize int,
+	options ...IterOption,
 ) error {
-	return doIterFunc(ctx, manipulator, identifiablesTemplate, mctx, iteratorFunc, blockSize, false)
+	return doIterFunc(ctx, manipulator, identifiablesTemplate, mctx, iteratorFunc, blockSize, false, options...)
 }
 
 // IterUntilFunc works as IterFunc but pagination will not increase.
@@ -63,8 +172,179 @@ func IterUntilFunc(
 	mctx Context,
 	iteratorFunc func(block elemental.Identifiables) error,
 	blockSize int,
+	options ...IterOption,
 ) error {
-	return doIterFunc(ctx, manipulator, identifiablesTemplate, mctx, iteratorFunc, blockSize, true)
+	return doIterFunc(ctx, manipulator, identifiablesTemplate, mctx, iteratorFunc, blockSize, true, options...)
+}
+
+// ParallelIterFunc works as IterFunc, but the given iterator function is called
+// from a pool of the given number of workers, while the next blocks are retrieved.
+// Up to workers blocks are prefetched ahead of the ones being processed.
+//
+// The iterator function may be called concurrently, unless IterOptionOrdered
+// is given. In that case, it is called sequentially in the order of the blocks,
+// and only the retrieval happens concurrently.
+//
+// The first error returned by the iterator function or by the manipulator stops
+// the iteration and is returned, unless it is ErrIterStop, in which case
+// ParallelIterFunc returns nil. Blocks that have not been passed to the iterator
+// function yet are discarded. The blocks that were being processed at that time
+// are not interrupted, but the retrieval in progress is canceled: the blocks are
+// retrieved using a context derived from the given one, in place of the context
+// of the given manipulate.Context.
+//
+// If the given workers is <= 0, then it will use the default that is 4.
+//
+// When using IterOptionReport, Next is the marker following the last retrieved
+// block. It is only meaningful when the iteration succeeded.
+func ParallelIterFunc(
+	ctx context.Context,
+	manipulator Manipulator,
+	identifiablesTemplate elemental.Identifiables,
+	mctx Context,
+	iteratorFunc func(block elemental.Identifiables) error,
+	blockSize int,
+	workers int,
+	options ...IterOption,
+) error {
+
+	if manipulator == nil {
+		panic("manipulator must not be nil")
+	}
+
+	if iteratorFunc == nil {
+		panic("iteratorFunc must not be nil")
+	}
+
+	if identifiablesTemplate == nil {
+		panic("identifiablesTemplate must not be nil")
+	}
+
+	if blockSize <= 0 {
+		blockSize = iterDefaultBlockSize
+	}
+
+	if workers <= 0 {
+		workers = iterDefaultWorkers
+	}
+
+	cfg := iterConfig{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	type block struct {
+		iter    int
+		objects elemental.Identifiables
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	if mctx == nil {
+		mctx = NewContext(gctx)
+	}
+
+	var lock sync.Mutex
+	report := IterReport{Next: cfg.startAfter}
+
+	if cfg.report != nil {
+		defer func() { *cfg.report = report }()
+	}
+
+	blocks := make(chan block, workers)
+
+	g.Go(func() error {
+
+		defer close(blocks)
+
+		after := cfg.startAfter
+
+		for iter := 1; ; iter++ {
+
+			if !cfg.deadline.IsZero() && time.Now().After(cfg.deadline) {
+				lock.Lock()
+				report.DeadlineReached = true
+				lock.Unlock()
+				return nil
+			}
+
+			objects := identifiablesTemplate.Copy()
+
+			smctx := mctx.Derive(ContextOptionAfter(after, blockSize))
+
+			// The block is retrieved with the context of the group,
+			// so the first failure cancels the retrieval in progress.
+			if d, ok := smctx.(*mcontext); ok {
+				d.ctx = gctx
+			}
+
+			if err := manipulator.RetrieveMany(smctx, objects); err != nil {
+				return fmt.Errorf("unable to retrieve objects for iteration %d: %w", iter, err)
+			}
+
+			if len(objects.List()) == 0 {
+				lock.Lock()
+				report.Next = ""
+				lock.Unlock()
+				return nil
+			}
+
+			select {
+			case blocks <- block{iter: iter, objects: objects}:
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+
+			lock.Lock()
+			report.Next = smctx.Next()
+			lock.Unlock()
+
+			if smctx.Next() == "" {
+				return nil
+			}
+
+			after = smctx.Next()
+		}
+	})
+
+	if cfg.ordered {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			for b := range blocks {
+
+				if gctx.Err() != nil {
+					return nil
+				}
+
+				err := iteratorFunc(b.objects)
+				if err != nil && !errors.Is(err, ErrIterStop) {
+					return fmt.Errorf("iter function returned an error on iteration %d: %w", b.iter, err)
+				}
+
+				lock.Lock()
+				report.Blocks++
+				report.Processed += len(b.objects.List())
+				if cfg.progress != nil {
+					cfg.progress(report.Blocks, report.Processed)
+				}
+				lock.Unlock()
+
+				if err != nil {
+					return ErrIterStop
+				}
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil && err != ErrIterStop {
+		return err
+	}
+
+	return nil
 }
 
 // Iter is a helper function for IterFunc.
@@ -86,6 +366,7 @@ func Iter(
 	mctx Context,
 	identifiablesTemplate elemental.Identifiables,
 	blockSize int,
+	options ...IterOption,
 ) (elemental.Identifiables, error) {
 
 	if err := IterFunc(
@@ -98,6 +379,7 @@ func Iter(
 			return nil
 		},
 		blockSize,
+		options...,
 	); err != nil {
 		return nil, err
 	}
@@ -105,6 +387,39 @@ func Iter(
 	return identifiablesTemplate, nil
 }
 
+// IterCursor works as Iter, but the objects are sorted by the given sortKey and
+// every block starts right after the last object of the previous one, using
+// ContextOptionAfter, instead of relying on page numbers. This keeps the
+// iteration fast and consistent on large collections that change while being
+// iterated.
+//
+// The sortKey can be prefixed by "-" to iterate in descending order. It overrides
+// the order set in the given manipulate.Context.
+//
+// Example:
+//     dest, err := IterCursor(context.Background(), m, mctx, model.ThingsList{}, "createTime", 100)
+//
+func IterCursor(
+	ctx context.Context,
+	m Manipulator,
+	mctx Context,
+	identifiablesTemplate elemental.Identifiables,
+	sortKey string,
+	blockSize int,
+	options ...IterOption,
+) (elemental.Identifiables, error) {
+
+	if sortKey == "" || sortKey == "-" {
+		panic("sortKey must not be empty")
+	}
+
+	if mctx == nil {
+		mctx = NewContext(ctx)
+	}
+
+	return Iter(ctx, m, mctx.Derive(ContextOptionOrder(sortKey)), identifiablesTemplate, blockSize, options...)
+}
+
 func doIterFunc(
 	ctx context.Context,
 	manipulator Manipulator,
@@ -113,6 +428,7 @@ func doIterFunc(
 	iteratorFunc func(block elemental.Identifiables) error,
 	blockSize int,
 	disablePageIncrease bool,
+	options ...IterOption,
 ) error {
 
 	if manipulator == nil {
@@ -135,10 +451,26 @@ func doIterFunc(
 		blockSize = iterDefaultBlockSize
 	}
 
+	cfg := iterConfig{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
 	var iter int
-	var after string
+	after := cfg.startAfter
+	report := IterReport{Next: cfg.startAfter}
+
+	if cfg.report != nil {
+		defer func() { *cfg.report = report }()
+	}
 
 	for {
+
+		if !cfg.deadline.IsZero() && time.Now().After(cfg.deadline) {
+			report.DeadlineReached = true
+			return nil
+		}
+
 		iter++
 
 		objects := identifiablesTemplate.Copy()
@@ -150,14 +482,24 @@ func doIterFunc(
 		}
 
 		if len(objects.List()) == 0 {
+			report.Next = ""
 			return nil
 		}
 
-		if err := iteratorFunc(objects); err != nil {
+		err := iteratorFunc(objects)
+		if err != nil && !errors.Is(err, ErrIterStop) {
 			return fmt.Errorf("iter function returned an error on iteration %d: %w", iter, err)
 		}
 
-		if smctx.Next() == "" {
+		report.Blocks++
+		report.Processed += len(objects.List())
+		report.Next = smctx.Next()
+
+		if cfg.progress != nil {
+			cfg.progress(report.Blocks, report.Processed)
+		}
+
+		if err != nil || smctx.Next() == "" {
 			return nil
 		}
 
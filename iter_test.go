@@ -15,7 +15,10 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/elemental"
@@ -86,6 +89,23 @@ func (m *testManipulator) RetrieveMany(mctx Context, dest elemental.Identifiable
 	return nil
 }
 
+// A blockingManipulator retrieves the first block, then blocks until
+// the context of the manipulate.Context is canceled.
+type blockingManipulator struct {
+	testManipulator
+	calls int32
+}
+
+func (m *blockingManipulator) RetrieveMany(mctx Context, dest elemental.Identifiables) error {
+
+	if atomic.AddInt32(&m.calls, 1) > 1 {
+		<-mctx.Context().Done()
+		return mctx.Context().Err()
+	}
+
+	return m.testManipulator.RetrieveMany(mctx, dest)
+}
+
 func (m *testManipulator) Retrieve(mctx Context, object elemental.Identifiable) error {
 	return nil
 }
@@ -344,6 +364,537 @@ func TestDoIterFunc(t *testing.T) {
 	})
 }
 
+func TestIterFunc_Options(t *testing.T) {
+
+	Convey("Given I have a manipulator and some objects in the db", t, func() {
+
+		m := &testManipulator{
+			data: makeData(45),
+		}
+
+		Convey("When I call IterFunc with a report", func() {
+
+			report := IterReport{}
+
+			err := IterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error { return nil },
+				10,
+				IterOptionReport(&report),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the report should be correct", func() {
+				So(report.Processed, ShouldEqual, 45)
+				So(report.Blocks, ShouldEqual, 5)
+				So(report.Next, ShouldEqual, "")
+				So(report.DeadlineReached, ShouldBeFalse)
+			})
+		})
+
+		Convey("When I call IterFunc with a short deadline", func() {
+
+			report := IterReport{}
+			var processed int
+
+			err := IterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(block elemental.Identifiables) error {
+					processed += len(block.List())
+					time.Sleep(100 * time.Millisecond)
+					return nil
+				},
+				10,
+				IterOptionDeadline(time.Now().Add(150*time.Millisecond)),
+				IterOptionReport(&report),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then only part of the data should have been processed", func() {
+				So(processed, ShouldEqual, 20)
+			})
+
+			Convey("Then the report should be correct", func() {
+				So(report.Processed, ShouldEqual, 20)
+				So(report.Blocks, ShouldEqual, 2)
+				So(report.Next, ShouldEqual, "19")
+				So(report.DeadlineReached, ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator and some objects in the db and an iterator stopping early", t, func() {
+
+		m := &testManipulator{
+			data: makeData(45),
+		}
+
+		Convey("When I call IterFunc and the iterator returns ErrIterStop", func() {
+
+			report := IterReport{}
+			var blocks int
+
+			err := IterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error {
+					blocks++
+					if blocks == 2 {
+						return ErrIterStop
+					}
+					return nil
+				},
+				10,
+				IterOptionReport(&report),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the iteration should have stopped", func() {
+				So(blocks, ShouldEqual, 2)
+			})
+
+			Convey("Then the report should be correct", func() {
+				So(report.Processed, ShouldEqual, 20)
+				So(report.Blocks, ShouldEqual, 2)
+				So(report.Next, ShouldEqual, "19")
+			})
+		})
+
+		Convey("When I call IterFunc and the iterator returns a wrapped ErrIterStop", func() {
+
+			var blocks int
+
+			err := IterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error {
+					blocks++
+					return fmt.Errorf("found it: %w", ErrIterStop)
+				},
+				10,
+			)
+
+			Convey("Then err should be nil and the iteration should have stopped", func() {
+				So(err, ShouldBeNil)
+				So(blocks, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I call ParallelIterFunc and the iterator returns ErrIterStop", func() {
+
+			var blocks int32
+
+			err := ParallelIterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error {
+					if atomic.AddInt32(&blocks, 1) == 2 {
+						return ErrIterStop
+					}
+					return nil
+				},
+				10,
+				1,
+			)
+
+			Convey("Then err should be nil and the iteration should have stopped", func() {
+				So(err, ShouldBeNil)
+				So(atomic.LoadInt32(&blocks), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator and some objects in the db and a progress function", t, func() {
+
+		m := &testManipulator{
+			data: makeData(45),
+		}
+
+		Convey("When I call IterFunc with a progress function", func() {
+
+			var blocks []int
+			var processed []int
+
+			err := IterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error { return nil },
+				10,
+				IterOptionProgress(func(b int, p int) {
+					blocks = append(blocks, b)
+					processed = append(processed, p)
+				}),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the progress function should have been called after each block", func() {
+				So(blocks, ShouldResemble, []int{1, 2, 3, 4, 5})
+				So(processed, ShouldResemble, []int{10, 20, 30, 40, 45})
+			})
+		})
+
+		Convey("When I call ParallelIterFunc with a progress function", func() {
+
+			var last int
+			var calls int
+
+			err := ParallelIterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error { return nil },
+				10,
+				3,
+				IterOptionProgress(func(b int, p int) {
+					calls++
+					last = p
+				}),
+			)
+
+			Convey("Then the progress function should have been called after each block", func() {
+				So(err, ShouldBeNil)
+				So(calls, ShouldEqual, 5)
+				So(last, ShouldEqual, 45)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator and some objects in the db and an iteration to resume", t, func() {
+
+		m := &testManipulator{
+			data: makeData(45),
+		}
+
+		Convey("When I call IterFunc that fails and resume it from the report", func() {
+
+			report := IterReport{}
+			var blocks int
+
+			err1 := IterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error {
+					blocks++
+					if blocks == 3 {
+						return fmt.Errorf("boom")
+					}
+					return nil
+				},
+				10,
+				IterOptionReport(&report),
+			)
+
+			next := report.Next
+
+			m.cursor = 0
+			var ids []string
+			err2 := IterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(block elemental.Identifiables) error {
+					for _, o := range block.List() {
+						ids = append(ids, o.Identifier())
+					}
+					return nil
+				},
+				10,
+				IterOptionStartAfter(next),
+			)
+
+			Convey("Then the first iteration should have failed", func() {
+				So(err1, ShouldNotBeNil)
+				So(next, ShouldEqual, "19")
+			})
+
+			Convey("Then the second iteration should have resumed after the last processed block", func() {
+				So(err2, ShouldBeNil)
+				So(len(ids), ShouldEqual, 25)
+				So(ids[0], ShouldEqual, "20")
+				So(ids[24], ShouldEqual, "44")
+			})
+		})
+
+		Convey("When I call IterFunc to resume with an expired deadline", func() {
+
+			report := IterReport{}
+
+			err := IterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error { return nil },
+				10,
+				IterOptionStartAfter("19"),
+				IterOptionDeadline(time.Now().Add(-time.Second)),
+				IterOptionReport(&report),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the report should still allow to resume", func() {
+				So(report.Blocks, ShouldEqual, 0)
+				So(report.Next, ShouldEqual, "19")
+				So(report.DeadlineReached, ShouldBeTrue)
+			})
+		})
+
+		Convey("When I call ParallelIterFunc to resume with an expired deadline", func() {
+
+			report := IterReport{}
+
+			err := ParallelIterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(elemental.Identifiables) error { return nil },
+				10,
+				3,
+				IterOptionStartAfter("19"),
+				IterOptionDeadline(time.Now().Add(-time.Second)),
+				IterOptionReport(&report),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the report should still allow to resume", func() {
+				So(report.Blocks, ShouldEqual, 0)
+				So(report.Next, ShouldEqual, "19")
+				So(report.DeadlineReached, ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Calling IterOptionReport with a nil report should panic", t, func() {
+		So(func() { IterOptionReport(nil) }, ShouldPanicWith, "report must not be nil")
+	})
+
+	Convey("Calling IterOptionProgress with a nil function should panic", t, func() {
+		So(func() { IterOptionProgress(nil) }, ShouldPanicWith, "progress must not be nil")
+	})
+}
+
+func TestParallelIterFunc(t *testing.T) {
+
+	Convey("Given I call ParallelIterFunc with invalid arguments", t, func() {
+		So(func() {
+			_ = ParallelIterFunc(context.Background(), nil, testmodel.ListsList{}, nil, func(elemental.Identifiables) error { return nil }, 10, 2)
+		}, ShouldPanicWith, "manipulator must not be nil")
+		So(func() {
+			_ = ParallelIterFunc(context.Background(), &testManipulator{}, testmodel.ListsList{}, nil, nil, 10, 2)
+		}, ShouldPanicWith, "iteratorFunc must not be nil")
+		So(func() {
+			_ = ParallelIterFunc(context.Background(), &testManipulator{}, nil, nil, func(elemental.Identifiables) error { return nil }, 10, 2)
+		}, ShouldPanicWith, "identifiablesTemplate must not be nil")
+	})
+
+	Convey("Given I have a manipulator and some objects in the db", t, func() {
+
+		m := &testManipulator{
+			data: makeData(45),
+		}
+
+		Convey("When I call ParallelIterFunc", func() {
+
+			var lock sync.Mutex
+			ids := map[string]struct{}{}
+			report := IterReport{}
+
+			err := ParallelIterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(block elemental.Identifiables) error {
+					lock.Lock()
+					defer lock.Unlock()
+					for _, o := range block.List() {
+						ids[o.Identifier()] = struct{}{}
+					}
+					return nil
+				},
+				10,
+				3,
+				IterOptionReport(&report),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then all objects should have been processed once", func() {
+				So(len(ids), ShouldEqual, 45)
+			})
+
+			Convey("Then the report should be correct", func() {
+				So(report.Processed, ShouldEqual, 45)
+				So(report.Blocks, ShouldEqual, 5)
+				So(report.Next, ShouldEqual, "")
+			})
+		})
+
+		Convey("When I call ParallelIterFunc with ordering", func() {
+
+			var names []string
+
+			err := ParallelIterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(block elemental.Identifiables) error {
+					for _, o := range block.List() {
+						names = append(names, o.(*testmodel.List).Name)
+					}
+					return nil
+				},
+				10,
+				3,
+				IterOptionOrdered(),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the objects should have been processed in order", func() {
+				So(len(names), ShouldEqual, 45)
+				for i, n := range names {
+					So(n, ShouldEqual, fmt.Sprintf("list #%d", i))
+				}
+			})
+		})
+
+		Convey("When I call ParallelIterFunc with an iterator returning an error", func() {
+
+			var calls int32
+
+			err := ParallelIterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(block elemental.Identifiables) error {
+					if atomic.AddInt32(&calls, 1) == 2 {
+						return fmt.Errorf("boom")
+					}
+					return nil
+				},
+				1,
+				1,
+			)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "iter function returned an error on iteration 2: boom")
+			})
+
+			Convey("Then the iteration should have stopped", func() {
+				So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator blocking after the first block", t, func() {
+
+		m := &blockingManipulator{
+			testManipulator: testManipulator{
+				data: makeData(45),
+			},
+		}
+
+		Convey("When I call ParallelIterFunc with a context and an iterator returning an error", func() {
+
+			done := make(chan error, 1)
+			go func() {
+				done <- ParallelIterFunc(
+					context.Background(),
+					m,
+					testmodel.ListsList{},
+					NewContext(context.Background()),
+					func(block elemental.Identifiables) error { return fmt.Errorf("boom") },
+					10,
+					1,
+				)
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(3 * time.Second):
+				t.Fatal("the retrieval in progress has not been canceled")
+			}
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "iter function returned an error on iteration 1: boom")
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator but it returns an error", t, func() {
+
+		m := &testManipulator{
+			data: makeData(45),
+			err:  fmt.Errorf("boom"),
+		}
+
+		Convey("When I call ParallelIterFunc", func() {
+
+			err := ParallelIterFunc(
+				context.Background(),
+				m,
+				testmodel.ListsList{},
+				nil,
+				func(block elemental.Identifiables) error { return nil },
+				10,
+				3,
+			)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to retrieve objects for iteration 1: boom")
+			})
+		})
+	})
+}
+
 func TestIter(t *testing.T) {
 
 	Convey("Given I have a manipulator and some objects in the db", t, func() {
@@ -428,6 +979,87 @@ func TestIter(t *testing.T) {
 	})
 }
 
+type orderRecordingManipulator struct {
+	*testManipulator
+	orders [][]string
+}
+
+func (m *orderRecordingManipulator) RetrieveMany(mctx Context, dest elemental.Identifiables) error {
+	m.orders = append(m.orders, mctx.Order())
+	return m.testManipulator.RetrieveMany(mctx, dest)
+}
+
+func TestIterCursor(t *testing.T) {
+
+	Convey("Given I have a manipulator and some objects in the db", t, func() {
+
+		m := &orderRecordingManipulator{
+			testManipulator: &testManipulator{
+				data: makeData(45),
+			},
+		}
+
+		Convey("When I call IterCursor", func() {
+
+			mctx := NewContext(context.Background(), ContextOptionOrder("name"))
+
+			dest, err := IterCursor(
+				context.Background(),
+				m,
+				mctx,
+				testmodel.ListsList{},
+				"-date",
+				10,
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then dest should be correct", func() {
+				So(dest, ShouldResemble, m.data)
+			})
+
+			Convey("Then every block should have been sorted by the sort key", func() {
+				So(len(m.orders), ShouldEqual, 5)
+				for _, order := range m.orders {
+					So(order, ShouldResemble, []string{"-date"})
+				}
+			})
+
+			Convey("Then the given context should be untouched", func() {
+				So(mctx.Order(), ShouldResemble, []string{"name"})
+			})
+		})
+
+		Convey("When I call IterCursor with a nil context", func() {
+
+			dest, err := IterCursor(
+				context.Background(),
+				m,
+				nil,
+				testmodel.ListsList{},
+				"name",
+				10,
+			)
+
+			Convey("Then it should work", func() {
+				So(err, ShouldBeNil)
+				So(dest, ShouldResemble, m.data)
+			})
+		})
+
+		Convey("When I call IterCursor with an empty sort key", func() {
+
+			Convey("Then it should panic", func() {
+				So(func() {
+					_, _ = IterCursor(context.Background(), m, nil, testmodel.ListsList{}, "", 10)
+				}, ShouldPanicWith, "sortKey must not be empty")
+			})
+		})
+	})
+}
+
 func TestIterUntilFunc(t *testing.T) {
 
 	Convey("Given I have a manipulator and some objects in the db", t, func() {
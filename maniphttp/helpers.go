@@ -105,6 +105,23 @@ func SetGlobalHeaders(manipulator manipulate.Manipulator, headers http.Header) {
 	m.globalHeaders = headers
 }
 
+// Close gracefully closes the given manipulator. It immediately stops
+// accepting new requests, that will fail with a manipulate.ErrDisconnected,
+// then waits for the in-flight requests to complete before closing the idle
+// connections. If the given context is done before all the in-flight requests
+// complete, Close stops waiting and returns an error. Closing a manipulator
+// more than once has no effect besides waiting again.
+// Note: the given manipulator must be an HTTP Manipulator or it will panic.
+func Close(ctx context.Context, manipulator manipulate.Manipulator) error {
+
+	m, ok := manipulator.(*httpManipulator)
+	if !ok {
+		panic("You can only pass a HTTP Manipulator to Close")
+	}
+
+	return m.close(ctx)
+}
+
 // DirectSend allows to send direct bytes using the given manipulator.
 // This is only useful in extremely particular scenario, like fuzzing.
 // Note: the given manipulator must be an HTTP Manipulator or it will panic.
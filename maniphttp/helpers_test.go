@@ -22,6 +22,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/elemental"
@@ -251,6 +252,99 @@ func TestManiphttp_DirectSend(t *testing.T) {
 	})
 }
 
+func TestManiphttp_Close(t *testing.T) {
+
+	Convey("Given I have a manipulator and a test server with a pending request", t, func() {
+
+		received := make(chan struct{}, 1)
+		release := make(chan struct{})
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received <- struct{}{}
+			<-release
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"ID": "xxx"}`)
+		}))
+		defer ts.Close()
+
+		var once sync.Once
+		unblock := func() { once.Do(func() { close(release) }) }
+		defer unblock()
+
+		m, _ := New(context.Background(), ts.URL)
+
+		sent := make(chan error, 1)
+		go func() { sent <- m.Retrieve(nil, &testmodel.List{ID: "xxx"}) }()
+		<-received
+
+		Convey("When I call Close and the request completes", func() {
+
+			closed := make(chan error, 1)
+			go func() { closed <- Close(context.Background(), m) }()
+
+			hm := m.(*httpManipulator)
+			for {
+				hm.closeLock.RLock()
+				isClosed := hm.closed
+				hm.closeLock.RUnlock()
+				if isClosed {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			Convey("Then new requests should be rejected", func() {
+
+				err := m.Retrieve(nil, &testmodel.List{ID: "xxx"})
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrDisconnected{})
+				So(err.Error(), ShouldEqual, "Disconnected: manipulator is closed")
+			})
+
+			Convey("Then Close should wait for the pending request", func() {
+
+				time.Sleep(100 * time.Millisecond)
+				So(len(closed), ShouldEqual, 0)
+
+				unblock()
+
+				So(<-sent, ShouldBeNil)
+				So(<-closed, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call Close and the context expires before the request completes", func() {
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err := Close(ctx, m)
+			unblock()
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotCommunicate{})
+				So(err.Error(), ShouldEqual, "Cannot communicate: unable to drain in-flight requests: context deadline exceeded")
+			})
+
+			Convey("Then the pending request should complete", func() {
+				So(<-sent, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have a non http manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call Close", func() {
+
+			Convey("Then it should panic", func() {
+				So(func() { _ = Close(context.Background(), m) }, ShouldPanicWith, "You can only pass a HTTP Manipulator to Close")
+			})
+		})
+	})
+}
+
 func TestManiphttp_BatchCreate(t *testing.T) {
 
 	Convey("Given I have a manipulator and a test server and I use JSON encoding", t, func() {
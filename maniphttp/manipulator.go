@@ -67,16 +67,22 @@ type httpManipulator struct {
 	tokenCookieKey       string
 	backoffCurve         []time.Duration
 	strongBackoffCurve   []time.Duration
+	disableBackoffJitter bool
+	inflight             sync.WaitGroup
+	closeLock            sync.RWMutex
+	closed               bool
 
 	// optionnable
-	ctx            context.Context
-	client         *http.Client
-	tlsConfig      *tls.Config
-	tokenManager   manipulate.TokenManager
-	globalHeaders  http.Header
-	transport      *http.Transport
-	encoding       elemental.EncodingType
-	tcpUserTimeout time.Duration
+	ctx             context.Context
+	client          *http.Client
+	tlsConfig       *tls.Config
+	tokenManager    manipulate.TokenManager
+	globalHeaders   http.Header
+	transport       *http.Transport
+	encoding        elemental.EncodingType
+	tcpUserTimeout  time.Duration
+	defaultTimeout  time.Duration
+	metricsRecorder manipulate.MetricsRecorder
 }
 
 // New returns a maniphttp.Manipulator configured according to the given suite of Option.
@@ -98,6 +104,8 @@ func New(ctx context.Context, url string, options ...Option) (manipulate.Manipul
 		encoding:           elemental.EncodingTypeJSON,
 		backoffCurve:       defaultBackoffCurve,
 		strongBackoffCurve: strongBackoffCurve,
+		defaultTimeout:     defaultGlobalContextTimeout,
+		metricsRecorder:    manipulate.NewNoopMetricsRecorder(),
 	}
 
 	// Apply the options.
@@ -161,14 +169,16 @@ func New(ctx context.Context, url string, options ...Option) (manipulate.Manipul
 	return m, nil
 }
 
-func (s *httpManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.Identifiables) error {
+func (s *httpManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.Identifiables) (err error) {
 
 	if dest == nil {
 		return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("nil dest")}
 	}
 
+	defer s.recordOperation(elemental.OperationRetrieveMany, dest.Identity(), time.Now(), &err)
+
 	if mctx == nil {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout)
 		defer cancel()
 		mctx = manipulate.NewContext(ctx)
 	}
@@ -176,6 +186,12 @@ func (s *httpManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.I
 	sp := tracing.StartTrace(mctx, fmt.Sprintf("maniphttp.retrieve_many.%s", dest.Identity().Category))
 	defer sp.Finish()
 
+	if err := manipulate.ValidatePagination(mctx); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
 	url, err := s.getURLForChildrenIdentity(mctx.Parent(), dest.Identity(), dest.Version(), mctx.Version())
 	if err != nil {
 		sp.SetTag("error", true)
@@ -204,14 +220,16 @@ func (s *httpManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.I
 	return nil
 }
 
-func (s *httpManipulator) Retrieve(mctx manipulate.Context, object elemental.Identifiable) error {
+func (s *httpManipulator) Retrieve(mctx manipulate.Context, object elemental.Identifiable) (err error) {
 
 	if object == nil {
 		return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("nil object")}
 	}
 
+	defer s.recordOperation(elemental.OperationRetrieve, object.Identity(), time.Now(), &err)
+
 	if mctx == nil {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout)
 		defer cancel()
 		mctx = manipulate.NewContext(ctx)
 	}
@@ -245,14 +263,16 @@ func (s *httpManipulator) Retrieve(mctx manipulate.Context, object elemental.Ide
 	return nil
 }
 
-func (s *httpManipulator) Create(mctx manipulate.Context, object elemental.Identifiable) error {
+func (s *httpManipulator) Create(mctx manipulate.Context, object elemental.Identifiable) (err error) {
 
 	if object == nil {
 		return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("nil object")}
 	}
 
+	defer s.recordOperation(elemental.OperationCreate, object.Identity(), time.Now(), &err)
+
 	if mctx == nil {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout)
 		defer cancel()
 		mctx = manipulate.NewContext(ctx)
 	}
@@ -303,14 +323,16 @@ func (s *httpManipulator) Create(mctx manipulate.Context, object elemental.Ident
 	return nil
 }
 
-func (s *httpManipulator) Update(mctx manipulate.Context, object elemental.Identifiable) error {
+func (s *httpManipulator) Update(mctx manipulate.Context, object elemental.Identifiable) (err error) {
 
 	if object == nil {
 		return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("nil object")}
 	}
 
+	defer s.recordOperation(elemental.OperationUpdate, object.Identity(), time.Now(), &err)
+
 	if mctx == nil {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout)
 		defer cancel()
 		mctx = manipulate.NewContext(ctx)
 	}
@@ -366,14 +388,16 @@ func (s *httpManipulator) Update(mctx manipulate.Context, object elemental.Ident
 	return nil
 }
 
-func (s *httpManipulator) Delete(mctx manipulate.Context, object elemental.Identifiable) error {
+func (s *httpManipulator) Delete(mctx manipulate.Context, object elemental.Identifiable) (err error) {
 
 	if object == nil {
 		return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("nil object")}
 	}
 
+	defer s.recordOperation(elemental.OperationDelete, object.Identity(), time.Now(), &err)
+
 	if mctx == nil {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout)
 		defer cancel()
 		mctx = manipulate.NewContext(ctx)
 	}
@@ -408,14 +432,53 @@ func (s *httpManipulator) Delete(mctx manipulate.Context, object elemental.Ident
 	return nil
 }
 
-func (s *httpManipulator) DeleteMany(mctx manipulate.Context, identity elemental.Identity) error {
-	return manipulate.ErrNotImplemented{Err: fmt.Errorf("DeleteMany not implemented in maniphttp")}
+// DeleteMany deletes all the objects matching the filter in the given
+// context and sets the number of removed objects in mctx.Count().
+// Calling it without any filter requires ContextOptionAllowDeleteAll.
+func (s *httpManipulator) DeleteMany(mctx manipulate.Context, identity elemental.Identity) (err error) {
+
+	defer s.recordOperation(elemental.OperationDelete, identity, time.Now(), &err)
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("maniphttp.delete_many.%s", identity.Category))
+	defer sp.Finish()
+
+	if f := mctx.Filter(); f == nil || len(f.Operators()) == 0 {
+		if _, ok := mctx.(opaquer).Opaque()[opaqueKeyAllowDeleteAll]; !ok {
+			err := manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("refusing to delete all objects without ContextOptionAllowDeleteAll")}
+			sp.SetTag("error", true)
+			sp.LogFields(log.Error(err))
+			return err
+		}
+	}
+
+	url, err := s.getURLForChildrenIdentity(mctx.Parent(), identity, 0, mctx.Version())
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return manipulate.ErrCannotBuildQuery{Err: err}
+	}
+
+	if _, err = s.send(mctx, http.MethodDelete, url, nil, nil, sp); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	return nil
 }
 
-func (s *httpManipulator) Count(mctx manipulate.Context, identity elemental.Identity) (int, error) {
+func (s *httpManipulator) Count(mctx manipulate.Context, identity elemental.Identity) (_ int, err error) {
+
+	defer s.recordOperation(elemental.OperationInfo, identity, time.Now(), &err)
 
 	if mctx == nil {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout)
 		defer cancel()
 		mctx = manipulate.NewContext(ctx)
 	}
@@ -439,6 +502,84 @@ func (s *httpManipulator) Count(mctx manipulate.Context, identity elemental.Iden
 	return mctx.Count(), nil
 }
 
+// ServerInfo returns the version of the API server, as advertised by the
+// Server header of its response to a HEAD request on the root url.
+func (s *httpManipulator) ServerInfo(ctx context.Context) (manipulate.ServerInfo, error) {
+
+	mctx := manipulate.NewContext(ctx)
+
+	sp := tracing.StartTrace(mctx, "maniphttp.server_info")
+	defer sp.Finish()
+
+	response, err := s.send(mctx, http.MethodHead, s.url, nil, nil, sp)
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return manipulate.ServerInfo{}, err
+	}
+
+	return manipulate.ServerInfo{
+		Backend:      "http",
+		Version:      response.Header.Get("Server"),
+		Capabilities: []string{},
+	}, nil
+}
+
+// Ping verifies that the API server is reachable
+// by sending a HEAD request on its root url.
+func (s *httpManipulator) Ping(ctx context.Context) error {
+
+	mctx := manipulate.NewContext(ctx)
+
+	sp := tracing.StartTrace(mctx, "maniphttp.ping")
+	defer sp.Finish()
+
+	if _, err := s.send(mctx, http.MethodHead, s.url, nil, nil, sp); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// close stops accepting new requests and waits for the in-flight
+// ones to complete, or for the given context to be done. The idle
+// connections are then closed.
+func (s *httpManipulator) close(ctx context.Context) error {
+
+	s.closeLock.Lock()
+	s.closed = true
+	s.closeLock.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = manipulate.ErrCannotCommunicate{Err: fmt.Errorf("unable to drain in-flight requests: %w", ctx.Err())}
+	}
+
+	if s.client != nil {
+		s.client.CloseIdleConnections()
+	}
+
+	return err
+}
+
+// recordOperation reports the given operation to the metrics recorder.
+func (s *httpManipulator) recordOperation(operation elemental.Operation, identity elemental.Identity, start time.Time, err *error) {
+
+	if s.metricsRecorder != nil {
+		s.metricsRecorder.RecordOperation(operation, identity, time.Since(start), *err)
+	}
+}
+
 func (s *httpManipulator) makeAuthorizationHeaders(username, password string) string {
 
 	return username + " " + password
@@ -457,6 +598,12 @@ func (s *httpManipulator) prepareHeaders(request *http.Request, mctx manipulate.
 
 	opaque := mctx.(opaquer).Opaque()
 
+	if headers, ok := opaque[opaqueKeyHeaders].(http.Header); ok {
+		for k, v := range headers {
+			request.Header[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+
 	if value, ok := opaque[opaqueKeyOverrideHeaderContentType]; ok {
 		request.Header.Set("Content-Type", value.(string))
 	} else {
@@ -528,6 +675,17 @@ func (s *httpManipulator) readHeaders(response *http.Response, mctx manipulate.C
 	mctx.SetCount(t)
 	mctx.SetNext(response.Header.Get("X-Next"))
 	mctx.SetMessages(response.Header["X-Messages"])
+
+	if headers, ok := mctx.(opaquer).Opaque()[opaqueKeyResponseHeaders].(http.Header); ok {
+		// Only the headers of the last response must be kept,
+		// not the ones of a previous response or of a previous call.
+		for k := range headers {
+			delete(headers, k)
+		}
+		for k, v := range response.Header {
+			headers[k] = append([]string{}, v...)
+		}
+	}
 }
 
 func (s *httpManipulator) computeVersion(modelVersion int, mctxVersion int) string {
@@ -588,6 +746,16 @@ func (s *httpManipulator) send(
 	sp opentracing.Span,
 ) (*http.Response, error) {
 
+	s.closeLock.RLock()
+	if s.closed {
+		s.closeLock.RUnlock()
+		return nil, manipulate.ErrDisconnected{Err: fmt.Errorf("manipulator is closed")}
+	}
+	s.inflight.Add(1)
+	s.closeLock.RUnlock()
+
+	defer s.inflight.Done()
+
 	if len(s.failureSimulations) > 0 {
 		for chance, err := range s.failureSimulations {
 			if rand.Float64() < chance {
@@ -695,7 +863,13 @@ func (s *httpManipulator) send(
 
 			case context.DeadlineExceeded:
 				if lastError == nil {
-					lastError = manipulate.ErrCannotCommunicate{Err: fmt.Errorf(snip.Snip(err, s.currentPassword()).Error())}
+					// If the main context expired, we make it clear
+					// the timeout comes from the client and not from the server.
+					if mctx.Context().Err() == context.DeadlineExceeded {
+						lastError = manipulate.ErrCannotCommunicate{Err: fmt.Errorf("client-side timeout: %s", snip.Snip(err, s.currentPassword()).Error())}
+					} else {
+						lastError = manipulate.ErrCannotCommunicate{Err: fmt.Errorf(snip.Snip(err, s.currentPassword()).Error())}
+					}
 				}
 				goto RETRY
 
@@ -865,7 +1039,11 @@ func (s *httpManipulator) send(
 		default:
 			// Otherwise we sleep backoff and we restart the retry loop.
 
-			time.Sleep(backoff.NextWithCurve(try, deadline, retryCurve))
+			wait := backoff.NextWithCurve(try, deadline, retryCurve)
+			if !s.disableBackoffJitter {
+				wait = backoff.Jitter(wait)
+			}
+			time.Sleep(wait)
 			try++
 		}
 	}
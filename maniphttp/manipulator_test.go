@@ -14,10 +14,12 @@ package maniphttp
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -291,6 +293,62 @@ func TestHTTP_RetrieveMany(t *testing.T) {
 				So(err, ShouldNotBeNil)
 			})
 		})
+
+		Convey("When I retrieve objects with a page but no page size", func() {
+
+			var l testmodel.ListsList
+
+			ctx := manipulate.NewContext(
+				context.Background(),
+				manipulate.ContextOptionPage(2, 0),
+			)
+
+			err := m.RetrieveMany(ctx, &l)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrInvalidQuery{})
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator and a server that returns the total count", t, func() {
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Count-Total", "42")
+			fmt.Fprint(w, `[{"ID": "1", "name": "name1"}, {"ID": "2", "name": "name2"}]`)
+		}))
+		defer ts.Close()
+
+		mm, _ := New(context.Background(), ts.URL)
+		m := mm.(*httpManipulator)
+
+		Convey("When I retrieve the objects", func() {
+
+			list := testmodel.NewList()
+			list.ID = "xxx"
+
+			mctx := manipulate.NewContext(
+				context.Background(),
+				manipulate.ContextOptionParent(list),
+			)
+
+			var l testmodel.TasksList
+			err := m.RetrieveMany(mctx, &l)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the length of the children list should be 2", func() {
+				So(len(l), ShouldEqual, 2)
+			})
+
+			Convey("Then the total count should be surfaced in the context", func() {
+				So(mctx.Count(), ShouldEqual, 42)
+			})
+		})
 	})
 
 	Convey("Given I have a manipulator and the server returns no data", t, func() {
@@ -521,6 +579,30 @@ func TestHTTP_Retrieve(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Given I have a manipulator with a default timeout and a server never returning", t, func() {
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(3 * time.Second)
+		}))
+		defer ts.Close()
+
+		mm, _ := New(context.Background(), ts.URL, OptionDefaultTimeout(time.Second))
+		m := mm.(*httpManipulator)
+
+		Convey("When I retrieve an object without context", func() {
+
+			list := testmodel.NewList()
+			list.ID = "xxx"
+			err := m.Retrieve(nil, list)
+
+			Convey("Then err should be a client-side timeout", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotCommunicate{})
+				So(err.Error(), ShouldContainSubstring, "client-side timeout")
+			})
+		})
+	})
 }
 
 func TestHTTP_Create(t *testing.T) {
@@ -921,15 +1003,222 @@ func TestHTTP_Delete(t *testing.T) {
 
 func TestHTTP_DeleteMany(t *testing.T) {
 
-	Convey("Given I have a manipulator", t, func() {
+	Convey("Given I have a manipulator and a working server", t, func() {
+
+		var method, path, query string
 
-		mm, _ := New(context.Background(), "https://fake.com")
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			path = r.URL.Path
+			query = r.URL.Query().Get("q")
+			w.Header().Set("X-Count-Total", "3")
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer ts.Close()
+
+		mm, _ := New(context.Background(), ts.URL)
 		m := mm.(*httpManipulator)
 
-		err := m.DeleteMany(nil, testmodel.TaskIdentity)
+		Convey("When I call DeleteMany with a filter", func() {
 
-		Convey("Then err should not be nil", func() {
-			So(err, ShouldNotBeNil)
+			mctx := manipulate.NewContext(
+				context.Background(),
+				manipulate.ContextOptionFilter(
+					elemental.NewFilterComposer().WithKey("name").Equals("a").Done(),
+				),
+			)
+
+			err := m.DeleteMany(mctx, testmodel.TaskIdentity)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the request should be correct", func() {
+				So(method, ShouldEqual, http.MethodDelete)
+				So(path, ShouldEqual, "/tasks")
+				So(query, ShouldEqual, `name == "a"`)
+			})
+
+			Convey("Then the deleted count should be surfaced in the context", func() {
+				So(mctx.Count(), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When I call DeleteMany without a filter", func() {
+
+			err := m.DeleteMany(nil, testmodel.TaskIdentity)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotBuildQuery{})
+			})
+
+			Convey("Then no request should have been sent", func() {
+				So(method, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I call DeleteMany with an empty filter", func() {
+
+			mctx := manipulate.NewContext(
+				context.Background(),
+				manipulate.ContextOptionFilter(elemental.NewFilterComposer().Done()),
+			)
+
+			err := m.DeleteMany(mctx, testmodel.TaskIdentity)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotBuildQuery{})
+				So(method, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I call DeleteMany without a filter but allowing to delete all", func() {
+
+			mctx := manipulate.NewContext(context.Background(), ContextOptionAllowDeleteAll())
+
+			err := m.DeleteMany(mctx, testmodel.TaskIdentity)
+
+			Convey("Then the request should be correct", func() {
+				So(err, ShouldBeNil)
+				So(method, ShouldEqual, http.MethodDelete)
+				So(path, ShouldEqual, "/tasks")
+				So(query, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I call DeleteMany with a parent that has no ID", func() {
+
+			mctx := manipulate.NewContext(
+				context.Background(),
+				manipulate.ContextOptionParent(testmodel.NewList()),
+				ContextOptionAllowDeleteAll(),
+			)
+
+			err := m.DeleteMany(mctx, testmodel.TaskIdentity)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotBuildQuery{})
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator and a server that returns an error", t, func() {
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `[{"code": 403, "title": "nope", "description": "nope."}]`)
+		}))
+		defer ts.Close()
+
+		mm, _ := New(context.Background(), ts.URL)
+		m := mm.(*httpManipulator)
+
+		Convey("When I call DeleteMany", func() {
+
+			err := m.DeleteMany(manipulate.NewContext(context.Background(), ContextOptionAllowDeleteAll()), testmodel.TaskIdentity)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestHTTP_ServerInfo(t *testing.T) {
+
+	Convey("Given I have a manipulator and a server advertising its version", t, func() {
+
+		var method string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			w.Header().Set("Server", "bahamut/1.2.3")
+		}))
+		defer ts.Close()
+
+		mm, _ := New(context.Background(), ts.URL)
+		m := mm.(*httpManipulator)
+
+		Convey("When I call ServerInfo", func() {
+
+			info, err := m.ServerInfo(context.Background())
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the info should be correct", func() {
+				So(method, ShouldEqual, http.MethodHead)
+				So(info.Backend, ShouldEqual, "http")
+				So(info.Version, ShouldEqual, "bahamut/1.2.3")
+				So(info.HasCapability("transactions"), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator and a server returning an error", t, func() {
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer ts.Close()
+
+		mm, _ := New(context.Background(), ts.URL)
+		m := mm.(*httpManipulator)
+
+		Convey("When I call ServerInfo", func() {
+
+			_, err := m.ServerInfo(context.Background())
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestHTTP_Ping(t *testing.T) {
+
+	Convey("Given I have a manipulator and a server that is up", t, func() {
+
+		var method string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+		}))
+		defer ts.Close()
+
+		mm, _ := New(context.Background(), ts.URL)
+
+		Convey("When I call Ping", func() {
+
+			err := manipulate.Ping(context.Background(), mm)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+				So(method, ShouldEqual, http.MethodHead)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator and a server returning an error", t, func() {
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer ts.Close()
+
+		mm, _ := New(context.Background(), ts.URL)
+
+		Convey("When I call Ping", func() {
+
+			err := manipulate.Ping(context.Background(), mm)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
 		})
 	})
 }
@@ -1006,6 +1295,93 @@ func TestHTTP_Count(t *testing.T) {
 	})
 }
 
+func TestHTTP_Namespace(t *testing.T) {
+
+	Convey("Given I have a manipulator with a default namespace and a working server", t, func() {
+
+		var lock sync.Mutex
+		namespaces := map[string]string{}
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			namespaces[r.Method] = r.Header.Get("X-Namespace")
+			lock.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Count-Total", "1")
+			switch {
+			case r.Method == http.MethodHead:
+			case r.Method == http.MethodGet && r.URL.Path == "/lists":
+				fmt.Fprint(w, `[{"ID": "xxx"}]`)
+			default:
+				fmt.Fprint(w, `{"ID": "xxx"}`)
+			}
+		}))
+		defer ts.Close()
+
+		m, _ := New(context.Background(), ts.URL, OptionNamespace("/default"))
+
+		run := func(mctx manipulate.Context) {
+			list := testmodel.NewList()
+			list.ID = "xxx"
+			So(m.RetrieveMany(mctx, &testmodel.ListsList{}), ShouldBeNil)
+			So(m.Create(mctx, testmodel.NewList()), ShouldBeNil)
+			So(m.Update(mctx, list), ShouldBeNil)
+			So(m.Delete(mctx, list), ShouldBeNil)
+			_, err := m.Count(mctx, testmodel.ListIdentity)
+			So(err, ShouldBeNil)
+		}
+
+		Convey("When I run operations with a namespace in the context", func() {
+
+			run(manipulate.NewContext(context.Background(), manipulate.ContextOptionNamespace("/other")))
+
+			Convey("Then the namespace of the context should be used", func() {
+				lock.Lock()
+				defer lock.Unlock()
+				So(namespaces, ShouldResemble, map[string]string{
+					http.MethodGet:    "/other",
+					http.MethodPost:   "/other",
+					http.MethodPut:    "/other",
+					http.MethodDelete: "/other",
+					http.MethodHead:   "/other",
+				})
+			})
+
+			Convey("When I retrieve an object with a namespace in the context", func() {
+
+				list := testmodel.NewList()
+				list.ID = "xxx"
+
+				err := m.Retrieve(manipulate.NewContext(context.Background(), manipulate.ContextOptionNamespace("/another")), list)
+
+				Convey("Then the namespace of the context should be used", func() {
+					lock.Lock()
+					defer lock.Unlock()
+					So(err, ShouldBeNil)
+					So(namespaces[http.MethodGet], ShouldEqual, "/another")
+				})
+			})
+		})
+
+		Convey("When I run operations without namespace in the context", func() {
+
+			run(manipulate.NewContext(context.Background()))
+
+			Convey("Then the default namespace should be used", func() {
+				lock.Lock()
+				defer lock.Unlock()
+				So(namespaces, ShouldResemble, map[string]string{
+					http.MethodGet:    "/default",
+					http.MethodPost:   "/default",
+					http.MethodPut:    "/default",
+					http.MethodDelete: "/default",
+					http.MethodHead:   "/default",
+				})
+			})
+		})
+	})
+}
+
 func TestHTTP_send(t *testing.T) {
 
 	sp := tracing.StartTrace(nil, "test")
@@ -1076,7 +1452,7 @@ func TestHTTP_send(t *testing.T) {
 
 		So(err, ShouldNotBeNil)
 		So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotCommunicate{})
-		So(err.Error(), ShouldEqual, `Cannot communicate: Post "https://google.com": context deadline exceeded`)
+		So(err.Error(), ShouldEqual, `Cannot communicate: client-side timeout: Post "https://google.com": context deadline exceeded`)
 
 		So(resp, ShouldBeNil)
 	})
@@ -1335,6 +1711,30 @@ func TestHTTP_send(t *testing.T) {
 		So(resp, ShouldBeNil)
 	})
 
+	Convey("Given I have a server never returning and I cancel the context", t, func() {
+
+		m, _ := New(context.Background(), "toto.com")
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(3 * time.Second)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(200*time.Millisecond, cancel)
+
+		now := time.Now()
+		resp, err := m.(*httpManipulator).send(
+			manipulate.NewContext(ctx), http.MethodPost, ts.URL, nil, nil, sp)
+
+		So(time.Since(now), ShouldBeLessThan, 2*time.Second)
+		So(err, ShouldNotBeNil)
+		So(err, ShouldHaveSameTypeAs, manipulate.ErrDisconnected{})
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+
+		So(resp, ShouldBeNil)
+	})
+
 	Convey("Given I have a server never returning", t, func() {
 
 		m, _ := New(context.Background(), "toto.com")
@@ -1352,7 +1752,7 @@ func TestHTTP_send(t *testing.T) {
 
 		So(err, ShouldNotBeNil)
 		So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotCommunicate{})
-		So(err.Error(), ShouldEqual, fmt.Sprintf(`Cannot communicate: Post "%s": context deadline exceeded`, ts.URL))
+		So(err.Error(), ShouldEqual, fmt.Sprintf(`Cannot communicate: client-side timeout: Post "%s": context deadline exceeded`, ts.URL))
 
 		So(resp, ShouldBeNil)
 	})
@@ -1923,6 +2323,44 @@ func TestHTTP_prepareHeaders(t *testing.T) {
 					So(req.Header.Get("Accept"), ShouldEqual, "mew")
 				})
 			})
+
+			Convey("When I prepareHeaders with a namespace in the context", func() {
+
+				m.prepareHeaders(req, manipulate.NewContext(context.Background(), manipulate.ContextOptionNamespace("/other")))
+
+				Convey("Then the namespace of the context should be used", func() {
+					So(req.Header.Get("X-Namespace"), ShouldEqual, "/other")
+				})
+
+				Convey("Then the namespace of the manipulator should not change", func() {
+					So(m.namespace, ShouldEqual, "myns")
+
+					req2, _ := http.NewRequest("GET", "http://fake.com", nil)
+					m.prepareHeaders(req2, manipulate.NewContext(context.Background()))
+					So(req2.Header.Get("X-Namespace"), ShouldEqual, "myns")
+				})
+			})
+
+			Convey("When I prepareHeaders with using ContextOptionHeaders", func() {
+
+				ctx := manipulate.NewContext(
+					context.Background(),
+					ContextOptionHeaders(http.Header{
+						"x-trace":     []string{"abc"},
+						"Header-1":    []string{"overridden"},
+						"X-Namespace": []string{"other"},
+					}),
+				)
+
+				m.prepareHeaders(req, ctx)
+
+				Convey("Then header should be correct", func() {
+					So(req.Header.Get("X-Trace"), ShouldEqual, "abc")
+					So(req.Header.Get("Header-1"), ShouldEqual, "overridden")
+					So(req.Header.Get("Header-2"), ShouldEqual, "ho")
+					So(req.Header.Get("X-Namespace"), ShouldEqual, "myns")
+				})
+			})
 		})
 	})
 }
@@ -1970,6 +2408,40 @@ func TestHTTP_readHeaders(t *testing.T) {
 				So(ctx.Messages(), ShouldResemble, []string{"hello", "bonjour"})
 			})
 		})
+
+		Convey("When I readHeaders with a context asking for the response headers", func() {
+
+			headers := http.Header{}
+			ctx = manipulate.NewContext(context.Background(), ContextOptionResponseHeaders(headers))
+
+			req.Header.Set("X-Request-ID", "abcd")
+			req.Header["Warning"] = []string{"299 - deprecated", "299 - removed soon"}
+
+			m.readHeaders(req, ctx)
+
+			Convey("Then the headers should have been populated", func() {
+				So(headers.Get("X-Request-ID"), ShouldEqual, "abcd")
+				So(headers["Warning"], ShouldResemble, []string{"299 - deprecated", "299 - removed soon"})
+			})
+		})
+
+		Convey("When I readHeaders twice with a context asking for the response headers", func() {
+
+			headers := http.Header{"X-Stale": []string{"yes"}}
+			ctx = manipulate.NewContext(context.Background(), ContextOptionResponseHeaders(headers))
+
+			req.Header.Set("X-Request-ID", "abcd")
+			req.Header.Set("X-Rate-Limit-Remaining", "10")
+			m.readHeaders(req, ctx)
+
+			last := &http.Response{Header: http.Header{}}
+			last.Header.Set("X-Request-ID", "efgh")
+			m.readHeaders(last, ctx)
+
+			Convey("Then only the headers of the last response should be kept", func() {
+				So(headers, ShouldResemble, http.Header{"X-Request-Id": []string{"efgh"}})
+			})
+		})
 	})
 }
 
@@ -165,6 +165,17 @@ func OptionTCPUserTimeout(t time.Duration) Option {
 	}
 }
 
+// OptionDefaultTimeout sets the timeout of the operations
+// performed with a nil manipulate.Context.
+// When a manipulate.Context is given, the deadline of its
+// context.Context is used instead.
+// The default is 2 minutes.
+func OptionDefaultTimeout(timeout time.Duration) Option {
+	return func(m *httpManipulator) {
+		m.defaultTimeout = timeout
+	}
+}
+
 // OptionBackoffCurve configures the backoff curve
 // the manipulator will use when performing internal retry
 // operations.
@@ -175,6 +186,30 @@ func OptionBackoffCurve(curve []time.Duration) Option {
 	}
 }
 
+// OptionDisableBackoffJitter disables the randomization of the time
+// waited between two retries. By default, a random duration up to the
+// one given by the backoff curve is used, so clients failing at the same
+// time do not retry all together.
+func OptionDisableBackoffJitter() Option {
+	return func(m *httpManipulator) {
+		m.disableBackoffJitter = true
+	}
+}
+
+// OptionMetricsRecorder sets the manipulate.MetricsRecorder
+// notified of every operation performed by the manipulator.
+// The default records nothing.
+func OptionMetricsRecorder(recorder manipulate.MetricsRecorder) Option {
+
+	if recorder == nil {
+		panic("recorder must not be nil")
+	}
+
+	return func(m *httpManipulator) {
+		m.metricsRecorder = recorder
+	}
+}
+
 // OptionStrongBackoffCurve configures the strong backoff curve
 // the manipulator will use when performing internal retry
 // operations that necessitate to wait more than usual like
@@ -189,6 +224,9 @@ func OptionStrongBackoffCurve(curve []time.Duration) Option {
 var (
 	opaqueKeyOverrideHeaderContentType = "maniphttp.opaqueKeyOverrideHeaderContentType"
 	opaqueKeyOverrideHeaderAccept      = "maniphttp.opaqueKeyOverrideHeaderAccept"
+	opaqueKeyResponseHeaders           = "maniphttp.opaqueKeyResponseHeaders"
+	opaqueKeyHeaders                   = "maniphttp.opaqueKeyHeaders"
+	opaqueKeyAllowDeleteAll            = "maniphttp.opaqueKeyAllowDeleteAll"
 )
 
 type opaquer interface {
@@ -214,3 +252,45 @@ func ContextOptionOverrideAccept(accept string) manipulate.ContextOption {
 		c.(opaquer).Opaque()[opaqueKeyOverrideHeaderAccept] = accept
 	}
 }
+
+// ContextOptionResponseHeaders allows to retrieve the raw headers
+// of the last response received from the server during the operation.
+// The given http.Header will be populated with all the headers sent
+// by the server (for instance rate limit information, deprecation
+// warnings or trace identifiers), including the ones already
+// interpreted by the manipulator, like X-Count-Total, X-Next or X-Messages.
+// The given headers must not be nil.
+func ContextOptionResponseHeaders(headers http.Header) manipulate.ContextOption {
+
+	if headers == nil {
+		panic("headers must not be nil")
+	}
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyResponseHeaders] = headers
+	}
+}
+
+// ContextOptionHeaders sets additional headers to send
+// with the requests of the operation. They are applied after the ones
+// set by OptionAdditonalHeaders, and replace them if they have the
+// same name. The headers set by the manipulator itself, like
+// X-Namespace, Authorization or Content-Type, always take precedence.
+// Use the dedicated options to change them.
+func ContextOptionHeaders(headers http.Header) manipulate.ContextOption {
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyHeaders] = headers
+	}
+}
+
+// ContextOptionAllowDeleteAll allows DeleteMany to run without any filter
+// in the manipulate.Context. Without this option, calling DeleteMany with
+// a nil or empty filter will return a manipulate.ErrCannotBuildQuery
+// in order to prevent accidentally wiping an entire collection.
+func ContextOptionAllowDeleteAll() manipulate.ContextOption {
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyAllowDeleteAll] = true
+	}
+}
@@ -130,6 +130,29 @@ func Test_Options(t *testing.T) {
 		So(m.tcpUserTimeout, ShouldEqual, t)
 	})
 
+	Convey("Calling OptionDisableBackoffJitter should work", t, func() {
+		m := &httpManipulator{}
+		OptionDisableBackoffJitter()(m)
+		So(m.disableBackoffJitter, ShouldBeTrue)
+	})
+
+	Convey("Calling OptionMetricsRecorder should work", t, func() {
+		r := manipulate.NewNoopMetricsRecorder()
+		m := &httpManipulator{}
+		OptionMetricsRecorder(r)(m)
+		So(m.metricsRecorder, ShouldEqual, r)
+	})
+
+	Convey("Calling OptionMetricsRecorder with a nil recorder should panic", t, func() {
+		So(func() { OptionMetricsRecorder(nil) }, ShouldPanicWith, "recorder must not be nil")
+	})
+
+	Convey("Calling OptionDefaultTimeout should work", t, func() {
+		m := &httpManipulator{}
+		OptionDefaultTimeout(10 * time.Second)(m)
+		So(m.defaultTimeout, ShouldEqual, 10*time.Second)
+	})
+
 	Convey("Calling OptionBackoffCurve should work", t, func() {
 		m := &httpManipulator{}
 		t := []time.Duration{10 * time.Second}
@@ -155,4 +178,28 @@ func Test_Options(t *testing.T) {
 		ContextOptionOverrideAccept("chien")(mctx)
 		So(mctx.(opaquer).Opaque()[opaqueKeyOverrideHeaderAccept], ShouldEqual, "chien")
 	})
+
+	Convey("Calling ContextOptionResponseHeaders should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		h := http.Header{}
+		ContextOptionResponseHeaders(h)(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyResponseHeaders], ShouldResemble, h)
+	})
+
+	Convey("Calling ContextOptionHeaders should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		h := http.Header{"X-Tenant": []string{"a"}}
+		ContextOptionHeaders(h)(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyHeaders], ShouldResemble, h)
+	})
+
+	Convey("Calling ContextOptionAllowDeleteAll should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionAllowDeleteAll()(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyAllowDeleteAll], ShouldEqual, true)
+	})
+
+	Convey("Calling ContextOptionResponseHeaders with nil headers should panic", t, func() {
+		So(func() { ContextOptionResponseHeaders(nil) }, ShouldPanicWith, "headers must not be nil")
+	})
 }
@@ -16,7 +16,9 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"go.aporeto.io/elemental"
 	"go.aporeto.io/manipulate"
 	"go.aporeto.io/manipulate/internal/push"
 )
@@ -28,6 +30,12 @@ type subscribeConfig struct {
 	supportErrorEvents  bool
 	recursive           bool
 	tlsConfig           *tls.Config
+	coalesceWindow      time.Duration
+	reorderWindow       time.Duration
+	lagFunc             func(time.Duration, *elemental.Event)
+	pingPeriod          time.Duration
+	pongWait            time.Duration
+	filter              *elemental.Filter
 }
 
 func newSubscribeConfig(m *httpManipulator) subscribeConfig {
@@ -82,7 +90,76 @@ func SubscriberOptionSupportErrorEvents() SubscriberOption {
 	}
 }
 
+// SubscriberOptionCoalesceWindow enables the coalescing of events.
+// Multiple events targeting the same object received within the given
+// window are collapsed into the most recent one before being delivered.
+// A delete event is never dropped in favor of a previous or subsequent
+// update. Events are delayed by at most the given window.
+func SubscriberOptionCoalesceWindow(window time.Duration) SubscriberOption {
+	return func(cfg *subscribeConfig) {
+		cfg.coalesceWindow = window
+	}
+}
+
+// SubscriberOptionReorderWindow enables the reordering of events by timestamp.
+// This is useful after a reconnection, when replayed events and live events
+// may be received interleaved. Events are buffered for at least the given
+// window, and at most twice that, then delivered sorted by timestamp.
+// Delivery is guaranteed to be monotonic as long as an event is not received
+// more than the window after an event with a more recent timestamp. Otherwise,
+// it is delivered as soon as possible.
+func SubscriberOptionReorderWindow(window time.Duration) SubscriberOption {
+	return func(cfg *subscribeConfig) {
+		cfg.reorderWindow = window
+	}
+}
+
+// SubscriberOptionLagFunc sets a function that will be called with the
+// lag of every received event, computed as the time elapsed since the event
+// was emitted. This assumes the clocks of the client and the server are
+// synchronized; a negative lag caused by clock skew is reported as zero.
+// The function is called from the read loop and must return quickly.
+func SubscriberOptionLagFunc(f func(lag time.Duration, event *elemental.Event)) SubscriberOption {
+	return func(cfg *subscribeConfig) {
+		cfg.lagFunc = f
+	}
+}
+
+// SubscriberOptionKeepAlive sets the interval between the pings sent to
+// the server and how long to wait for a pong before considering the connection
+// dead and reconnecting. This is useful to keep the connection alive through
+// load balancers dropping idle connections, and to detect them faster.
+// The default is to ping every 5 seconds and to wait 10 seconds for a pong.
+// The pong wait must be greater than the ping period.
+func SubscriberOptionKeepAlive(pingPeriod time.Duration, pongWait time.Duration) SubscriberOption {
+
+	if pingPeriod <= 0 {
+		panic("ping period must be greater than 0")
+	}
+
+	if pongWait <= pingPeriod {
+		panic("pong wait must be greater than ping period")
+	}
+
+	return func(cfg *subscribeConfig) {
+		cfg.pingPeriod = pingPeriod
+		cfg.pongWait = pongWait
+	}
+}
+
+// SubscriberOptionFilter sets a filter that is sent to the server
+// so it only pushes the events matching it. Servers that do not support
+// it ignore it, so the PushConfig given to Start should still be used
+// to filter the events by identity on the client side.
+func SubscriberOptionFilter(filter *elemental.Filter) SubscriberOption {
+	return func(cfg *subscribeConfig) {
+		cfg.filter = filter
+	}
+}
+
 // NewSubscriber returns a new subscription.
+// If the manipulator uses a TokenManager, the token is renewed
+// when the server rejects it while connecting.
 func NewSubscriber(manipulator manipulate.Manipulator, options ...SubscriberOption) manipulate.Subscriber {
 
 	m, ok := manipulator.(*httpManipulator)
@@ -103,6 +180,21 @@ func NewSubscriber(manipulator manipulate.Manipulator, options ...SubscriberOpti
 		cfg.tlsConfig.NextProtos = nil
 	}
 
+	pushOptions := []push.Option{
+		push.OptionCoalesceWindow(cfg.coalesceWindow),
+		push.OptionReorderWindow(cfg.reorderWindow),
+		push.OptionLagFunc(cfg.lagFunc),
+		push.OptionFilter(cfg.filter),
+	}
+
+	if cfg.pingPeriod > 0 {
+		pushOptions = append(pushOptions, push.OptionKeepAlive(cfg.pingPeriod, cfg.pongWait))
+	}
+
+	if m.tokenManager != nil {
+		pushOptions = append(pushOptions, push.OptionTokenRenewer(m.atomicRenewTokenFunc))
+	}
+
 	return push.NewSubscriber(
 		fmt.Sprintf("%s/%s", m.url, cfg.endpoint),
 		cfg.namespace,
@@ -117,6 +209,7 @@ func NewSubscriber(manipulator manipulate.Manipulator, options ...SubscriberOpti
 		cfg.supportErrorEvents,
 		cfg.recursive,
 		cfg.credentialCookieKey,
+		pushOptions...,
 	)
 }
 
@@ -14,8 +14,10 @@ package maniphttp
 import (
 	"crypto/tls"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
 	"go.aporeto.io/manipulate/maniptest"
 )
 
@@ -80,6 +82,45 @@ func TestOptions(t *testing.T) {
 		SubscriberOptionSupportErrorEvents()(&cfg)
 		So(cfg.supportErrorEvents, ShouldBeTrue)
 	})
+
+	Convey("SubscriberOptionCoalesceWindow should work", t, func() {
+		cfg := newSubscribeConfig(m)
+		SubscriberOptionCoalesceWindow(time.Second)(&cfg)
+		So(cfg.coalesceWindow, ShouldEqual, time.Second)
+	})
+
+	Convey("SubscriberOptionReorderWindow should work", t, func() {
+		cfg := newSubscribeConfig(m)
+		SubscriberOptionReorderWindow(time.Second)(&cfg)
+		So(cfg.reorderWindow, ShouldEqual, time.Second)
+	})
+
+	Convey("SubscriberOptionLagFunc should work", t, func() {
+		var called bool
+		cfg := newSubscribeConfig(m)
+		SubscriberOptionLagFunc(func(time.Duration, *elemental.Event) { called = true })(&cfg)
+		cfg.lagFunc(time.Second, nil)
+		So(called, ShouldBeTrue)
+	})
+
+	Convey("SubscriberOptionFilter should work", t, func() {
+		f := elemental.NewFilterComposer().WithKey("name").Equals("a").Done()
+		cfg := newSubscribeConfig(m)
+		SubscriberOptionFilter(f)(&cfg)
+		So(cfg.filter, ShouldEqual, f)
+	})
+
+	Convey("SubscriberOptionKeepAlive should work", t, func() {
+		cfg := newSubscribeConfig(m)
+		SubscriberOptionKeepAlive(time.Second, 2*time.Second)(&cfg)
+		So(cfg.pingPeriod, ShouldEqual, time.Second)
+		So(cfg.pongWait, ShouldEqual, 2*time.Second)
+	})
+
+	Convey("SubscriberOptionKeepAlive with invalid values should panic", t, func() {
+		So(func() { SubscriberOptionKeepAlive(0, time.Second) }, ShouldPanicWith, "ping period must be greater than 0")
+		So(func() { SubscriberOptionKeepAlive(time.Second, time.Second) }, ShouldPanicWith, "pong wait must be greater than ping period")
+	})
 }
 
 func TestNewSubscriber(t *testing.T) {
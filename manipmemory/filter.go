@@ -0,0 +1,392 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipmemory
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"go.aporeto.io/elemental"
+)
+
+// matchFilter returns true if the given object matches the given filter.
+// The keys of the filter are resolved to struct fields using the given
+// attributes, which map index names to struct field names, or by looking
+// up a struct field with the same name, case insensitively.
+//
+// The evaluation mimics the semantics of mongo: comparing a slice to a
+// value matches if any element of the slice matches the value. Like in
+// mongo, Contains with several values matches if the field contains any
+// of them, and several Contains on the same key must all match.
+func matchFilter(obj interface{}, f *elemental.Filter, attributes map[string]string) (bool, error) {
+	return matchFilterServed(obj, f, attributes, nil)
+}
+
+// matchFilterServed works like matchFilter, but considers the given
+// served condition as matching, as it has already been evaluated using
+// an index.
+func matchFilterServed(obj interface{}, f *elemental.Filter, attributes map[string]string, served *servedCondition) (bool, error) {
+
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	if v.Kind() != reflect.Struct {
+		return false, fmt.Errorf("stored object is not a struct: %T", obj)
+	}
+
+	for i, operator := range f.Operators() {
+
+		switch operator {
+
+		case elemental.AndOperator:
+
+			if served != nil && served.filter == f && served.index == i {
+				continue
+			}
+
+			fv, err := filterField(v, f.Keys()[i], attributes)
+			if err != nil {
+				return false, err
+			}
+
+			ok, err := matchComparator(fv, f.Comparators()[i], f.Values()[i])
+			if err != nil || !ok {
+				return false, err
+			}
+
+		case elemental.AndFilterOperator:
+
+			for _, sub := range f.AndFilters()[i] {
+				ok, err := matchFilterServed(obj, sub, attributes, served)
+				if err != nil || !ok {
+					return false, err
+				}
+			}
+
+		case elemental.OrFilterOperator:
+
+			var matched bool
+			for _, sub := range f.OrFilters()[i] {
+				ok, err := matchFilterServed(obj, sub, attributes, served)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				return false, nil
+			}
+
+		default:
+			return false, fmt.Errorf("invalid operator for memdb: %d", operator)
+		}
+	}
+
+	return true, nil
+}
+
+// A servedCondition identifies a condition of a
+// filter that has been evaluated using an index.
+type servedCondition struct {
+	filter *elemental.Filter
+	index  int
+}
+
+// An indexLookup describes how to find the candidates
+// of a filter using an index instead of scanning the table.
+type indexLookup struct {
+	index  string
+	values []interface{}
+	served servedCondition
+}
+
+// planIndexLookup returns the lookup serving one of the conditions every
+// object matching the given filter must satisfy, or nil if none of them
+// can be served by the given indexes.
+//
+// Equals, In and Contains are served by the index named after their key,
+// lowercased. Matches is served by a prefix lookup of that index when all
+// its patterns are like ^prefix, the trailing $ being ignored as it always
+// has been. The other comparators cannot be served.
+func planIndexLookup(f *elemental.Filter, indexes map[string]*memdb.IndexSchema) *indexLookup {
+
+	if f == nil {
+		return nil
+	}
+
+	for i, operator := range f.Operators() {
+
+		if operator != elemental.AndOperator {
+			continue
+		}
+
+		name := strings.ToLower(f.Keys()[i])
+		schema, ok := indexes[name]
+		if !ok || len(f.Values()[i]) == 0 {
+			continue
+		}
+
+		served := servedCondition{filter: f, index: i}
+
+		switch f.Comparators()[i] {
+
+		case elemental.EqualComparator:
+			return &indexLookup{index: name, values: f.Values()[i][:1], served: served}
+
+		case elemental.InComparator, elemental.ContainComparator:
+			return &indexLookup{index: name, values: f.Values()[i], served: served}
+
+		case elemental.MatchComparator:
+
+			if _, ok := schema.Indexer.(memdb.PrefixIndexer); !ok {
+				continue
+			}
+
+			if prefixes, ok := literalPrefixes(f.Values()[i]); ok {
+				return &indexLookup{index: name + "_prefix", values: prefixes, served: served}
+			}
+		}
+	}
+
+	// All the sub filters of an And must match,
+	// so any of them can provide the candidates.
+	for i, operator := range f.Operators() {
+
+		if operator != elemental.AndFilterOperator {
+			continue
+		}
+
+		for _, sub := range f.AndFilters()[i] {
+			if l := planIndexLookup(sub, indexes); l != nil {
+				return l
+			}
+		}
+	}
+
+	return nil
+}
+
+// literalPrefixes returns the prefixes matched by the given patterns,
+// or false if any of them is not like ^prefix.
+func literalPrefixes(patterns []interface{}) ([]interface{}, bool) {
+
+	out := make([]interface{}, len(patterns))
+
+	for i, pattern := range patterns {
+
+		s, ok := pattern.(string)
+		if !ok || !strings.HasPrefix(s, "^") {
+			return nil, false
+		}
+
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "^"), "$")
+		if regexp.QuoteMeta(s) != s {
+			return nil, false
+		}
+
+		out[i] = s
+	}
+
+	return out, true
+}
+
+func filterField(v reflect.Value, key string, attributes map[string]string) (reflect.Value, error) {
+
+	name := key
+	if attr, ok := attributes[strings.ToLower(key)]; ok {
+		name = attr
+	}
+
+	fv := fieldByName(v, name)
+	if !fv.IsValid() {
+		return reflect.Value{}, fmt.Errorf("invalid filter key '%s'", key)
+	}
+
+	return fv, nil
+}
+
+func matchComparator(fv reflect.Value, comparator elemental.FilterComparator, values []interface{}) (bool, error) {
+
+	equal := compareWith(func(c int) bool { return c == 0 })
+
+	switch comparator {
+
+	case elemental.EqualComparator:
+		return matchAny(fv, values[:1], equal), nil
+
+	case elemental.NotEqualComparator:
+		return !matchAny(fv, values[:1], equal), nil
+
+	case elemental.InComparator, elemental.ContainComparator:
+		return matchAny(fv, values, equal), nil
+
+	case elemental.NotInComparator, elemental.NotContainComparator:
+		return !matchAny(fv, values, equal), nil
+
+	case elemental.GreaterComparator:
+		return matchAny(fv, values[:1], compareWith(func(c int) bool { return c > 0 })), nil
+
+	case elemental.GreaterOrEqualComparator:
+		return matchAny(fv, values[:1], compareWith(func(c int) bool { return c >= 0 })), nil
+
+	case elemental.LesserComparator:
+		return matchAny(fv, values[:1], compareWith(func(c int) bool { return c < 0 })), nil
+
+	case elemental.LesserOrEqualComparator:
+		return matchAny(fv, values[:1], compareWith(func(c int) bool { return c <= 0 })), nil
+
+	case elemental.ExistsComparator:
+		return !fv.IsZero(), nil
+
+	case elemental.NotExistsComparator:
+		return fv.IsZero(), nil
+
+	case elemental.MatchComparator:
+
+		exps := make([]*regexp.Regexp, len(values))
+		for i, value := range values {
+			s, ok := value.(string)
+			if !ok {
+				return false, fmt.Errorf("matches filter values must be strings: %v", value)
+			}
+			exp, err := regexp.Compile(s)
+			if err != nil {
+				return false, fmt.Errorf("invalid regular expression '%s': %w", s, err)
+			}
+			exps[i] = exp
+		}
+
+		return matchAny(fv, values, func(a reflect.Value, b interface{}) bool {
+			if a.Kind() != reflect.String {
+				return false
+			}
+			for _, exp := range exps {
+				if exp.MatchString(a.String()) {
+					return true
+				}
+			}
+			return false
+		}), nil
+
+	default:
+		return false, fmt.Errorf("invalid comparator for memdb: %d", comparator)
+	}
+}
+
+// matchAny returns true if the given field value, or any of its elements
+// if it is a slice, matches any of the given values according to the
+// given match function.
+func matchAny(fv reflect.Value, values []interface{}, match func(reflect.Value, interface{}) bool) bool {
+
+	candidates := []reflect.Value{fv}
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		candidates = make([]reflect.Value, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			candidates[i] = fv.Index(i)
+		}
+	}
+
+	for _, c := range candidates {
+		for _, v := range values {
+			if match(c, v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compareWith returns a match function that compares the field value with
+// the filter value and passes the result to the given test function.
+// Values that cannot be compared never match.
+func compareWith(test func(int) bool) func(reflect.Value, interface{}) bool {
+	return func(fv reflect.Value, value interface{}) bool {
+		c := compareFilterValue(fv, value)
+		return c != incomparable && test(c)
+	}
+}
+
+// incomparable is returned by compareFilterValue when
+// the values cannot be compared.
+const incomparable = 2
+
+// compareFilterValue compares the given field value with the given filter value.
+// It returns -1, 0 or 1 if the field value is lesser, equal or greater than the
+// filter value, or incomparable if they cannot be compared.
+func compareFilterValue(fv reflect.Value, value interface{}) int {
+
+	if d, ok := value.(time.Duration); ok {
+		value = time.Now().Add(d)
+	}
+
+	vv := reflect.ValueOf(value)
+	if !fv.IsValid() || !vv.IsValid() {
+		return incomparable
+	}
+
+	if ta, ok := fv.Interface().(time.Time); ok {
+		tb, ok := value.(time.Time)
+		if !ok {
+			return incomparable
+		}
+		return compareValues(reflect.ValueOf(ta), reflect.ValueOf(tb))
+	}
+
+	if isNumber(fv.Kind()) && isNumber(vv.Kind()) {
+		return compareValues(reflect.ValueOf(toFloat(fv)), reflect.ValueOf(toFloat(vv)))
+	}
+
+	if fv.Kind() == reflect.String && vv.Kind() == reflect.String {
+		return strings.Compare(fv.String(), vv.String())
+	}
+
+	if fv.Kind() == reflect.Bool && vv.Kind() == reflect.Bool {
+		return compareValues(fv, vv)
+	}
+
+	if reflect.DeepEqual(fv.Interface(), value) {
+		return 0
+	}
+
+	return incomparable
+}
+
+func isNumber(k reflect.Kind) bool {
+
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func toFloat(v reflect.Value) float64 {
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipmemory
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"go.aporeto.io/elemental"
+)
+
+func Test_matchFilter(t *testing.T) {
+
+	type object struct {
+		ID      string
+		Name    string
+		Tags    []string
+		Count   int
+		Enabled bool
+		Date    time.Time
+		Empty   string
+	}
+
+	now := time.Now()
+
+	obj := &object{
+		ID:      "1",
+		Name:    "hello",
+		Tags:    []string{"a=b", "c=d"},
+		Count:   42,
+		Enabled: true,
+		Date:    now,
+	}
+
+	attributes := map[string]string{"tag": "Tags"}
+
+	tests := []struct {
+		name    string
+		filter  *elemental.Filter
+		want    bool
+		wantErr bool
+	}{
+		{
+			"equal",
+			elemental.NewFilterComposer().WithKey("name").Equals("hello").Done(),
+			true,
+			false,
+		},
+		{
+			"equal no match",
+			elemental.NewFilterComposer().WithKey("name").Equals("world").Done(),
+			false,
+			false,
+		},
+		{
+			"equal on slice",
+			elemental.NewFilterComposer().WithKey("tags").Equals("c=d").Done(),
+			true,
+			false,
+		},
+		{
+			"equal using index name",
+			elemental.NewFilterComposer().WithKey("tag").Equals("a=b").Done(),
+			true,
+			false,
+		},
+		{
+			"equal bool",
+			elemental.NewFilterComposer().WithKey("enabled").Equals(true).Done(),
+			true,
+			false,
+		},
+		{
+			"not equal",
+			elemental.NewFilterComposer().WithKey("name").NotEquals("hello").Done(),
+			false,
+			false,
+		},
+		{
+			"in",
+			elemental.NewFilterComposer().WithKey("name").In("world", "hello").Done(),
+			true,
+			false,
+		},
+		{
+			"not in",
+			elemental.NewFilterComposer().WithKey("name").NotIn("world", "hello").Done(),
+			false,
+			false,
+		},
+		{
+			"contains",
+			elemental.NewFilterComposer().WithKey("tags").Contains("x=y", "a=b").Done(),
+			true,
+			false,
+		},
+		{
+			"contains none",
+			elemental.NewFilterComposer().WithKey("tags").Contains("x=y", "z=w").Done(),
+			false,
+			false,
+		},
+		{
+			"contains all",
+			elemental.NewFilterComposer().WithKey("tags").Contains("a=b").WithKey("tags").Contains("c=d").Done(),
+			true,
+			false,
+		},
+		{
+			"contains all with one missing",
+			elemental.NewFilterComposer().WithKey("tags").Contains("a=b").WithKey("tags").Contains("x=y").Done(),
+			false,
+			false,
+		},
+		{
+			"not contains",
+			elemental.NewFilterComposer().WithKey("tags").NotContains("x=y").Done(),
+			true,
+			false,
+		},
+		{
+			"greater",
+			elemental.NewFilterComposer().WithKey("count").GreaterThan(41).Done(),
+			true,
+			false,
+		},
+		{
+			"greater or equal",
+			elemental.NewFilterComposer().WithKey("count").GreaterOrEqualThan(42.0).Done(),
+			true,
+			false,
+		},
+		{
+			"lesser",
+			elemental.NewFilterComposer().WithKey("count").LesserThan(42).Done(),
+			false,
+			false,
+		},
+		{
+			"lesser or equal",
+			elemental.NewFilterComposer().WithKey("count").LesserOrEqualThan(42).Done(),
+			true,
+			false,
+		},
+		{
+			"lesser with incomparable value",
+			elemental.NewFilterComposer().WithKey("count").LesserThan("a").Done(),
+			false,
+			false,
+		},
+		{
+			"time range",
+			elemental.NewFilterComposer().
+				WithKey("date").GreaterThan(now.Add(-time.Minute)).
+				WithKey("date").LesserThan(now.Add(time.Minute)).
+				Done(),
+			true,
+			false,
+		},
+		{
+			"time with duration",
+			elemental.NewFilterComposer().WithKey("date").LesserThan(time.Hour).Done(),
+			true,
+			false,
+		},
+		{
+			"exists",
+			elemental.NewFilterComposer().WithKey("name").Exists().Done(),
+			true,
+			false,
+		},
+		{
+			"not exists",
+			elemental.NewFilterComposer().WithKey("empty").NotExists().Done(),
+			true,
+			false,
+		},
+		{
+			"matches",
+			elemental.NewFilterComposer().WithKey("name").Matches("^hel+o$").Done(),
+			true,
+			false,
+		},
+		{
+			"matches with anchor",
+			elemental.NewFilterComposer().WithKey("name").Matches("^hell$").Done(),
+			false,
+			false,
+		},
+		{
+			"matches with several patterns",
+			elemental.NewFilterComposer().WithKey("name").Matches("^nope", "^hel+o$").Done(),
+			true,
+			false,
+		},
+		{
+			"matches with several non matching patterns",
+			elemental.NewFilterComposer().WithKey("name").Matches("^nope", "^hell$").Done(),
+			false,
+			false,
+		},
+		{
+			"matches on slice",
+			elemental.NewFilterComposer().WithKey("tags").Matches("^c=").Done(),
+			true,
+			false,
+		},
+		{
+			"matches with invalid regexp",
+			elemental.NewFilterComposer().WithKey("name").Matches("(").Done(),
+			false,
+			true,
+		},
+		{
+			"and",
+			elemental.NewFilterComposer().And(
+				elemental.NewFilterComposer().WithKey("name").Equals("hello").Done(),
+				elemental.NewFilterComposer().WithKey("count").Equals(43).Done(),
+			).Done(),
+			false,
+			false,
+		},
+		{
+			"or",
+			elemental.NewFilterComposer().Or(
+				elemental.NewFilterComposer().WithKey("name").Equals("world").Done(),
+				elemental.NewFilterComposer().WithKey("count").Equals(42).Done(),
+			).Done(),
+			true,
+			false,
+		},
+		{
+			"nested and in or",
+			elemental.NewFilterComposer().Or(
+				elemental.NewFilterComposer().WithKey("name").Equals("world").Done(),
+				elemental.NewFilterComposer().And(
+					elemental.NewFilterComposer().WithKey("tags").Contains("a=b").Done(),
+					elemental.NewFilterComposer().Or(
+						elemental.NewFilterComposer().WithKey("count").Equals(1).Done(),
+						elemental.NewFilterComposer().WithKey("enabled").Equals(true).Done(),
+					).Done(),
+				).Done(),
+			).Done(),
+			true,
+			false,
+		},
+		{
+			"unknown key",
+			elemental.NewFilterComposer().WithKey("nope").Equals("hello").Done(),
+			false,
+			true,
+		},
+		{
+			"unknown key in or",
+			elemental.NewFilterComposer().Or(
+				elemental.NewFilterComposer().WithKey("nope").Equals("hello").Done(),
+			).Done(),
+			false,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchFilter(obj, tt.filter, attributes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("matchFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("matchFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_planIndexLookup(t *testing.T) {
+
+	indexes := map[string]*memdb.IndexSchema{
+		"name": {Name: "name", Indexer: &memdb.StringFieldIndex{Field: "Name"}},
+		"kind": {Name: "kind", Indexer: &stringBasedFieldIndex{Field: "Kind"}},
+	}
+
+	tests := []struct {
+		name       string
+		filter     *elemental.Filter
+		wantIndex  string
+		wantValues []interface{}
+	}{
+		{
+			"nil filter",
+			nil,
+			"",
+			nil,
+		},
+		{
+			"equal on indexed key",
+			elemental.NewFilterComposer().WithKey("Name").Equals("a").Done(),
+			"name",
+			[]interface{}{"a"},
+		},
+		{
+			"equal on non indexed key",
+			elemental.NewFilterComposer().WithKey("description").Equals("a").Done(),
+			"",
+			nil,
+		},
+		{
+			"in on indexed key",
+			elemental.NewFilterComposer().WithKey("name").In("a", "b").Done(),
+			"name",
+			[]interface{}{"a", "b"},
+		},
+		{
+			"contains on indexed key",
+			elemental.NewFilterComposer().WithKey("name").Contains("a", "b").Done(),
+			"name",
+			[]interface{}{"a", "b"},
+		},
+		{
+			"first servable condition",
+			elemental.NewFilterComposer().WithKey("count").GreaterThan(1).WithKey("name").Equals("a").Done(),
+			"name",
+			[]interface{}{"a"},
+		},
+		{
+			"matches with prefixes",
+			elemental.NewFilterComposer().WithKey("name").Matches("^a", "^b$").Done(),
+			"name_prefix",
+			[]interface{}{"a", "b"},
+		},
+		{
+			"matches with a regular expression",
+			elemental.NewFilterComposer().WithKey("name").Matches("^a.*b").Done(),
+			"",
+			nil,
+		},
+		{
+			"matches without anchor",
+			elemental.NewFilterComposer().WithKey("name").Matches("a").Done(),
+			"",
+			nil,
+		},
+		{
+			"matches on an index without prefix support",
+			elemental.NewFilterComposer().WithKey("kind").Matches("^a").Done(),
+			"",
+			nil,
+		},
+		{
+			"not equal on indexed key",
+			elemental.NewFilterComposer().WithKey("name").NotEquals("a").Done(),
+			"",
+			nil,
+		},
+		{
+			"nested and",
+			elemental.NewFilterComposer().And(
+				elemental.NewFilterComposer().WithKey("count").Equals(1).Done(),
+				elemental.NewFilterComposer().WithKey("name").Equals("a").Done(),
+			).Done(),
+			"name",
+			[]interface{}{"a"},
+		},
+		{
+			"or",
+			elemental.NewFilterComposer().Or(
+				elemental.NewFilterComposer().WithKey("name").Equals("a").Done(),
+				elemental.NewFilterComposer().WithKey("name").Equals("b").Done(),
+			).Done(),
+			"",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planIndexLookup(tt.filter, indexes)
+			if got == nil {
+				if tt.wantIndex != "" {
+					t.Errorf("planIndexLookup() = nil, want %s %v", tt.wantIndex, tt.wantValues)
+				}
+				return
+			}
+			if got.index != tt.wantIndex || !reflect.DeepEqual(got.values, tt.wantValues) {
+				t.Errorf("planIndexLookup() = %s %v, want %s %v", got.index, got.values, tt.wantIndex, tt.wantValues)
+			}
+		})
+	}
+}
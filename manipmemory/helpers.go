@@ -0,0 +1,152 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipmemory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/mitchellh/copystructure"
+	"go.aporeto.io/elemental"
+	"go.aporeto.io/manipulate"
+)
+
+// Clone returns a new memory manipulator holding the same data as the given one.
+// The two manipulators are then fully independent: writes done on one of them
+// are not visible from the other. Cloning is cheap, as it only takes a snapshot
+// of the underlying immutable database, which makes it convenient to reset a
+// base dataset between test cases. Pending transactions and subscribers are
+// not cloned.
+//
+// If the given manipulator has been created with OptionNoCopy, the stored
+// objects are shared between the clones, and must not be modified.
+func Clone(manipulator manipulate.Manipulator) manipulate.TransactionalManipulator {
+
+	m, ok := manipulator.(*memdbManipulator)
+	if !ok {
+		panic("you can only pass a memory manipulator to Clone")
+	}
+
+	return &memdbManipulator{
+		schema:          m.schema,
+		db:              m.getDB().Snapshot(),
+		noCopy:          m.noCopy,
+		attributes:      m.attributes,
+		metricsRecorder: m.metricsRecorder,
+		idGenerator:     m.idGenerator,
+		txnTimeout:      m.txnTimeout,
+		txnRegistry:     txnRegistry{},
+		subscriptions:   map[*subscription]struct{}{},
+		pendingEvents:   map[manipulate.TransactionID][]*elemental.Event{},
+	}
+}
+
+// StartReaper starts a goroutine that deletes the objects created
+// with ContextOptionTTL once they expired. It checks for expired objects
+// at the given interval, until the given context is canceled.
+// A delete event is published for every deleted object.
+//
+// If the manipulator has been created with OptionTransactionTimeout, the
+// reaper also aborts the transactions started for longer than the timeout.
+// If the given interval is not positive, StartReaper will panic.
+func StartReaper(ctx context.Context, manipulator manipulate.Manipulator, interval time.Duration) {
+
+	m, ok := manipulator.(*memdbManipulator)
+	if !ok {
+		panic("you can only pass a memory manipulator to StartReaper")
+	}
+
+	if interval <= 0 {
+		panic("interval must be positive")
+	}
+
+	go func() {
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				// Stale transactions are aborted first, as they
+				// hold the writer lock needed to delete objects.
+				_ = m.reapTransactions(now)
+				_, _ = m.reap(now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Touch sets the UpdateTime field of the object of the given identity with
+// the given ID to the current time, without modifying any other field.
+// The field must be a time.Time. It returns a manipulate.ErrObjectNotFound
+// if the object does not exist.
+func Touch(manipulator manipulate.Manipulator, mctx manipulate.Context, identity elemental.Identity, id string) error {
+
+	m, ok := manipulator.(*memdbManipulator)
+	if !ok {
+		panic("you can only pass a memory manipulator to Touch")
+	}
+
+	if mctx == nil {
+		mctx = manipulate.NewContext(context.Background())
+	}
+
+	tid := mctx.TransactionID()
+	txn, release, err := m.txnForID(tid)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if tid == "" {
+		defer txn.Abort()
+	}
+
+	raw, err := txn.First(identity.Category, "id", id)
+	if err != nil {
+		return manipulate.ErrCannotExecuteQuery{Err: err}
+	}
+
+	if raw == nil {
+		return manipulate.ErrObjectNotFound{Err: fmt.Errorf("cannot find the object for the given ID")}
+	}
+
+	// Stored objects must never be modified in place.
+	cp, err := copystructure.Copy(raw)
+	if err != nil {
+		return manipulate.ErrCannotExecuteQuery{Err: err}
+	}
+
+	f := fieldByName(reflect.Indirect(reflect.ValueOf(cp)), "UpdateTime")
+	if !f.IsValid() || !f.CanSet() || f.Type() != reflect.TypeOf(time.Time{}) {
+		return manipulate.ErrCannotExecuteQuery{Err: fmt.Errorf("%s has no UpdateTime field of type time.Time", identity.Name)}
+	}
+
+	f.Set(reflect.ValueOf(time.Now()))
+
+	if err := txn.Insert(identity.Category, cp); err != nil {
+		return manipulate.ErrCannotExecuteQuery{Err: err}
+	}
+
+	if tid == "" {
+		txn.Commit()
+	}
+
+	m.publishEvent(tid, elemental.EventUpdate, cp.(elemental.Identifiable))
+
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+	"go.aporeto.io/manipulate"
+	"go.aporeto.io/manipulate/maniptest"
+)
+
+func TestClone(t *testing.T) {
+
+	Convey("Given I have a non memory manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call Clone", func() {
+			Convey("Then it should panic", func() {
+				So(func() { Clone(m) }, ShouldPanicWith, "you can only pass a memory manipulator to Clone")
+			})
+		})
+	})
+
+	Convey("Given I have a memory manipulator with some data", t, func() {
+
+		recorder := manipulate.NewNoopMetricsRecorder()
+		base, err := New(datastoreIndexConfig(), OptionMetricsRecorder(recorder))
+		So(err, ShouldBeNil)
+
+		l1 := &testmodel.List{Name: "l1"}
+		So(base.Create(nil, l1), ShouldBeNil)
+
+		Convey("When I clone it", func() {
+
+			clone := Clone(base)
+
+			Convey("Then the clone should contain the data", func() {
+				lst := testmodel.ListsList{}
+				So(clone.RetrieveMany(nil, &lst), ShouldBeNil)
+				So(lst, ShouldResemble, testmodel.ListsList{l1})
+			})
+
+			Convey("Then the clone should keep the metrics recorder", func() {
+				So(clone.(*memdbManipulator).metricsRecorder, ShouldEqual, recorder)
+			})
+
+			Convey("When I modify the clone", func() {
+
+				l1.Name = "modified"
+				So(clone.Update(nil, l1), ShouldBeNil)
+				So(clone.Create(nil, &testmodel.List{Name: "l2"}), ShouldBeNil)
+
+				Convey("Then the base should not be modified", func() {
+
+					lst := testmodel.ListsList{}
+					So(base.RetrieveMany(nil, &lst), ShouldBeNil)
+					So(len(lst), ShouldEqual, 1)
+					So(lst[0].Name, ShouldEqual, "l1")
+
+					n, err := clone.Count(nil, testmodel.ListIdentity)
+					So(err, ShouldBeNil)
+					So(n, ShouldEqual, 2)
+				})
+			})
+
+			Convey("When I modify the base", func() {
+
+				So(base.Delete(nil, l1), ShouldBeNil)
+
+				Convey("Then the clone should not be modified", func() {
+					n, err := clone.Count(nil, testmodel.ListIdentity)
+					So(err, ShouldBeNil)
+					So(n, ShouldEqual, 1)
+				})
+			})
+		})
+	})
+}
+
+func TestStartReaper(t *testing.T) {
+
+	Convey("Given I have a non memory manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call StartReaper", func() {
+			Convey("Then it should panic", func() {
+				So(func() { StartReaper(context.Background(), m, time.Second) }, ShouldPanicWith, "you can only pass a memory manipulator to StartReaper")
+			})
+		})
+	})
+
+	Convey("Given I have a memory manipulator", t, func() {
+
+		m, err := New(datastoreIndexConfig())
+		So(err, ShouldBeNil)
+
+		Convey("When I call StartReaper with a zero interval", func() {
+			Convey("Then it should panic", func() {
+				So(func() { StartReaper(context.Background(), m, 0) }, ShouldPanicWith, "interval must be positive")
+			})
+		})
+	})
+}
+
+var touchableIdentity = elemental.MakeIdentity("touchable", "touchables")
+
+type touchableObject struct {
+	ID         string
+	Name       string
+	UpdateTime time.Time
+}
+
+func (o *touchableObject) Identity() elemental.Identity { return touchableIdentity }
+func (o *touchableObject) Identifier() string           { return o.ID }
+func (o *touchableObject) SetIdentifier(id string)      { o.ID = id }
+func (o *touchableObject) Version() int                 { return 1 }
+
+func TestTouch(t *testing.T) {
+
+	Convey("Given I have a non memory manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call Touch", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = Touch(m, nil, testmodel.ListIdentity, "x") }, ShouldPanicWith, "you can only pass a memory manipulator to Touch")
+			})
+		})
+	})
+
+	Convey("Given I have a memory manipulator with a touchable object", t, func() {
+
+		identity := touchableIdentity
+		schema := datastoreIndexConfig()
+		schema[identity.Category] = &IdentitySchema{
+			Identity: identity,
+			Indexes: []*Index{
+				{
+					Name:      "id",
+					Type:      IndexTypeString,
+					Unique:    true,
+					Attribute: "ID",
+				},
+			},
+		}
+
+		m, err := New(schema)
+		So(err, ShouldBeNil)
+
+		before := time.Now().Add(-time.Hour)
+		o := &touchableObject{Name: "a", UpdateTime: before}
+		So(m.Create(nil, o), ShouldBeNil)
+
+		Convey("When I touch it", func() {
+
+			err := Touch(m, nil, identity, o.ID)
+
+			Convey("Then only its update time should have changed", func() {
+				So(err, ShouldBeNil)
+
+				o2 := &touchableObject{ID: o.ID}
+				So(m.Retrieve(nil, o2), ShouldBeNil)
+				So(o2.Name, ShouldEqual, "a")
+				So(o2.UpdateTime, ShouldHappenAfter, before)
+				So(o.UpdateTime, ShouldEqual, before)
+			})
+		})
+
+		Convey("When I touch an object that does not exist", func() {
+
+			err := Touch(m, nil, identity, "nope")
+
+			Convey("Then err should be an ErrObjectNotFound", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrObjectNotFound{})
+			})
+		})
+
+		Convey("When I touch an object without update time", func() {
+
+			l := &testmodel.List{Name: "l"}
+			So(m.Create(nil, l), ShouldBeNil)
+
+			err := Touch(m, nil, testmodel.ListIdentity, l.ID)
+
+			Convey("Then err should be an ErrCannotExecuteQuery", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotExecuteQuery{})
+			})
+		})
+	})
+}
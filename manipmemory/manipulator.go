@@ -17,17 +17,56 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/globalsign/mgo/bson"
 	memdb "github.com/hashicorp/go-memdb"
 	"github.com/mitchellh/copystructure"
 	"go.aporeto.io/elemental"
 	"go.aporeto.io/manipulate"
+	"go.uber.org/zap"
 )
 
-type txnRegistry map[manipulate.TransactionID]*memdb.Txn
+// A registeredTxn is a transaction opened using a TransactionID.
+// Its lock must be held while using the memdb transaction, as it
+// can be aborted by the reaper. Once reaped, the transaction is kept
+// in the registry with its abort date, so it cannot be used anymore.
+type registeredTxn struct {
+	lock      sync.Mutex
+	txn       *memdb.Txn
+	startedAt time.Time
+	reapedAt  time.Time
+}
+
+type txnRegistry map[manipulate.TransactionID]*registeredTxn
+
+// expirationsTable is the internal table holding
+// the expiration dates set with ContextOptionTTL.
+const expirationsTable = "manipmemory.expirations"
+
+// contextCheckInterval is the number of stored objects
+// scanned between two checks of the context.
+const contextCheckInterval = 1000
+
+type expiration struct {
+	Key      string
+	Table    string
+	ID       string
+	ExpireAt time.Time
+}
+
+func expirationKey(table string, id string) string {
+	return table + "/" + id
+}
 
 // A memoryManipulator is an empty manipulator that can be used with ApoMock.
+//
+// A memdbManipulator can be shared across goroutines. Reads are
+// served from an immutable snapshot of the database taken at the
+// beginning of the call. Writes are serialized by memdb: a write without
+// a TransactionID is committed immediately, and a write using a
+// TransactionID holds the single writer lock until the transaction is
+// committed or aborted, blocking all other writers in the meantime.
+// A given TransactionID must not be used concurrently by several goroutines.
 type memdbManipulator struct {
 	db              *memdb.MemDB
 	schema          *memdb.DBSchema
@@ -35,6 +74,14 @@ type memdbManipulator struct {
 	txnRegistryLock sync.RWMutex
 	dbLock          sync.RWMutex
 	noCopy          bool
+	attributes      map[string]map[string]string
+	metricsRecorder manipulate.MetricsRecorder
+	idGenerator     func() string
+	txnTimeout      time.Duration
+
+	subscriptions     map[*subscription]struct{}
+	pendingEvents     map[manipulate.TransactionID][]*elemental.Event
+	subscriptionsLock sync.RWMutex
 }
 
 // New creates a new datastore backed by a memdb.
@@ -49,12 +96,30 @@ func New(c map[string]*IdentitySchema, options ...Option) (manipulate.Transactio
 		Tables: map[string]*memdb.TableSchema{},
 	}
 
+	attributes := map[string]map[string]string{}
+
 	for table, cfg := range c {
 		index, err := createSchema(cfg)
 		if err != nil {
 			return nil, err
 		}
 		schema.Tables[table] = index
+
+		attributes[table] = map[string]string{}
+		for _, idx := range cfg.Indexes {
+			attributes[table][strings.ToLower(idx.Name)] = idx.Attribute
+		}
+	}
+
+	schema.Tables[expirationsTable] = &memdb.TableSchema{
+		Name: expirationsTable,
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": {
+				Name:    "id",
+				Unique:  true,
+				Indexer: &memdb.StringFieldIndex{Field: "Key"},
+			},
+		},
 	}
 
 	db, err := memdb.NewMemDB(schema)
@@ -63,10 +128,17 @@ func New(c map[string]*IdentitySchema, options ...Option) (manipulate.Transactio
 	}
 
 	return &memdbManipulator{
-		schema:      schema,
-		db:          db,
-		noCopy:      cfg.noCopy,
-		txnRegistry: txnRegistry{},
+		schema:          schema,
+		db:              db,
+		noCopy:          cfg.noCopy,
+		txnRegistry:     txnRegistry{},
+		attributes:      attributes,
+		metricsRecorder: cfg.metricsRecorder,
+		idGenerator:     cfg.idGenerator,
+		txnTimeout:      cfg.txnTimeout,
+
+		subscriptions: map[*subscription]struct{}{},
+		pendingEvents: map[manipulate.TransactionID][]*elemental.Event{},
 	}, nil
 }
 
@@ -83,22 +155,60 @@ func (m *memdbManipulator) Flush(ctx context.Context) error {
 	return nil
 }
 
+// Ping is part of the implementation of the PingableManipulator interface.
+// The memory backend is always reachable, so it always returns nil.
+func (m *memdbManipulator) Ping(ctx context.Context) error {
+	return nil
+}
+
 // RetrieveMany is part of the implementation of the Manipulator interface.
-func (m *memdbManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.Identifiables) error {
+func (m *memdbManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.Identifiables) (err error) {
+
+	defer m.recordOperation(elemental.OperationRetrieveMany, dest.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		mctx = manipulate.NewContext(context.Background())
 	}
 
+	if err := manipulate.ValidatePagination(mctx); err != nil {
+		return err
+	}
+
+	if err := mctx.Context().Err(); err != nil {
+		return manipulate.ErrCannotExecuteQuery{Err: err}
+	}
+
+	if err := checkGeoWithin(mctx); err != nil {
+		return err
+	}
+
 	items := map[string]elemental.Identifiable{}
 
-	if err := m.retrieveFromFilter(dest.Identity().Category, mctx.Filter(), &items, true); err != nil {
+	if err := m.retrieveFromFilter(mctx.Context(), m.getDB().Txn(false), dest.Identity().Category, mctx.Filter(), &items); err != nil {
 		return err
 	}
 
+	objects := make([]elemental.Identifiable, 0, len(items))
+	for _, obj := range items {
+		objects = append(objects, obj)
+	}
+
+	order := mctx.Order()
+	paginated := mctx.PageSize() > 0 || mctx.After() != "" || mctx.Limit() > 0
+
+	if len(order) > 0 || paginated {
+		sortIdentifiables(objects, order)
+	}
+
+	if paginated {
+		var next string
+		objects, next = paginate(objects, mctx, len(order) == 0)
+		mctx.SetNext(next)
+	}
+
 	out := reflect.ValueOf(dest).Elem()
 
-	for _, obj := range items {
+	for _, obj := range objects {
 		out.Set(reflect.Append(out, reflect.ValueOf(obj)))
 	}
 
@@ -106,7 +216,9 @@ func (m *memdbManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.
 }
 
 // Retrieve is part of the implementation of the Manipulator interface.
-func (m *memdbManipulator) Retrieve(mctx manipulate.Context, object elemental.Identifiable) error {
+func (m *memdbManipulator) Retrieve(mctx manipulate.Context, object elemental.Identifiable) (err error) {
+
+	defer m.recordOperation(elemental.OperationRetrieve, object.Identity(), time.Now(), &err)
 
 	txn := m.getDB().Txn(false)
 
@@ -135,20 +247,29 @@ func (m *memdbManipulator) Retrieve(mctx manipulate.Context, object elemental.Id
 }
 
 // Create is part of the implementation of the Manipulator interface.
-func (m *memdbManipulator) Create(mctx manipulate.Context, object elemental.Identifiable) error {
+func (m *memdbManipulator) Create(mctx manipulate.Context, object elemental.Identifiable) (err error) {
+
+	defer m.recordOperation(elemental.OperationCreate, object.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		mctx = manipulate.NewContext(context.Background())
 	}
 
 	tid := mctx.TransactionID()
-	txn := m.txnForID(tid)
-	defer txn.Abort()
+	txn, release, err := m.txnForID(tid)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if tid == "" {
+		defer txn.Abort()
+	}
 
 	// In caching scenarios the identifier is already set. Do not insert
 	// here. We will get it pre-populated from the master DB.
 	if object.Identifier() == "" {
-		object.SetIdentifier(bson.NewObjectId().Hex())
+		object.SetIdentifier(m.idGenerator())
 	}
 
 	var cp interface{}
@@ -166,23 +287,45 @@ func (m *memdbManipulator) Create(mctx manipulate.Context, object elemental.Iden
 		return manipulate.ErrCannotExecuteQuery{Err: err}
 	}
 
+	if ttl, ok := mctx.(opaquer).Opaque()[opaqueKeyTTL].(time.Duration); ok {
+		if err := txn.Insert(expirationsTable, &expiration{
+			Key:      expirationKey(object.Identity().Category, object.Identifier()),
+			Table:    object.Identity().Category,
+			ID:       object.Identifier(),
+			ExpireAt: time.Now().Add(ttl),
+		}); err != nil {
+			return manipulate.ErrCannotExecuteQuery{Err: err}
+		}
+	}
+
 	if tid == "" {
 		txn.Commit()
 	}
 
+	m.publishEvent(tid, elemental.EventCreate, object)
+
 	return nil
 }
 
 // Update is part of the implementation of the Manipulator interface.
-func (m *memdbManipulator) Update(mctx manipulate.Context, object elemental.Identifiable) error {
+func (m *memdbManipulator) Update(mctx manipulate.Context, object elemental.Identifiable) (err error) {
+
+	defer m.recordOperation(elemental.OperationUpdate, object.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		mctx = manipulate.NewContext(context.Background())
 	}
 
 	tid := mctx.TransactionID()
-	txn := m.txnForID(tid)
-	defer txn.Abort()
+	txn, release, err := m.txnForID(tid)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if tid == "" {
+		defer txn.Abort()
+	}
 
 	o, err := txn.Get(object.Identity().Category, "id", object.Identifier())
 	if err != nil || o.Next() == nil {
@@ -207,19 +350,30 @@ func (m *memdbManipulator) Update(mctx manipulate.Context, object elemental.Iden
 		txn.Commit()
 	}
 
+	m.publishEvent(tid, elemental.EventUpdate, object)
+
 	return nil
 }
 
 // Delete is part of the implementation of the Manipulator interface.
-func (m *memdbManipulator) Delete(mctx manipulate.Context, object elemental.Identifiable) error {
+func (m *memdbManipulator) Delete(mctx manipulate.Context, object elemental.Identifiable) (err error) {
+
+	defer m.recordOperation(elemental.OperationDelete, object.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		mctx = manipulate.NewContext(context.Background())
 	}
 
 	tid := mctx.TransactionID()
-	txn := m.txnForID(tid)
-	defer txn.Abort()
+	txn, release, err := m.txnForID(tid)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if tid == "" {
+		defer txn.Abort()
+	}
 
 	if err := txn.Delete(object.Identity().Category, object); err != nil {
 		if err == memdb.ErrNotFound {
@@ -228,10 +382,16 @@ func (m *memdbManipulator) Delete(mctx manipulate.Context, object elemental.Iden
 		return manipulate.ErrCannotExecuteQuery{Err: err}
 	}
 
+	if _, err := txn.DeleteAll(expirationsTable, "id", expirationKey(object.Identity().Category, object.Identifier())); err != nil {
+		return manipulate.ErrCannotExecuteQuery{Err: err}
+	}
+
 	if tid == "" {
 		txn.Commit()
 	}
 
+	m.publishEvent(tid, elemental.EventDelete, object)
+
 	return nil
 }
 
@@ -240,29 +400,80 @@ func (m *memdbManipulator) DeleteMany(mctx manipulate.Context, identity elementa
 	return manipulate.ErrNotImplemented{Err: fmt.Errorf("DeleteMany not implemented in manipmemory")}
 }
 
-// Count is part of the implementation of the Manipulator interface. Count is very expensive.
-func (m *memdbManipulator) Count(mctx manipulate.Context, identity elemental.Identity) (int, error) {
+// Count is part of the implementation of the Manipulator interface.
+// It evaluates the filter against every stored object of the identity,
+// but does not copy them.
+func (m *memdbManipulator) Count(mctx manipulate.Context, identity elemental.Identity) (_ int, err error) {
 
-	items := map[string]elemental.Identifiable{}
+	defer m.recordOperation(elemental.OperationInfo, identity, time.Now(), &err)
+
+	if mctx == nil {
+		mctx = manipulate.NewContext(context.Background())
+	}
+
+	if err := mctx.Context().Err(); err != nil {
+		return 0, manipulate.ErrCannotExecuteQuery{Err: err}
+	}
+
+	if err := checkGeoWithin(mctx); err != nil {
+		return 0, err
+	}
 
-	if err := m.retrieveFromFilter(identity.Category, mctx.Filter(), &items, true); err != nil {
+	var count int
+
+	if err := m.forEachMatch(mctx.Context(), m.getDB().Txn(false), identity.Category, mctx.Filter(), func(interface{}) error {
+		count++
+		return nil
+	}); err != nil {
 		return 0, err
 	}
 
-	return len(items), nil
+	return count, nil
+}
+
+// checkGeoWithin returns an error if the given context
+// has been set with manipmongo.ContextOptionGeoWithin.
+func checkGeoWithin(mctx manipulate.Context) error {
+
+	o, ok := mctx.(opaquer)
+	if !ok {
+		return nil
+	}
+
+	if _, ok := o.Opaque()[opaqueKeyMongoGeoWithin]; ok {
+		return manipulate.ErrNotImplemented{Err: fmt.Errorf("geo queries are not implemented in manipmemory")}
+	}
+
+	return nil
 }
 
 // Commit is part of the implementation of the TransactionalManipulator interface.
 func (m *memdbManipulator) Commit(id manipulate.TransactionID) error {
 
-	txn := m.registeredTxnWithID(id)
+	r := m.registeredTxnForID(id)
+	if r == nil {
+		return manipulate.ErrCannotCommit{Err: fmt.Errorf("Cannot find transaction: %s ", id)}
+	}
 
-	if txn == nil {
+	r.lock.Lock()
+
+	if !r.reapedAt.IsZero() {
+		m.unregisterTxn(id)
+		r.lock.Unlock()
+		return manipulate.ErrCannotCommit{Err: fmt.Errorf("Transaction %s has been aborted after timing out", id)}
+	}
+
+	if r.txn == nil {
+		r.lock.Unlock()
 		return manipulate.ErrCannotCommit{Err: fmt.Errorf("Cannot find transaction: %s ", id)}
 	}
 
-	txn.Commit()
+	r.txn.Commit()
+	r.txn = nil
 	m.unregisterTxn(id)
+	r.lock.Unlock()
+
+	m.flushEvents(id)
 
 	return nil
 }
@@ -270,38 +481,78 @@ func (m *memdbManipulator) Commit(id manipulate.TransactionID) error {
 // Abort is part of the implementation of the TransactionalManipulator interface.
 func (m *memdbManipulator) Abort(id manipulate.TransactionID) bool {
 
-	txn := m.registeredTxnWithID(id)
-	if txn == nil {
+	r := m.registeredTxnForID(id)
+	if r == nil {
 		return false
 	}
 
-	txn.Abort()
+	r.lock.Lock()
+	aborted := r.txn != nil
+	if aborted {
+		r.txn.Abort()
+		r.txn = nil
+	}
 	m.unregisterTxn(id)
+	r.lock.Unlock()
+
+	m.subscriptionsLock.Lock()
+	delete(m.pendingEvents, id)
+	m.subscriptionsLock.Unlock()
 
-	return true
+	return aborted
 }
 
-func (m *memdbManipulator) txnForID(id manipulate.TransactionID) *memdb.Txn {
+// txnForID returns the write transaction to use for the given
+// TransactionID, and a function that must be called once done with it.
+// If the ID is empty, a new transaction is returned, which the caller
+// must commit or abort. It returns an error if the transaction has been
+// aborted by the reaper.
+func (m *memdbManipulator) txnForID(id manipulate.TransactionID) (*memdb.Txn, func(), error) {
 
 	if id == "" {
-		return m.getDB().Txn(true)
+		return m.getDB().Txn(true), func() {}, nil
+	}
+
+	m.txnRegistryLock.Lock()
+	r, ok := m.txnRegistry[id]
+	if !ok {
+		r = &registeredTxn{}
+		m.txnRegistry[id] = r
 	}
+	m.txnRegistryLock.Unlock()
+
+	r.lock.Lock()
 
-	txn := m.registeredTxnWithID(id)
+	if r.txn == nil && r.reapedAt.IsZero() {
 
-	if txn == nil {
-		txn = m.getDB().Txn(true)
-		m.registerTxn(id, txn)
+		// Acquiring the writer lock may take a while, so we must not
+		// hold the lock of the registered transaction meanwhile: the
+		// reaper may need it to abort the transaction holding the writer lock.
+		r.lock.Unlock()
+		txn := m.getDB().Txn(true)
+		r.lock.Lock()
+
+		if r.txn == nil && r.reapedAt.IsZero() {
+			r.txn = txn
+			r.startedAt = time.Now()
+		} else {
+			txn.Abort()
+		}
 	}
 
-	return txn
+	if !r.reapedAt.IsZero() {
+		r.lock.Unlock()
+		return nil, nil, manipulate.ErrCannotExecuteQuery{Err: fmt.Errorf("transaction %s has been aborted after timing out", id)}
+	}
+
+	return r.txn, r.lock.Unlock, nil
 }
 
 func (m *memdbManipulator) registerTxn(id manipulate.TransactionID, txn *memdb.Txn) {
 
 	m.txnRegistryLock.Lock()
 	defer m.txnRegistryLock.Unlock()
-	m.txnRegistry[id] = txn
+	m.txnRegistry[id] = &registeredTxn{txn: txn, startedAt: time.Now()}
 }
 
 func (m *memdbManipulator) unregisterTxn(id manipulate.TransactionID) {
@@ -311,147 +562,213 @@ func (m *memdbManipulator) unregisterTxn(id manipulate.TransactionID) {
 	delete(m.txnRegistry, id)
 }
 
-func (m *memdbManipulator) registeredTxnWithID(id manipulate.TransactionID) *memdb.Txn {
+func (m *memdbManipulator) registeredTxnForID(id manipulate.TransactionID) *registeredTxn {
 
 	m.txnRegistryLock.RLock()
 	defer m.txnRegistryLock.RUnlock()
-	b := m.txnRegistry[id]
 
-	return b
+	return m.txnRegistry[id]
 }
 
-// RetrieveFromFilter compiles the given manipulate Filter into a mongo filter.
-func (m *memdbManipulator) retrieveFromFilter(identity string, f *elemental.Filter, items *map[string]elemental.Identifiable, fullQuery bool) error {
-
-	if f == nil {
-		return m.retrieveIntersection(identity, "id", nil, items, fullQuery)
-	}
+func (m *memdbManipulator) registeredTxnWithID(id manipulate.TransactionID) *memdb.Txn {
 
-	if len(f.Operators()) == 0 {
+	r := m.registeredTxnForID(id)
+	if r == nil {
 		return nil
 	}
 
-	for i, operator := range f.Operators() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 
-		switch operator {
+	return r.txn
+}
 
-		case elemental.AndOperator:
+// reapTransactions aborts the transactions that have been started for
+// longer than the transaction timeout at the given time, releasing the
+// writer lock they hold. It returns the number of aborted transactions.
+// The aborted transactions stay registered for another timeout, so their
+// later use fails instead of silently starting a new transaction.
+func (m *memdbManipulator) reapTransactions(now time.Time) int {
 
-			k := strings.ToLower(f.Keys()[i])
+	if m.txnTimeout <= 0 {
+		return 0
+	}
 
-			switch f.Comparators()[i] {
+	m.txnRegistryLock.RLock()
+	registered := make(map[manipulate.TransactionID]*registeredTxn, len(m.txnRegistry))
+	for id, r := range m.txnRegistry {
+		registered[id] = r
+	}
+	m.txnRegistryLock.RUnlock()
 
-			case elemental.EqualComparator:
+	var aborted []manipulate.TransactionID
 
-				if err := m.retrieveIntersection(identity, k, f.Values()[i][0], items, fullQuery); err != nil {
-					return err
-				}
+	for id, r := range registered {
 
-			case elemental.MatchComparator:
+		r.lock.Lock()
 
-				values := f.Values()[i]
+		switch {
+		case r.txn != nil && now.Sub(r.startedAt) >= m.txnTimeout:
+			r.txn.Abort()
+			r.txn = nil
+			r.reapedAt = now
+			aborted = append(aborted, id)
 
-				for _, v := range values {
+		case !r.reapedAt.IsZero() && now.Sub(r.reapedAt) >= m.txnTimeout:
+			m.unregisterTxn(id)
+		}
 
-					if !strings.HasPrefix(v.(string), "^") {
-						return manipulate.ErrCannotExecuteQuery{Err: fmt.Errorf("Matches filter only works for prefix matching and must always start with a '^'")}
-					}
+		r.lock.Unlock()
+	}
 
-					fv := strings.TrimPrefix(v.(string), "^")
-					fv = strings.TrimSuffix(fv, "$")
+	for _, id := range aborted {
 
-					valueItems := map[string]elemental.Identifiable{}
-					if err := m.retrieveIntersection(identity, k+"_prefix", fv, &valueItems, fullQuery); err != nil {
-						return err
-					}
-					mergeIn(items, &valueItems)
-				}
+		m.subscriptionsLock.Lock()
+		delete(m.pendingEvents, id)
+		m.subscriptionsLock.Unlock()
 
-			case elemental.ContainComparator:
+		zap.L().Warn("Aborted stale transaction", zap.String("id", string(id)), zap.Duration("timeout", m.txnTimeout))
+	}
 
-				values := f.Values()[i]
+	return len(aborted)
+}
 
-				containItems := map[string]elemental.Identifiable{}
+func (m *memdbManipulator) registerSubscription(s *subscription) {
 
-				for _, value := range values {
-					valueItems := map[string]elemental.Identifiable{}
-					if err := m.retrieveIntersection(identity, k, value, &valueItems, true); err != nil {
-						return err
-					}
-					mergeIn(&containItems, &valueItems)
-				}
+	m.subscriptionsLock.Lock()
+	m.subscriptions[s] = struct{}{}
+	m.subscriptionsLock.Unlock()
+}
 
-				intersection(items, &containItems, fullQuery)
+func (m *memdbManipulator) unregisterSubscription(s *subscription) {
 
-			default:
-				return manipulate.ErrCannotExecuteQuery{Err: fmt.Errorf("invalid comparator for memdb: %d", f.Comparators()[i])}
-			}
+	m.subscriptionsLock.Lock()
+	delete(m.subscriptions, s)
+	m.subscriptionsLock.Unlock()
+}
 
-		case elemental.AndFilterOperator:
+// reap deletes the objects that expired at the given time,
+// and returns the number of objects deleted.
+func (m *memdbManipulator) reap(now time.Time) (int, error) {
 
-			for _, sub := range f.AndFilters()[i] {
-				if err := m.retrieveFromFilter(identity, sub, items, fullQuery); err != nil {
-					return err
-				}
-				fullQuery = false
-			}
+	// The expired objects are looked up using a read transaction,
+	// so the writer lock is only acquired if there is something to delete.
+	it, err := m.getDB().Txn(false).Get(expirationsTable, "id")
+	if err != nil {
+		return 0, manipulate.ErrCannotExecuteQuery{Err: err}
+	}
 
-		case elemental.OrFilterOperator:
+	var hasExpired bool
+	for raw := it.Next(); raw != nil; raw = it.Next() {
+		if !raw.(*expiration).ExpireAt.After(now) {
+			hasExpired = true
+			break
+		}
+	}
 
-			orItems := map[string]elemental.Identifiable{}
+	if !hasExpired {
+		return 0, nil
+	}
 
-			for _, sub := range f.OrFilters()[i] {
-				valueItems := map[string]elemental.Identifiable{}
+	txn := m.getDB().Txn(true)
+	defer txn.Abort()
 
-				if err := m.retrieveFromFilter(identity, sub, &valueItems, true); err != nil {
-					return err
-				}
+	// The expirations are looked up again, as they
+	// may have changed before we got the writer lock.
+	it, err = txn.Get(expirationsTable, "id")
+	if err != nil {
+		return 0, manipulate.ErrCannotExecuteQuery{Err: err}
+	}
 
-				mergeIn(&orItems, &valueItems)
-			}
+	var expired []*expiration
+	for raw := it.Next(); raw != nil; raw = it.Next() {
+		if e := raw.(*expiration); !e.ExpireAt.After(now) {
+			expired = append(expired, e)
+		}
+	}
+
+	var deleted []elemental.Identifiable
+	for _, e := range expired {
 
-			intersection(items, &orItems, fullQuery)
+		raw, err := txn.First(e.Table, "id", e.ID)
+		if err != nil {
+			return 0, manipulate.ErrCannotExecuteQuery{Err: err}
+		}
 
-		default:
-			return manipulate.ErrCannotExecuteQuery{Err: fmt.Errorf("invalid operator for memdb: %d", operator)}
+		if raw != nil {
+			if err := txn.Delete(e.Table, raw); err != nil {
+				return 0, manipulate.ErrCannotExecuteQuery{Err: err}
+			}
+			deleted = append(deleted, raw.(elemental.Identifiable))
 		}
 
-		fullQuery = false
+		if err := txn.Delete(expirationsTable, e); err != nil {
+			return 0, manipulate.ErrCannotExecuteQuery{Err: err}
+		}
 	}
 
-	return nil
+	txn.Commit()
+
+	for _, object := range deleted {
+		m.publishEvent("", elemental.EventDelete, object)
+	}
+
+	return len(deleted), nil
 }
 
-func (m *memdbManipulator) retrieveIntersection(identity string, k string, value interface{}, items *map[string]elemental.Identifiable, fullquery bool) error {
+// publishEvent sends an event for the given object to all the subscriptions.
+// If the given TransactionID is not empty, the event is kept until the
+// transaction is committed.
+func (m *memdbManipulator) publishEvent(tid manipulate.TransactionID, typ elemental.EventType, object elemental.Identifiable) {
 
-	var iterator memdb.ResultIterator
-	var err error
+	m.subscriptionsLock.Lock()
+	defer m.subscriptionsLock.Unlock()
 
-	existingItems := *items
+	if len(m.subscriptions) == 0 {
+		return
+	}
 
-	txn := m.getDB().Txn(false)
+	evt := elemental.NewEvent(typ, object)
 
-	if value == nil {
-		iterator, err = txn.Get(identity, k)
-	} else {
-		iterator, err = txn.Get(identity, k, value)
+	if tid != "" {
+		m.pendingEvents[tid] = append(m.pendingEvents[tid], evt)
+		return
 	}
-	if err != nil {
-		return manipulate.ErrCannotExecuteQuery{Err: err}
+
+	for s := range m.subscriptions {
+		s.dispatch(evt)
 	}
+}
 
-	combinedItems := map[string]elemental.Identifiable{}
+// flushEvents sends the events kept for the given
+// TransactionID to all the subscriptions.
+func (m *memdbManipulator) flushEvents(tid manipulate.TransactionID) {
 
-	raw := iterator.Next()
+	m.subscriptionsLock.Lock()
+	defer m.subscriptionsLock.Unlock()
 
-	for raw != nil {
+	for _, evt := range m.pendingEvents[tid] {
+		for s := range m.subscriptions {
+			s.dispatch(evt)
+		}
+	}
+
+	delete(m.pendingEvents, tid)
+}
+
+// RetrieveFromFilter compiles the given manipulate Filter into a mongo filter.
+// All the lookups are done using the given txn, so the result is
+// computed from a single consistent snapshot of the database.
+func (m *memdbManipulator) retrieveFromFilter(ctx context.Context, txn *memdb.Txn, identity string, f *elemental.Filter, items *map[string]elemental.Identifiable) error {
+
+	return m.forEachMatch(ctx, txn, identity, f, func(raw interface{}) error {
 
 		var o interface{}
 		if m.noCopy {
 			o = raw
 		} else {
-			o, err = copystructure.Copy(raw)
-			if err != nil {
+			var err error
+			if o, err = copystructure.Copy(raw); err != nil {
 				return manipulate.ErrCannotExecuteQuery{Err: err}
 			}
 		}
@@ -460,17 +777,110 @@ func (m *memdbManipulator) retrieveIntersection(identity string, k string, value
 		if !ok {
 			return manipulate.ErrCannotExecuteQuery{Err: fmt.Errorf("stored object is not an identifiable")}
 		}
-		if _, ok := existingItems[obj.Identifier()]; ok || fullquery {
-			combinedItems[obj.Identifier()] = obj
+
+		(*items)[obj.Identifier()] = obj
+
+		return nil
+	})
+}
+
+// forEachMatch calls the given function with every stored object of the
+// given identity matching the given filter. The objects are passed as stored
+// in the database and must not be modified. The given context is checked
+// every contextCheckInterval objects, and the scan stops if it is done.
+func (m *memdbManipulator) forEachMatch(ctx context.Context, txn *memdb.Txn, identity string, f *elemental.Filter, do func(raw interface{}) error) error {
+
+	iterators, served := m.lookupCandidates(txn, identity, f)
+
+	if iterators == nil {
+		iterator, err := txn.Get(identity, "id")
+		if err != nil {
+			return manipulate.ErrCannotExecuteQuery{Err: err}
 		}
-		raw = iterator.Next()
+		iterators = []memdb.ResultIterator{iterator}
 	}
 
-	*items = combinedItems
+	// Several lookups can return the same object.
+	var seen map[interface{}]struct{}
+	if len(iterators) > 1 {
+		seen = map[interface{}]struct{}{}
+	}
+
+	var scanned int
+	for _, iterator := range iterators {
+
+		for raw := iterator.Next(); raw != nil; raw = iterator.Next() {
+
+			scanned++
+			if scanned%contextCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return manipulate.ErrCannotExecuteQuery{Err: err}
+				}
+			}
+
+			if seen != nil {
+				if _, ok := seen[raw]; ok {
+					continue
+				}
+				seen[raw] = struct{}{}
+			}
+
+			if f != nil {
+				ok, err := matchFilterServed(raw, f, m.attributes[identity], served)
+				if err != nil {
+					return manipulate.ErrCannotExecuteQuery{Err: err}
+				}
+				if !ok {
+					continue
+				}
+			}
+
+			if err := do(raw); err != nil {
+				return err
+			}
+		}
+	}
 
 	return nil
 }
 
+// lookupCandidates uses the indexes to find the objects that may match the
+// given filter, and returns the condition it served. It returns nil if the
+// filter cannot be served by an index, in which case the table must be scanned.
+func (m *memdbManipulator) lookupCandidates(txn *memdb.Txn, identity string, f *elemental.Filter) ([]memdb.ResultIterator, *servedCondition) {
+
+	table, ok := m.schema.Tables[identity]
+	if !ok {
+		return nil, nil
+	}
+
+	lookup := planIndexLookup(f, table.Indexes)
+	if lookup == nil {
+		return nil, nil
+	}
+
+	iterators := make([]memdb.ResultIterator, len(lookup.values))
+	for i, value := range lookup.values {
+		iterator, err := txn.Get(identity, lookup.index, value)
+		if err != nil {
+			// The value cannot be looked up in the index, for
+			// instance because of its type, so we scan instead.
+			return nil, nil
+		}
+		iterators[i] = iterator
+	}
+
+	return iterators, &lookup.served
+}
+
+// recordOperation reports the given operation to the metrics recorder.
+func (m *memdbManipulator) recordOperation(operation elemental.Operation, identity elemental.Identity, start time.Time, err *error) {
+
+	if m.metricsRecorder != nil {
+		m.metricsRecorder.RecordOperation(operation, identity, time.Since(start), *err)
+	}
+}
+
 func (m *memdbManipulator) getDB() *memdb.MemDB {
 
 	m.dbLock.RLock()
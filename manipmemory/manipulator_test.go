@@ -14,9 +14,12 @@ package manipmemory
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"reflect"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"go.aporeto.io/elemental"
 
@@ -125,8 +128,9 @@ func TestMemManipulator_New(t *testing.T) {
 		})
 
 		Convey("Then the schema should be correct", func() {
-			So(len(d.schema.Tables), ShouldEqual, 1)
+			So(len(d.schema.Tables), ShouldEqual, 2)
 			So(d.schema.Tables, ShouldContainKey, testmodel.ListIdentity.Category)
+			So(d.schema.Tables, ShouldContainKey, expirationsTable)
 			So(len(d.schema.Tables[testmodel.ListIdentity.Category].Indexes), ShouldEqual, 6)
 			So(d.schema.Tables[testmodel.ListIdentity.Category].Indexes["id"],
 				ShouldResemble,
@@ -206,6 +210,73 @@ func Test_Flush(t *testing.T) {
 	})
 }
 
+func TestMemManipulator_Ping(t *testing.T) {
+
+	Convey("Given a valid data store", t, func() {
+
+		m, _ := New(datastoreIndexConfig())
+
+		Convey("When I ping it", func() {
+
+			err := manipulate.Ping(context.Background(), m)
+
+			Convey("Then there should be no error", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
+
+type testMetricsRecorder struct {
+	operations []string
+	errors     []error
+	sync.Mutex
+}
+
+func (r *testMetricsRecorder) RecordOperation(operation elemental.Operation, identity elemental.Identity, duration time.Duration, err error) {
+	r.Lock()
+	r.operations = append(r.operations, string(operation)+" "+identity.Name)
+	r.errors = append(r.errors, err)
+	r.Unlock()
+}
+
+func TestMemManipulator_MetricsRecorder(t *testing.T) {
+
+	Convey("Given a data store with a metrics recorder", t, func() {
+
+		r := &testMetricsRecorder{}
+		m, _ := New(datastoreIndexConfig(), OptionMetricsRecorder(r))
+
+		Convey("When I perform some operations", func() {
+
+			l := &testmodel.List{Name: "a"}
+
+			So(m.Create(nil, l), ShouldBeNil)
+			So(m.Update(nil, l), ShouldBeNil)
+			So(m.Retrieve(nil, l), ShouldBeNil)
+			So(m.RetrieveMany(nil, &testmodel.ListsList{}), ShouldBeNil)
+			_, err := m.Count(nil, testmodel.ListIdentity)
+			So(err, ShouldBeNil)
+			So(m.Delete(nil, l), ShouldBeNil)
+			So(m.Retrieve(nil, l), ShouldNotBeNil)
+
+			Convey("Then they should have been recorded", func() {
+				So(r.operations, ShouldResemble, []string{
+					"create list",
+					"update list",
+					"retrieve list",
+					"retrieve-many list",
+					"info list",
+					"delete list",
+					"retrieve list",
+				})
+				So(r.errors[:6], ShouldResemble, []error{nil, nil, nil, nil, nil, nil})
+				So(r.errors[6], ShouldHaveSameTypeAs, manipulate.ErrObjectNotFound{})
+			})
+		})
+	})
+}
+
 func TestMemManipulator_Create(t *testing.T) {
 
 	Convey("Given I have a memory manipulator and a list", t, func() {
@@ -248,6 +319,37 @@ func TestMemManipulator_Create(t *testing.T) {
 			})
 		})
 
+		Convey("When I create a list with a preset identifier", func() {
+
+			p.ID = "5d83e7eedb40280001887565"
+			err := m.Create(nil, p)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then list ID should not have been changed", func() {
+				So(p.ID, ShouldEqual, "5d83e7eedb40280001887565")
+			})
+
+			Convey("When I retrieve the list using the preset identifier", func() {
+
+				l2 := &testmodel.List{
+					ID: "5d83e7eedb40280001887565",
+				}
+
+				err := m.Retrieve(nil, l2)
+
+				Convey("Then err should be nil", func() {
+					So(err, ShouldBeNil)
+				})
+
+				Convey("Then l2 should be p", func() {
+					So(l2, ShouldResemble, p)
+				})
+			})
+		})
+
 		Convey("When I create an object that is not part of the schema", func() {
 
 			err := m.Create(nil, &testmodel.Task{})
@@ -259,6 +361,40 @@ func TestMemManipulator_Create(t *testing.T) {
 	})
 }
 
+func TestMemManipulator_CreateWithIDGenerator(t *testing.T) {
+
+	Convey("Given I have a memory manipulator with an id generator", t, func() {
+
+		var n int
+		m, err := New(datastoreIndexConfig(), OptionIDGenerator(func() string {
+			n++
+			return "id-" + strconv.Itoa(n)
+		}))
+		So(err, ShouldBeNil)
+
+		Convey("When I create lists", func() {
+
+			l1 := &testmodel.List{Name: "a"}
+			l2 := &testmodel.List{Name: "b"}
+			l3 := &testmodel.List{ID: "preset", Name: "c"}
+
+			So(m.Create(nil, l1), ShouldBeNil)
+			So(m.Create(nil, l2), ShouldBeNil)
+			So(m.Create(nil, l3), ShouldBeNil)
+
+			Convey("Then the identifiers should have been generated", func() {
+				So(l1.ID, ShouldEqual, "id-1")
+				So(l2.ID, ShouldEqual, "id-2")
+			})
+
+			Convey("Then the identifier already set should have been kept", func() {
+				So(l3.ID, ShouldEqual, "preset")
+				So(n, ShouldEqual, 2)
+			})
+		})
+	})
+}
+
 func TestMemManipulator_Retrieve(t *testing.T) {
 
 	Convey("Given I have a memory manipulator and a list", t, func() {
@@ -411,6 +547,30 @@ func TestMemManipulator_RetrieveMany(t *testing.T) {
 			})
 		})
 
+		Convey("When I retrieve the lists with a filter that matches l1 and l2 using In", func() {
+
+			ps := testmodel.ListsList{}
+
+			mctx := manipulate.NewContext(
+				context.Background(),
+				manipulate.ContextOptionFilter(
+					elemental.NewFilterComposer().WithKey("Name").In("Antoine1", "Antoine2", "Antoine1").Done(),
+				),
+			)
+
+			err := m.RetrieveMany(mctx, &ps)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then I should only have retrieved l1 and l2", func() {
+				So(len(ps), ShouldEqual, 2)
+				So(ps, ShouldContain, l1)
+				So(ps, ShouldContain, l2)
+			})
+		})
+
 		Convey("When I retrieve the lists with an OR filter that matches l1 and l2", func() {
 
 			ps := testmodel.ListsList{}
@@ -469,6 +629,62 @@ func TestMemManipulator_RetrieveMany(t *testing.T) {
 			})
 		})
 
+		Convey("When I retrieve the lists with nested AND and OR filters", func() {
+
+			ps := testmodel.ListsList{}
+
+			filter := elemental.NewFilterComposer().
+				WithKey("Slice").Contains("a=b").
+				And(
+					elemental.NewFilterComposer().Or(
+						elemental.NewFilterComposer().WithKey("Name").Equals("Antoine1").Done(),
+						elemental.NewFilterComposer().And(
+							elemental.NewFilterComposer().WithKey("Name").Matches("^Dimitri").Done(),
+							elemental.NewFilterComposer().WithKey("Name").NotEquals("Dimitri1").Done(),
+						).Done(),
+					).Done(),
+				).Done()
+
+			mctx := manipulate.NewContext(
+				context.Background(),
+				manipulate.ContextOptionFilter(filter),
+			)
+
+			err := m.RetrieveMany(mctx, &ps)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then I should have l1 and l4", func() {
+				So(len(ps), ShouldEqual, 2)
+				So(ps, ShouldContain, l1)
+				So(ps, ShouldContain, l4)
+			})
+		})
+
+		Convey("When I retrieve the lists with a filter on a non indexed attribute", func() {
+
+			ps := testmodel.ListsList{}
+
+			mctx := manipulate.NewContext(
+				context.Background(),
+				manipulate.ContextOptionFilter(
+					elemental.NewFilterComposer().WithKey("Description").NotExists().Done(),
+				),
+			)
+
+			err := m.RetrieveMany(mctx, &ps)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then I should have retrieved all the items", func() {
+				So(len(ps), ShouldEqual, 4)
+			})
+		})
+
 		Convey("When I retrieve the lists with the Contains comparator", func() {
 
 			ps := testmodel.ListsList{}
@@ -565,6 +781,126 @@ func TestMemManipulator_RetrieveMany(t *testing.T) {
 				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotExecuteQuery{})
 			})
 		})
+
+		Convey("When I retrieve the lists ordered by name", func() {
+
+			ps := testmodel.ListsList{}
+
+			err := m.RetrieveMany(manipulate.NewContext(context.Background(), manipulate.ContextOptionOrder("name")), &ps)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the items should be sorted", func() {
+				So(ps, ShouldResemble, testmodel.ListsList{l1, l2, l3, l4})
+			})
+		})
+
+		Convey("When I retrieve the lists ordered by descending name", func() {
+
+			ps := testmodel.ListsList{}
+
+			err := m.RetrieveMany(manipulate.NewContext(context.Background(), manipulate.ContextOptionOrder("-name")), &ps)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the items should be sorted", func() {
+				So(ps, ShouldResemble, testmodel.ListsList{l4, l3, l2, l1})
+			})
+		})
+
+		Convey("When I retrieve the lists using pages", func() {
+
+			p1 := testmodel.ListsList{}
+			p2 := testmodel.ListsList{}
+			p3 := testmodel.ListsList{}
+
+			err1 := m.RetrieveMany(manipulate.NewContext(context.Background(), manipulate.ContextOptionOrder("name"), manipulate.ContextOptionPage(1, 3)), &p1)
+			err2 := m.RetrieveMany(manipulate.NewContext(context.Background(), manipulate.ContextOptionOrder("name"), manipulate.ContextOptionPage(2, 3)), &p2)
+			err3 := m.RetrieveMany(manipulate.NewContext(context.Background(), manipulate.ContextOptionOrder("name"), manipulate.ContextOptionPage(3, 3)), &p3)
+
+			Convey("Then err should be nil", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldBeNil)
+				So(err3, ShouldBeNil)
+			})
+
+			Convey("Then the pages should be correct", func() {
+				So(p1, ShouldResemble, testmodel.ListsList{l1, l2, l3})
+				So(p2, ShouldResemble, testmodel.ListsList{l4})
+				So(len(p3), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I retrieve the lists using after and limit", func() {
+
+			p1 := testmodel.ListsList{}
+			mctx1 := manipulate.NewContext(context.Background(), manipulate.ContextOptionOrder("name"), manipulate.ContextOptionAfter("", 3))
+			err1 := m.RetrieveMany(mctx1, &p1)
+
+			p2 := testmodel.ListsList{}
+			mctx2 := manipulate.NewContext(context.Background(), manipulate.ContextOptionOrder("name"), manipulate.ContextOptionAfter(mctx1.Next(), 3))
+			err2 := m.RetrieveMany(mctx2, &p2)
+
+			Convey("Then err should be nil", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldBeNil)
+			})
+
+			Convey("Then the pages should be correct", func() {
+				So(p1, ShouldResemble, testmodel.ListsList{l1, l2, l3})
+				So(mctx1.Next(), ShouldEqual, l3.ID)
+				So(p2, ShouldResemble, testmodel.ListsList{l4})
+				So(mctx2.Next(), ShouldEqual, "")
+			})
+		})
+
+		Convey("When I iterate over the lists", func() {
+
+			var blocks int
+			ps := testmodel.ListsList{}
+
+			err := manipulate.IterFunc(
+				context.Background(),
+				m,
+				&testmodel.ListsList{},
+				nil,
+				func(block elemental.Identifiables) error {
+					blocks++
+					ps = append(ps, *block.(*testmodel.ListsList)...)
+					return nil
+				},
+				3,
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then I should have retrieved all the lists in two blocks", func() {
+				So(blocks, ShouldEqual, 2)
+				So(len(ps), ShouldEqual, 4)
+				So(ps, ShouldContain, l1)
+				So(ps, ShouldContain, l2)
+				So(ps, ShouldContain, l3)
+				So(ps, ShouldContain, l4)
+			})
+		})
+
+		Convey("When I retrieve the lists with a page but no page size", func() {
+
+			ps := testmodel.ListsList{}
+
+			err := m.RetrieveMany(manipulate.NewContext(context.Background(), manipulate.ContextOptionPage(2, 0)), &ps)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrInvalidQuery{})
+			})
+		})
 	})
 }
 
@@ -814,6 +1150,305 @@ func TestMemManipulator_Count(t *testing.T) {
 	})
 }
 
+func TestMemManipulator_GeoWithin(t *testing.T) {
+
+	Convey("Given I have a memory manipulator and a context with a geo query", t, func() {
+
+		m, err := New(datastoreIndexConfig())
+		So(err, ShouldBeNil)
+
+		mctx := manipulate.NewContext(
+			context.Background(),
+			manipulate.ContextOptionOpaque(map[string]interface{}{opaqueKeyMongoGeoWithin: struct{}{}}),
+		)
+
+		Convey("When I retrieve many lists", func() {
+
+			err := m.RetrieveMany(mctx, &testmodel.ListsList{})
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrNotImplemented{})
+			})
+		})
+
+		Convey("When I count the lists", func() {
+
+			_, err := m.Count(mctx, testmodel.ListIdentity)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrNotImplemented{})
+			})
+		})
+	})
+}
+
+func TestMemManipulator_ContextCancellation(t *testing.T) {
+
+	Convey("Given I have a memory manipulator with a lot of lists", t, func() {
+
+		m, err := New(datastoreIndexConfig())
+		So(err, ShouldBeNil)
+
+		for i := 0; i < 3*contextCheckInterval; i++ {
+			So(m.Create(nil, &testmodel.List{Name: "list-" + strconv.Itoa(i)}), ShouldBeNil)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		mctx := manipulate.NewContext(ctx)
+
+		Convey("When I retrieve the lists with a canceled context", func() {
+
+			cancel()
+			err := m.RetrieveMany(mctx, &testmodel.ListsList{})
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotExecuteQuery{})
+				So(errors.Is(err, context.Canceled), ShouldBeTrue)
+			})
+		})
+
+		Convey("When I count the lists with a canceled context", func() {
+
+			cancel()
+			_, err := m.Count(mctx, testmodel.ListIdentity)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotExecuteQuery{})
+				So(errors.Is(err, context.Canceled), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the context is canceled during a scan", func() {
+
+			var scanned int
+			err := m.(*memdbManipulator).forEachMatch(ctx, m.(*memdbManipulator).getDB().Txn(false), testmodel.ListIdentity.Category, nil, func(interface{}) error {
+				scanned++
+				cancel()
+				return nil
+			})
+
+			Convey("Then the scan should stop at the next check", func() {
+				So(errors.Is(err, context.Canceled), ShouldBeTrue)
+				So(scanned, ShouldEqual, contextCheckInterval-1)
+			})
+		})
+
+		Reset(cancel)
+	})
+}
+
+func TestMemManipulator_TTL(t *testing.T) {
+
+	Convey("Given I have a memory manipulator with an expiring object and a regular one", t, func() {
+
+		m, err := New(datastoreIndexConfig())
+		So(err, ShouldBeNil)
+		d := m.(*memdbManipulator)
+
+		l1 := &testmodel.List{Name: "expiring"}
+		l2 := &testmodel.List{Name: "regular"}
+		So(m.Create(manipulate.NewContext(context.Background(), ContextOptionTTL(time.Hour)), l1), ShouldBeNil)
+		So(m.Create(nil, l2), ShouldBeNil)
+
+		Convey("When I reap before the expiration", func() {
+
+			n, err := d.reap(time.Now())
+
+			Convey("Then nothing should be deleted", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, 0)
+				c, _ := m.Count(nil, testmodel.ListIdentity)
+				So(c, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I reap after the expiration", func() {
+
+			n, err := d.reap(time.Now().Add(2 * time.Hour))
+
+			Convey("Then the expiring object should be deleted", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, 1)
+				So(m.Retrieve(nil, &testmodel.List{ID: l1.ID}), ShouldHaveSameTypeAs, manipulate.ErrObjectNotFound{})
+				So(m.Retrieve(nil, &testmodel.List{ID: l2.ID}), ShouldBeNil)
+			})
+
+			Convey("Then the expiration should be removed", func() {
+				n, err := d.reap(time.Now().Add(2 * time.Hour))
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, 0)
+				raw, _ := d.getDB().Txn(false).First(expirationsTable, "id", expirationKey(testmodel.ListIdentity.Category, l1.ID))
+				So(raw, ShouldBeNil)
+			})
+		})
+
+		Convey("When I delete the expiring object before the expiration", func() {
+
+			So(m.Delete(nil, l1), ShouldBeNil)
+
+			Convey("Then its expiration should be removed", func() {
+				raw, _ := d.getDB().Txn(false).First(expirationsTable, "id", expirationKey(testmodel.ListIdentity.Category, l1.ID))
+				So(raw, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have a memory manipulator with a running reaper", t, func() {
+
+		m, err := New(datastoreIndexConfig())
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		StartReaper(ctx, m, 10*time.Millisecond)
+
+		Convey("When I create an object expiring soon", func() {
+
+			So(m.Create(manipulate.NewContext(context.Background(), ContextOptionTTL(20*time.Millisecond)), &testmodel.List{}), ShouldBeNil)
+
+			Convey("Then it should eventually disappear", func() {
+
+				var c int
+				for i := 0; i < 100; i++ {
+					if c, _ = m.Count(nil, testmodel.ListIdentity); c == 0 {
+						break
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+
+				So(c, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestMemManipulator_ReapTransactions(t *testing.T) {
+
+	Convey("Given I have a memory manipulator with a transaction timeout", t, func() {
+
+		m, err := New(datastoreIndexConfig(), OptionTransactionTimeout(time.Minute))
+		So(err, ShouldBeNil)
+		d := m.(*memdbManipulator)
+
+		tid := manipulate.NewTransactionID()
+		So(m.Create(manipulate.NewContext(context.Background(), manipulate.ContextOptionTransactionID(tid)), &testmodel.List{Name: "a"}), ShouldBeNil)
+
+		Convey("When I reap the transactions before the timeout", func() {
+
+			n := d.reapTransactions(time.Now())
+
+			Convey("Then nothing should be aborted", func() {
+				So(n, ShouldEqual, 0)
+				So(d.registeredTxnWithID(tid), ShouldNotBeNil)
+			})
+		})
+
+		Convey("When I reap the transactions after the timeout", func() {
+
+			n := d.reapTransactions(time.Now().Add(2 * time.Minute))
+
+			Convey("Then the transaction should be aborted", func() {
+				So(n, ShouldEqual, 1)
+				So(d.registeredTxnWithID(tid), ShouldBeNil)
+				So(m.Commit(tid), ShouldHaveSameTypeAs, manipulate.ErrCannotCommit{})
+			})
+
+			Convey("Then the object should not have been created", func() {
+				c, err := m.Count(nil, testmodel.ListIdentity)
+				So(err, ShouldBeNil)
+				So(c, ShouldEqual, 0)
+			})
+
+			Convey("Then other writers should not be blocked", func() {
+				So(m.Create(nil, &testmodel.List{Name: "b"}), ShouldBeNil)
+			})
+
+			Convey("Then using the transaction again should fail", func() {
+				err := m.Create(manipulate.NewContext(context.Background(), manipulate.ContextOptionTransactionID(tid)), &testmodel.List{Name: "b"})
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotExecuteQuery{})
+			})
+
+			Convey("When I reap the transactions again after another timeout", func() {
+
+				n := d.reapTransactions(time.Now().Add(4 * time.Minute))
+
+				Convey("Then the aborted transaction should be unregistered", func() {
+					So(n, ShouldEqual, 0)
+					So(d.registeredTxnForID(tid), ShouldBeNil)
+				})
+			})
+		})
+	})
+
+	Convey("Given I have a memory manipulator with a transaction timeout and a running reaper", t, func() {
+
+		m, err := New(datastoreIndexConfig(), OptionTransactionTimeout(50*time.Millisecond))
+		So(err, ShouldBeNil)
+
+		So(m.Create(manipulate.NewContext(context.Background(), ContextOptionTTL(time.Millisecond)), &testmodel.List{Name: "expiring"}), ShouldBeNil)
+
+		tid := manipulate.NewTransactionID()
+		So(m.Create(manipulate.NewContext(context.Background(), manipulate.ContextOptionTransactionID(tid)), &testmodel.List{Name: "a"}), ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		StartReaper(ctx, m, 10*time.Millisecond)
+
+		Convey("When I create an object outside of the stale transaction", func() {
+
+			done := make(chan error, 1)
+			go func() { done <- m.Create(nil, &testmodel.List{Name: "b"}) }()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(3 * time.Second):
+				t.Fatal("create is still blocked by the stale transaction")
+			}
+
+			Convey("Then it should succeed", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the expired object should eventually be deleted", func() {
+
+				var c int
+				for i := 0; i < 100; i++ {
+					if c, _ = m.Count(nil, testmodel.ListIdentity); c == 1 {
+						break
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+
+				So(c, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given I have a memory manipulator without transaction timeout", t, func() {
+
+		m, err := New(datastoreIndexConfig())
+		So(err, ShouldBeNil)
+		d := m.(*memdbManipulator)
+
+		tid := manipulate.NewTransactionID()
+		So(m.Create(manipulate.NewContext(context.Background(), manipulate.ContextOptionTransactionID(tid)), &testmodel.List{Name: "a"}), ShouldBeNil)
+
+		Convey("When I reap the transactions", func() {
+
+			n := d.reapTransactions(time.Now().Add(24 * time.Hour))
+
+			Convey("Then nothing should be aborted", func() {
+				So(n, ShouldEqual, 0)
+				So(d.registeredTxnWithID(tid), ShouldNotBeNil)
+				So(m.Commit(tid), ShouldBeNil)
+			})
+		})
+	})
+}
+
 func TestMemManipulator_Commit(t *testing.T) {
 
 	Convey("Given I have a memory manipulator and a transaction ID", t, func() {
@@ -841,6 +1476,31 @@ func TestMemManipulator_Commit(t *testing.T) {
 				So(err, ShouldBeNil)
 			})
 		})
+
+		Convey("When I create several objects in a transaction and call Commit", func() {
+
+			mctx := manipulate.NewContext(context.Background(), manipulate.ContextOptionTransactionID(tid))
+
+			So(m.Create(mctx, &testmodel.List{Name: "a"}), ShouldBeNil)
+			So(m.Create(mctx, &testmodel.List{Name: "b"}), ShouldBeNil)
+
+			c1, err := m.Count(nil, testmodel.ListIdentity)
+			So(err, ShouldBeNil)
+
+			err = m.Commit(tid)
+
+			c2, err2 := m.Count(nil, testmodel.ListIdentity)
+			So(err2, ShouldBeNil)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the objects should only be visible after the commit", func() {
+				So(c1, ShouldEqual, 0)
+				So(c2, ShouldEqual, 2)
+			})
+		})
 	})
 }
 
@@ -873,6 +1533,78 @@ func TestMemManipulator_Abort(t *testing.T) {
 	})
 }
 
+func TestMemManipulator_Concurrency(t *testing.T) {
+
+	Convey("Given I have a memory manipulator shared by many goroutines", t, func() {
+
+		m, err := New(datastoreIndexConfig())
+		So(err, ShouldBeNil)
+
+		const workers = 20
+		const objects = 50
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers*objects*4)
+
+		for w := 0; w < workers; w++ {
+
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+
+				for i := 0; i < objects; i++ {
+
+					obj := &testmodel.List{Name: "w" + strconv.Itoa(w)}
+					if err := m.Create(nil, obj); err != nil {
+						errs <- err
+						continue
+					}
+
+					obj.Description = "updated"
+					if err := m.Update(nil, obj); err != nil {
+						errs <- err
+					}
+
+					if err := m.Retrieve(nil, &testmodel.List{ID: obj.ID}); err != nil {
+						errs <- err
+					}
+
+					l := testmodel.ListsList{}
+					mctx := manipulate.NewContext(
+						context.Background(),
+						manipulate.ContextOptionFilter(
+							elemental.NewFilterComposer().WithKey("name").Equals(obj.Name).Done(),
+						),
+					)
+					if err := m.RetrieveMany(mctx, &l); err != nil {
+						errs <- err
+					}
+				}
+			}(w)
+		}
+
+		wg.Wait()
+		close(errs)
+
+		Convey("Then no operation should have failed", func() {
+			for err := range errs {
+				So(err, ShouldBeNil)
+			}
+		})
+
+		Convey("Then no write should have been lost", func() {
+
+			l := testmodel.ListsList{}
+			So(m.RetrieveMany(nil, &l), ShouldBeNil)
+			So(len(l), ShouldEqual, workers*objects)
+
+			for _, o := range l {
+				So(o.Description, ShouldEqual, "updated")
+			}
+		})
+	})
+}
+
 func TestMemManipulator_txnForID(t *testing.T) {
 
 	Convey("Given I have a memory manipulator and a transaction ID", t, func() {
@@ -883,9 +1615,11 @@ func TestMemManipulator_txnForID(t *testing.T) {
 
 		Convey("When I call txnForID with an empty ID", func() {
 
-			txn := m.(*memdbManipulator).txnForID("")
+			txn, release, err := m.(*memdbManipulator).txnForID("")
+			defer release()
 
 			Convey("Then txn should not be nil", func() {
+				So(err, ShouldBeNil)
 				So(txn, ShouldNotBeNil)
 			})
 		})
@@ -894,18 +1628,22 @@ func TestMemManipulator_txnForID(t *testing.T) {
 
 			btxn := m.(*memdbManipulator).db.Txn(true)
 			m.(*memdbManipulator).registerTxn(tid, btxn)
-			txn := m.(*memdbManipulator).txnForID(tid)
+			txn, release, err := m.(*memdbManipulator).txnForID(tid)
+			defer release()
 
 			Convey("Then txn should not be nil", func() {
+				So(err, ShouldBeNil)
 				So(txn, ShouldEqual, btxn)
 			})
 		})
 
 		Convey("When I call txnForID with an non existing ID", func() {
 
-			txn := m.(*memdbManipulator).txnForID(tid)
+			txn, release, err := m.(*memdbManipulator).txnForID(tid)
+			defer release()
 
 			Convey("Then txn should not be nil", func() {
+				So(err, ShouldBeNil)
 				So(txn, ShouldNotBeNil)
 			})
 		})
@@ -953,6 +1691,53 @@ func BenchmarkRetrieveMany(b *testing.B) {
 	}
 }
 
+func BenchmarkCount(b *testing.B) {
+	b.StopTimer()
+
+	m, err := New(datastoreIndexConfig())
+	So(err, ShouldBeNil)
+	err = populateDB(m, 10000)
+	So(err, ShouldBeNil)
+
+	mctx := manipulate.NewContext(
+		context.Background(),
+		manipulate.ContextOptionFilter(
+			elemental.NewFilterComposer().WithKey("Slice").Contains("label=common").Done(),
+		),
+	)
+	b.StartTimer()
+
+	b.Run("Count", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			n, err := m.Count(mctx, testmodel.ListIdentity)
+			if err != nil {
+				b.Errorf("Error in count: %s", err.Error())
+				b.FailNow()
+			}
+			if n != 10000 {
+				b.Errorf("Count is wrong: %d", n)
+				b.FailNow()
+			}
+		}
+	})
+
+	b.Run("RetrieveMany", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			list := testmodel.ListsList{}
+			if err := m.RetrieveMany(mctx, &list); err != nil {
+				b.Errorf("Error in retrieve many: %s", err.Error())
+				b.FailNow()
+			}
+			if len(list) != 10000 {
+				b.Errorf("Length of list is wrong: %d", len(list))
+				b.FailNow()
+			}
+		}
+	})
+}
+
 func populateDB(m manipulate.TransactionalManipulator, num int) error {
 
 	for i := 0; i < num; i++ {
@@ -11,15 +11,28 @@
 
 package manipmemory
 
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"go.aporeto.io/manipulate"
+)
+
 // An Option represents a maniphttp.Manipulator option.
 type Option func(*config)
 
 type config struct {
-	noCopy bool
+	noCopy          bool
+	metricsRecorder manipulate.MetricsRecorder
+	idGenerator     func() string
+	txnTimeout      time.Duration
 }
 
 func newConfig() *config {
-	return &config{}
+	return &config{
+		metricsRecorder: manipulate.NewNoopMetricsRecorder(),
+		idGenerator:     func() string { return bson.NewObjectId().Hex() },
+	}
 }
 
 // OptionNoCopy tells the manipulator to store the data
@@ -33,3 +46,73 @@ func OptionNoCopy(noCopy bool) Option {
 		c.noCopy = noCopy
 	}
 }
+
+// OptionMetricsRecorder sets the manipulate.MetricsRecorder
+// notified of every operation performed by the manipulator.
+// The default records nothing.
+func OptionMetricsRecorder(recorder manipulate.MetricsRecorder) Option {
+
+	if recorder == nil {
+		panic("recorder must not be nil")
+	}
+
+	return func(c *config) {
+		c.metricsRecorder = recorder
+	}
+}
+
+// OptionIDGenerator sets the function used by Create to generate
+// the identifier of objects that do not have one already. This is
+// mostly useful to get predictable identifiers in tests.
+// The default generates a new mongo ObjectId.
+func OptionIDGenerator(generator func() string) Option {
+
+	if generator == nil {
+		panic("generator must not be nil")
+	}
+
+	return func(c *config) {
+		c.idGenerator = generator
+	}
+}
+
+// OptionTransactionTimeout sets the duration after which a transaction
+// started using a TransactionID is considered stale. Stale transactions
+// are aborted by the reaper started with StartReaper, releasing the
+// write lock they hold. The default is to never abort transactions.
+// If the given timeout is not positive, OptionTransactionTimeout will panic.
+func OptionTransactionTimeout(timeout time.Duration) Option {
+
+	if timeout <= 0 {
+		panic("timeout must be positive")
+	}
+
+	return func(c *config) {
+		c.txnTimeout = timeout
+	}
+}
+
+const opaqueKeyTTL = "manipmemory.ttl"
+
+// opaqueKeyMongoGeoWithin is the key set by manipmongo.ContextOptionGeoWithin.
+// Geo queries are not supported, and must not be silently ignored.
+const opaqueKeyMongoGeoWithin = "manipmongo.geowithin"
+
+type opaquer interface {
+	Opaque() map[string]interface{}
+}
+
+// ContextOptionTTL tells Create to set an expiration date on the created
+// object. Expired objects are deleted by the reaper started with StartReaper,
+// so this is only best effort: they stay retrievable until it runs.
+// If the given ttl is not positive, ContextOptionTTL will panic.
+func ContextOptionTTL(ttl time.Duration) manipulate.ContextOption {
+
+	if ttl <= 0 {
+		panic("ttl must be positive")
+	}
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyTTL] = ttl
+	}
+}
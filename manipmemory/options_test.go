@@ -12,9 +12,12 @@
 package manipmemory
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/manipulate"
 )
 
 func Test_newConfig(t *testing.T) {
@@ -25,6 +28,7 @@ func Test_newConfig(t *testing.T) {
 
 		Convey("Then I should get the default config", func() {
 			So(c.noCopy, ShouldBeFalse)
+			So(c.metricsRecorder, ShouldNotBeNil)
 		})
 	})
 }
@@ -36,4 +40,48 @@ func Test_Options(t *testing.T) {
 		OptionNoCopy(true)(c)
 		So(c.noCopy, ShouldBeTrue)
 	})
+
+	Convey("Calling OptionMetricsRecorder should work", t, func() {
+		r := manipulate.NewNoopMetricsRecorder()
+		c := newConfig()
+		OptionMetricsRecorder(r)(c)
+		So(c.metricsRecorder, ShouldEqual, r)
+	})
+
+	Convey("Calling OptionMetricsRecorder with a nil recorder should panic", t, func() {
+		So(func() { OptionMetricsRecorder(nil) }, ShouldPanicWith, "recorder must not be nil")
+	})
+
+	Convey("Calling OptionIDGenerator should work", t, func() {
+		c := newConfig()
+		OptionIDGenerator(func() string { return "id" })(c)
+		So(c.idGenerator(), ShouldEqual, "id")
+	})
+
+	Convey("Calling OptionIDGenerator with a nil generator should panic", t, func() {
+		So(func() { OptionIDGenerator(nil) }, ShouldPanicWith, "generator must not be nil")
+	})
+
+	Convey("Calling OptionTransactionTimeout should work", t, func() {
+		c := newConfig()
+		OptionTransactionTimeout(time.Minute)(c)
+		So(c.txnTimeout, ShouldEqual, time.Minute)
+	})
+
+	Convey("Calling OptionTransactionTimeout with a zero timeout should panic", t, func() {
+		So(func() { OptionTransactionTimeout(0) }, ShouldPanicWith, "timeout must be positive")
+	})
+}
+
+func Test_ContextOptions(t *testing.T) {
+
+	Convey("Calling ContextOptionTTL should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionTTL(time.Hour)(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyTTL], ShouldEqual, time.Hour)
+	})
+
+	Convey("Calling ContextOptionTTL with a zero ttl should panic", t, func() {
+		So(func() { ContextOptionTTL(0) }, ShouldPanicWith, "ttl must be positive")
+	})
 }
@@ -12,6 +12,10 @@
 package manipmemory
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+
 	"go.aporeto.io/elemental"
 )
 
@@ -52,3 +56,38 @@ type IdentitySchema struct {
 	// Indexes of the object
 	Indexes []*Index
 }
+
+// NewIndexFromTag returns an Index of the given type on the field of the given
+// object whose json or bson tag is the given name. The Index is named after
+// the tag, so filters can use the same keys as the ones used in the json or
+// bson representation of the object, even if they differ from the name of
+// the Go struct field.
+func NewIndexFromTag(obj interface{}, tag string, typ IndexType, unique bool) (*Index, error) {
+
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("object must be a struct or a pointer to a struct: %T", obj)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+
+		for _, key := range []string{"json", "bson"} {
+			if name := strings.Split(f.Tag.Get(key), ",")[0]; name != "" && name != "-" && name == tag {
+				return &Index{
+					Name:      tag,
+					Type:      typ,
+					Unique:    unique,
+					Attribute: f.Name,
+				}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no field of %s has a json or bson tag named '%s'", t.Name(), tag)
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipmemory
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewIndexFromTag(t *testing.T) {
+
+	type taggedObject struct {
+		Identifier string   `json:"ID" bson:"_id"`
+		FullName   string   `json:"name,omitempty" bson:"fullname"`
+		Labels     []string `json:"tags"`
+		Ignored    string   `json:"-"`
+	}
+
+	Convey("Given I have a tagged object", t, func() {
+
+		Convey("When I create an index from a json tag", func() {
+
+			idx, err := NewIndexFromTag(&taggedObject{}, "name", IndexTypeString, false)
+
+			Convey("Then the index should target the right field", func() {
+				So(err, ShouldBeNil)
+				So(idx, ShouldResemble, &Index{Name: "name", Type: IndexTypeString, Attribute: "FullName"})
+			})
+		})
+
+		Convey("When I create an index from a bson tag", func() {
+
+			idx, err := NewIndexFromTag(taggedObject{}, "fullname", IndexTypeString, true)
+
+			Convey("Then the index should target the right field", func() {
+				So(err, ShouldBeNil)
+				So(idx, ShouldResemble, &Index{Name: "fullname", Type: IndexTypeString, Unique: true, Attribute: "FullName"})
+			})
+		})
+
+		Convey("When I create an index on a slice", func() {
+
+			idx, err := NewIndexFromTag(&taggedObject{}, "tags", IndexTypeSlice, false)
+
+			Convey("Then the index should target the right field", func() {
+				So(err, ShouldBeNil)
+				So(idx.Attribute, ShouldEqual, "Labels")
+			})
+
+			Convey("Then it should be usable in a schema", func() {
+				_, err := createSchema(&IdentitySchema{Indexes: []*Index{idx}})
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When I create an index from an unknown tag", func() {
+
+			_, err := NewIndexFromTag(&taggedObject{}, "FullName", IndexTypeString, false)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "no field of taggedObject has a json or bson tag named 'FullName'")
+			})
+		})
+
+		Convey("When I create an index from an ignored field", func() {
+
+			_, err := NewIndexFromTag(&taggedObject{}, "-", IndexTypeString, false)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When I create an index from something that is not a struct", func() {
+
+			_, err := NewIndexFromTag("nope", "name", IndexTypeString, false)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "object must be a struct or a pointer to a struct: string")
+			})
+		})
+	})
+}
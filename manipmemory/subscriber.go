@@ -0,0 +1,114 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipmemory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.aporeto.io/elemental"
+	"go.aporeto.io/manipulate"
+)
+
+const (
+	eventChSize  = 2048
+	errorChSize  = 64
+	statusChSize = 8
+)
+
+type subscription struct {
+	manipulator *memdbManipulator
+	events      chan *elemental.Event
+	errors      chan error
+	status      chan manipulate.SubscriberStatus
+	filter      *elemental.PushConfig
+	filterLock  sync.RWMutex
+}
+
+// NewSubscriber returns a new manipulate.Subscriber receiving an event
+// every time an object is created, updated or deleted in the given memory
+// manipulator. Events of writes done in a transaction are sent once the
+// transaction is committed. As for a websocket subscription, the events
+// can be filtered by identity and type using an elemental.PushConfig.
+func NewSubscriber(manipulator manipulate.Manipulator) manipulate.Subscriber {
+
+	m, ok := manipulator.(*memdbManipulator)
+	if !ok {
+		panic("you can only pass a memory manipulator to NewSubscriber")
+	}
+
+	return &subscription{
+		manipulator: m,
+		events:      make(chan *elemental.Event, eventChSize),
+		errors:      make(chan error, errorChSize),
+		status:      make(chan manipulate.SubscriberStatus, statusChSize),
+	}
+}
+
+func (s *subscription) Events() chan *elemental.Event            { return s.events }
+func (s *subscription) Errors() chan error                       { return s.errors }
+func (s *subscription) Status() chan manipulate.SubscriberStatus { return s.status }
+
+func (s *subscription) Start(ctx context.Context, filter *elemental.PushConfig) {
+
+	s.UpdateFilter(filter)
+
+	s.manipulator.registerSubscription(s)
+	s.publishStatus(manipulate.SubscriberStatusInitialConnection)
+
+	go func() {
+		<-ctx.Done()
+		s.manipulator.unregisterSubscription(s)
+		s.publishStatus(manipulate.SubscriberStatusFinalDisconnection)
+	}()
+}
+
+func (s *subscription) UpdateFilter(filter *elemental.PushConfig) {
+
+	s.filterLock.Lock()
+	s.filter = filter
+	s.filterLock.Unlock()
+}
+
+func (s *subscription) dispatch(evt *elemental.Event) {
+
+	s.filterLock.RLock()
+	filter := s.filter
+	s.filterLock.RUnlock()
+
+	if filter != nil && filter.IsFilteredOut(evt.Identity, evt.Type) {
+		return
+	}
+
+	// Each subscription gets its own copy of the
+	// event, as the receivers are free to modify it.
+	select {
+	case s.events <- evt.Duplicate():
+	default:
+		s.publishError(fmt.Errorf("unable to forward event: channel full"))
+	}
+}
+
+func (s *subscription) publishError(err error) {
+	select {
+	case s.errors <- err:
+	default:
+	}
+}
+
+func (s *subscription) publishStatus(st manipulate.SubscriberStatus) {
+	select {
+	case s.status <- st:
+	default:
+	}
+}
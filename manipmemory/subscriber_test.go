@@ -0,0 +1,182 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+	"go.aporeto.io/manipulate"
+	"go.aporeto.io/manipulate/maniptest"
+)
+
+func TestNewSubscriber(t *testing.T) {
+
+	Convey("Given I have a non memory manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call NewSubscriber", func() {
+			Convey("Then it should panic", func() {
+				So(func() { NewSubscriber(m) }, ShouldPanicWith, "you can only pass a memory manipulator to NewSubscriber")
+			})
+		})
+	})
+}
+
+func TestSubscriber_Events(t *testing.T) {
+
+	Convey("Given I have a memory manipulator and a started subscriber", t, func() {
+
+		m, err := New(datastoreIndexConfig())
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		s := NewSubscriber(m)
+		s.Start(ctx, nil)
+
+		So(<-s.Status(), ShouldEqual, manipulate.SubscriberStatusInitialConnection)
+
+		Convey("When I create, update and delete an object", func() {
+
+			l := &testmodel.List{Name: "hello"}
+
+			So(m.Create(nil, l), ShouldBeNil)
+			l.Name = "world"
+			So(m.Update(nil, l), ShouldBeNil)
+			So(m.Delete(nil, l), ShouldBeNil)
+
+			Convey("Then I should receive the events", func() {
+
+				evt := <-s.Events()
+				So(evt.Type, ShouldEqual, elemental.EventCreate)
+				So(evt.Identity, ShouldEqual, testmodel.ListIdentity.Name)
+
+				dl := &testmodel.List{}
+				So(evt.Decode(dl), ShouldBeNil)
+				So(dl.ID, ShouldEqual, l.ID)
+				So(dl.Name, ShouldEqual, "hello")
+
+				evt = <-s.Events()
+				So(evt.Type, ShouldEqual, elemental.EventUpdate)
+
+				evt = <-s.Events()
+				So(evt.Type, ShouldEqual, elemental.EventDelete)
+			})
+		})
+
+		Convey("When I have another subscriber and create an object", func() {
+
+			s2 := NewSubscriber(m)
+			s2.Start(ctx, nil)
+			So(<-s2.Status(), ShouldEqual, manipulate.SubscriberStatusInitialConnection)
+
+			So(m.Create(nil, &testmodel.List{Name: "hello"}), ShouldBeNil)
+
+			Convey("Then each subscriber should receive its own event", func() {
+
+				evt1 := <-s.Events()
+				evt2 := <-s2.Events()
+
+				So(evt1, ShouldNotPointTo, evt2)
+				So(evt1, ShouldResemble, evt2)
+
+				evt1.Type = elemental.EventDelete
+				So(evt2.Type, ShouldEqual, elemental.EventCreate)
+			})
+		})
+
+		Convey("When I filter out the identity and create an object", func() {
+
+			pc := elemental.NewPushConfig()
+			pc.FilterIdentity(testmodel.TaskIdentity.Name)
+			s.UpdateFilter(pc)
+
+			So(m.Create(nil, &testmodel.List{Name: "hello"}), ShouldBeNil)
+
+			Convey("Then I should not receive any event", func() {
+				So(len(s.Events()), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I filter out the event type and write an object", func() {
+
+			pc := elemental.NewPushConfig()
+			pc.FilterIdentity(testmodel.ListIdentity.Name, elemental.EventUpdate)
+			s.UpdateFilter(pc)
+
+			l := &testmodel.List{Name: "hello"}
+			So(m.Create(nil, l), ShouldBeNil)
+			So(m.Update(nil, l), ShouldBeNil)
+
+			Convey("Then I should only receive the update event", func() {
+				So(len(s.Events()), ShouldEqual, 1)
+				So((<-s.Events()).Type, ShouldEqual, elemental.EventUpdate)
+			})
+		})
+
+		Convey("When I create an object in a transaction", func() {
+
+			tid := manipulate.NewTransactionID()
+			mctx := manipulate.NewContext(context.Background(), manipulate.ContextOptionTransactionID(tid))
+
+			So(m.Create(mctx, &testmodel.List{Name: "hello"}), ShouldBeNil)
+
+			Convey("Then I should not receive any event before committing", func() {
+				So(len(s.Events()), ShouldEqual, 0)
+			})
+
+			Convey("When I commit the transaction", func() {
+
+				So(m.Commit(tid), ShouldBeNil)
+
+				Convey("Then I should receive the event", func() {
+					So(len(s.Events()), ShouldEqual, 1)
+					So((<-s.Events()).Type, ShouldEqual, elemental.EventCreate)
+				})
+			})
+
+			Convey("When I abort the transaction", func() {
+
+				So(m.Abort(tid), ShouldBeTrue)
+
+				Convey("Then I should not receive any event", func() {
+					So(len(s.Events()), ShouldEqual, 0)
+					So(len(m.(*memdbManipulator).pendingEvents), ShouldEqual, 0)
+				})
+			})
+		})
+
+		Convey("When I cancel the context", func() {
+
+			cancel()
+
+			Convey("Then the subscriber should be disconnected", func() {
+
+				select {
+				case st := <-s.Status():
+					So(st, ShouldEqual, manipulate.SubscriberStatusFinalDisconnection)
+				case <-time.After(time.Second):
+					So("no final disconnection received", ShouldBeEmpty)
+				}
+
+				So(len(m.(*memdbManipulator).subscriptions), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -14,10 +14,13 @@ package manipmemory
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	memdb "github.com/hashicorp/go-memdb"
 	"go.aporeto.io/elemental"
+	"go.aporeto.io/manipulate"
 )
 
 // stringBasedFieldIndex is used to extract a field from an object
@@ -139,21 +142,184 @@ func boolIndex(obj interface{}, field string) (bool, error) {
 	return fv.Bool(), nil
 }
 
-func mergeIn(target, source *map[string]elemental.Identifiable) {
-	for k, v := range *source {
-		(*target)[k] = v
+// sortIdentifiables sorts the given objects according to the given order.
+// Each order is the name of a field, matched case insensitively, optionally
+// prefixed with '-' to sort in descending order. Objects that cannot
+// be told apart are sorted by identifier so the result is deterministic.
+func sortIdentifiables(items []elemental.Identifiable, order []string) {
+
+	sort.SliceStable(items, func(i, j int) bool {
+
+		vi := reflect.Indirect(reflect.ValueOf(items[i]))
+		vj := reflect.Indirect(reflect.ValueOf(items[j]))
+
+		for _, o := range order {
+
+			if o == "" {
+				continue
+			}
+
+			desc := strings.HasPrefix(o, "-")
+			field := strings.TrimPrefix(o, "-")
+
+			c := compareValues(fieldByName(vi, field), fieldByName(vj, field))
+			if c == 0 {
+				continue
+			}
+
+			if desc {
+				return c > 0
+			}
+
+			return c < 0
+		}
+
+		return items[i].Identifier() < items[j].Identifier()
+	})
+}
+
+func fieldByName(v reflect.Value, name string) reflect.Value {
+
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	fv := v.FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, name) })
+	if !fv.IsValid() || !fv.CanInterface() {
+		return reflect.Value{}
+	}
+
+	return fv
+}
+
+// compareValues compares the two given values. It returns -1, 0 or 1
+// if a is lesser, equal or greater than b. Values that are invalid,
+// of different kinds or of unsupported kinds are considered equal.
+func compareValues(a, b reflect.Value) int {
+
+	if !a.IsValid() || !b.IsValid() || a.Kind() != b.Kind() {
+		return 0
+	}
+
+	if ta, ok := a.Interface().(time.Time); ok {
+		tb := b.Interface().(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1
+		case ta.After(tb):
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	switch a.Kind() {
+
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case a.Int() < b.Int():
+			return -1
+		case a.Int() > b.Int():
+			return 1
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch {
+		case a.Uint() < b.Uint():
+			return -1
+		case a.Uint() > b.Uint():
+			return 1
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case a.Float() < b.Float():
+			return -1
+		case a.Float() > b.Float():
+			return 1
+		}
+
+	case reflect.Bool:
+		switch {
+		case !a.Bool() && b.Bool():
+			return -1
+		case a.Bool() && !b.Bool():
+			return 1
+		}
 	}
+
+	return 0
 }
 
-func intersection(target, source *map[string]elemental.Identifiable, queryStart bool) {
+// paginate returns the page of the given sorted objects designated by the
+// pagination parameters of the given manipulate.Context. When using after
+// and limit, it also returns the identifier to use to retrieve the next page,
+// or an empty string if there is none. The after identifier is looked up in
+// the objects; if it cannot be found and the objects are sorted by identifier,
+// the page starts at the first object with a greater identifier.
+func paginate(objects []elemental.Identifiable, mctx manipulate.Context, sortedByID bool) ([]elemental.Identifiable, string) {
+
+	after, limit := mctx.After(), mctx.Limit()
+
+	if after == "" && limit <= 0 {
+
+		pageSize := mctx.PageSize()
+		if pageSize <= 0 {
+			return objects, ""
+		}
+
+		start := 0
+		if page := mctx.Page(); page > 0 {
+			start = (page - 1) * pageSize
+		}
+
+		return window(objects, start, pageSize), ""
+	}
+
+	start := 0
+	if after != "" {
 
-	combined := map[string]elemental.Identifiable{}
+		start = len(objects)
 
-	for k, v := range *source {
-		if _, ok := (*target)[k]; ok || queryStart {
-			combined[k] = v
+		for i, o := range objects {
+			if o.Identifier() == after {
+				start = i + 1
+				break
+			}
 		}
+
+		if start == len(objects) && sortedByID {
+			start = sort.Search(len(objects), func(i int) bool { return objects[i].Identifier() > after })
+		}
+	}
+
+	if limit <= 0 {
+		return window(objects, start, len(objects)), ""
+	}
+
+	out := window(objects, start, limit)
+
+	var next string
+	if len(out) == limit {
+		next = out[len(out)-1].Identifier()
+	}
+
+	return out, next
+}
+
+func window(objects []elemental.Identifiable, start int, size int) []elemental.Identifiable {
+
+	if start >= len(objects) {
+		return []elemental.Identifiable{}
+	}
+
+	end := start + size
+	if end > len(objects) {
+		end = len(objects)
 	}
 
-	*target = combined
+	return objects[start:end]
 }
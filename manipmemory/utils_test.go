@@ -13,8 +13,10 @@ package manipmemory
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
 )
 
 func Test_boolIndex(t *testing.T) {
@@ -68,3 +70,65 @@ func Test_boolIndex(t *testing.T) {
 		})
 	})
 }
+
+type sortableObject struct {
+	ID    string
+	Name  string
+	Count int
+	Date  time.Time
+}
+
+func (o *sortableObject) Identity() elemental.Identity { return elemental.MakeIdentity("obj", "objs") }
+func (o *sortableObject) Identifier() string           { return o.ID }
+func (o *sortableObject) SetIdentifier(id string)      { o.ID = id }
+func (o *sortableObject) Version() int                 { return 1 }
+
+func Test_sortIdentifiables(t *testing.T) {
+
+	now := time.Now()
+
+	o1 := &sortableObject{ID: "1", Name: "b", Count: 10, Date: now}
+	o2 := &sortableObject{ID: "2", Name: "a", Count: 2, Date: now.Add(time.Second)}
+	o3 := &sortableObject{ID: "3", Name: "b", Count: 2, Date: now.Add(-time.Second)}
+	o4 := &sortableObject{ID: "4", Name: "a", Count: 10, Date: now}
+
+	Convey("Given I have some objects", t, func() {
+
+		items := []elemental.Identifiable{o1, o2, o3, o4}
+
+		Convey("When I sort them by a string field", func() {
+			sortIdentifiables(items, []string{"name"})
+			So(items, ShouldResemble, []elemental.Identifiable{o2, o4, o1, o3})
+		})
+
+		Convey("When I sort them by a descending string field", func() {
+			sortIdentifiables(items, []string{"-Name"})
+			So(items, ShouldResemble, []elemental.Identifiable{o1, o3, o2, o4})
+		})
+
+		Convey("When I sort them by an integer field", func() {
+			sortIdentifiables(items, []string{"count"})
+			So(items, ShouldResemble, []elemental.Identifiable{o2, o3, o1, o4})
+		})
+
+		Convey("When I sort them by a descending integer field", func() {
+			sortIdentifiables(items, []string{"-count"})
+			So(items, ShouldResemble, []elemental.Identifiable{o1, o4, o2, o3})
+		})
+
+		Convey("When I sort them by multiple fields", func() {
+			sortIdentifiables(items, []string{"-count", "name"})
+			So(items, ShouldResemble, []elemental.Identifiable{o4, o1, o2, o3})
+		})
+
+		Convey("When I sort them by a time field", func() {
+			sortIdentifiables(items, []string{"date"})
+			So(items, ShouldResemble, []elemental.Identifiable{o3, o1, o4, o2})
+		})
+
+		Convey("When I sort them by an unknown field", func() {
+			sortIdentifiables(items, []string{"nope", ""})
+			So(items, ShouldResemble, []elemental.Identifiable{o1, o2, o3, o4})
+		})
+	})
+}
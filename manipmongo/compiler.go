@@ -47,6 +47,11 @@ func CompilerOptionTranslateKeysFromSpec(spec elemental.AttributeSpecifiable) Co
 }
 
 // CompileFilter compiles the given manipulate Filter into a mongo filter.
+//
+// Contains with several values matches the documents containing any of
+// them, as it is compiled to $in. To match the documents containing all of
+// them, chain several Contains on the same key, as the resulting conditions
+// are and-ed together, which is equivalent to $all.
 func CompileFilter(f *elemental.Filter, opts ...CompilerOption) bson.D {
 
 	config := compilerConfig{}
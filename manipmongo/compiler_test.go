@@ -402,6 +402,23 @@ func TestUtils_compiler(t *testing.T) {
 		})
 	})
 
+	Convey("Given I have a range filter on a single key", t, func() {
+
+		f := elemental.NewFilterComposer().
+			WithKey("date").GreaterOrEqualThan(1).
+			WithKey("date").LesserOrEqualThan(10).
+			Done()
+
+		Convey("When I compile the filter", func() {
+
+			b, _ := bson.MarshalJSON(toMap(CompileFilter(f)))
+
+			Convey("Then both constraints should be applied", func() {
+				So(strings.Replace(string(b), "\n", "", 1), ShouldEqual, `{"$and":[{"date":{"$gte":1}},{"date":{"$lte":10}}]}`)
+			})
+		})
+	})
+
 	Convey("Given I have a simple a complex and manipulate.Filter", t, func() {
 
 		f := elemental.NewFilterComposer().
@@ -423,6 +440,23 @@ func TestUtils_compiler(t *testing.T) {
 		})
 	})
 
+	Convey("Given I have filter that contains several Contains on the same key", t, func() {
+
+		f := elemental.NewFilterComposer().
+			WithKey("z").Contains("a").
+			WithKey("z").Contains("b").
+			Done()
+
+		Convey("When I compile the filter", func() {
+
+			b, _ := bson.MarshalJSON(toMap(CompileFilter(f)))
+
+			Convey("Then all the values should be required", func() {
+				So(strings.Replace(string(b), "\n", "", 1), ShouldEqual, `{"$and":[{"z":{"$in":["a"]}},{"z":{"$in":["b"]}}]}`)
+			})
+		})
+	})
+
 	Convey("Given I have filter that contains Match", t, func() {
 
 		f := elemental.NewFilterComposer().
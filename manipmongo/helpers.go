@@ -12,16 +12,21 @@
 package manipmongo
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
+	"github.com/opentracing/opentracing-go/log"
 	"go.aporeto.io/elemental"
 	"go.aporeto.io/manipulate"
 	"go.aporeto.io/manipulate/internal/backoff"
+	"go.aporeto.io/manipulate/internal/objectid"
+	"go.aporeto.io/manipulate/internal/tracing"
 )
 
 // DoesDatabaseExist checks if the database used by the given manipulator exists.
@@ -78,7 +83,7 @@ func CreateIndex(manipulator manipulate.Manipulator, identity elemental.Identity
 			index.Name = "index_" + identity.Name + "_" + strconv.Itoa(i)
 		}
 		if err := collection.EnsureIndex(index); err != nil {
-			return fmt.Errorf("unable to ensure index '%s': %s", index.Name, err)
+			return fmt.Errorf("unable to ensure index '%s': %w", index.Name, err)
 		}
 	}
 
@@ -118,17 +123,17 @@ func EnsureIndex(manipulator manipulate.Manipulator, identity elemental.Identity
 						{Name: "collMod", Value: collection.Name},
 						{Name: "index", Value: bson.M{"name": index.Name, "expireAfterSeconds": int(index.ExpireAfter.Seconds())}},
 					}, nil); err != nil {
-						return fmt.Errorf("cannot update TTL index: %s", err)
+						return fmt.Errorf("cannot update TTL index: %w", err)
 					}
 
 				} else {
 
 					if err := collection.DropIndexName(index.Name); err != nil {
-						return fmt.Errorf("cannot delete previous index: %s", err)
+						return fmt.Errorf("cannot delete previous index: %w", err)
 					}
 
 					if err := collection.EnsureIndex(index); err != nil {
-						return fmt.Errorf("unable to ensure index after dropping old one '%s': %s", index.Name, err)
+						return fmt.Errorf("unable to ensure index after dropping old one '%s': %w", index.Name, err)
 					}
 
 				}
@@ -136,7 +141,61 @@ func EnsureIndex(manipulator manipulate.Manipulator, identity elemental.Identity
 				continue
 			}
 
-			return fmt.Errorf("unable to ensure index '%s': %s", index.Name, err)
+			return fmt.Errorf("unable to ensure index '%s': %w", index.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// EnsureIndexes calls EnsureIndex for each identity of the given map with
+// the associated indexes. This allows to keep all the index definitions of
+// a model in one place. Identities are processed in the alphabetical order
+// of their names, and the first error stops the process.
+func EnsureIndexes(manipulator manipulate.Manipulator, indexes map[elemental.Identity][]mgo.Index) error {
+
+	if _, ok := manipulator.(*mongoManipulator); !ok {
+		panic("you can only pass a mongo manipulator to EnsureIndexes")
+	}
+
+	identities := make([]elemental.Identity, 0, len(indexes))
+	for identity := range indexes {
+		identities = append(identities, identity)
+	}
+
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Name < identities[j].Name })
+
+	for _, identity := range identities {
+		if err := EnsureIndex(manipulator, identity, indexes[identity]...); err != nil {
+			return fmt.Errorf("unable to ensure indexes of '%s': %w", identity.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// EnsureTTLIndex ensures the index needed to expire the objects of the given
+// identities created with ContextOptionTTL exists. The objects are then deleted
+// by mongo once their expiration date passed. Note that mongo only removes expired
+// documents every 60 seconds, so they can still be retrieved for up to a minute
+// or so after they expired.
+func EnsureTTLIndex(manipulator manipulate.Manipulator, identities ...elemental.Identity) error {
+
+	if _, ok := manipulator.(*mongoManipulator); !ok {
+		panic("you can only pass a mongo manipulator to EnsureTTLIndex")
+	}
+
+	for _, identity := range identities {
+		if err := EnsureIndex(
+			manipulator,
+			identity,
+			mgo.Index{
+				Name:        "index_" + identity.Name + "_ttl",
+				Key:         []string{ExpirationField},
+				ExpireAfter: time.Second,
+			},
+		); err != nil {
+			return err
 		}
 	}
 
@@ -211,12 +270,20 @@ func SetConsistencyMode(manipulator manipulate.Manipulator, mode mgo.Mode, refre
 }
 
 // RunQuery runs a function that must run a mongodb operation.
-// It will retry in case of failure. This is an advanced helper can
-// be used when you get a session from using GetDatabase().
+// It will retry in case of failure, until the context deadline passes or
+// the maximum number of retries set by ContextOptionMaxRetries is reached.
+// This is an advanced helper can be used when you get a session from using GetDatabase().
 func RunQuery(mctx manipulate.Context, operationFunc func() (interface{}, error), baseRetryInfo RetryInfo) (interface{}, error) {
 
 	var try int
 
+	maxRetries := -1
+	if o, ok := mctx.(opaquer); ok {
+		if n, ok := o.Opaque()[opaqueKeyMaxRetries].(int); ok {
+			maxRetries = n
+		}
+	}
+
 	for {
 
 		out, err := operationFunc()
@@ -229,6 +296,10 @@ func RunQuery(mctx manipulate.Context, operationFunc func() (interface{}, error)
 			return out, err
 		}
 
+		if maxRetries >= 0 && try >= maxRetries {
+			return out, err
+		}
+
 		baseRetryInfo.try = try
 		baseRetryInfo.err = err
 		baseRetryInfo.mctx = mctx
@@ -250,7 +321,11 @@ func RunQuery(mctx manipulate.Context, operationFunc func() (interface{}, error)
 		}
 
 		deadline, _ := mctx.Context().Deadline()
-		time.Sleep(backoff.NextWithCurve(try, deadline, defaultBackoffCurve))
+		wait := backoff.NextWithCurve(try, deadline, defaultBackoffCurve)
+		if !baseRetryInfo.disableJitter {
+			wait = backoff.Jitter(wait)
+		}
+		time.Sleep(wait)
 		try++
 	}
 }
@@ -290,3 +365,738 @@ func IsMongoManipulator(manipulator manipulate.Manipulator) bool {
 
 	return ok
 }
+
+// Increment increments the given counter attribute by inc in all the objects
+// of the given identity matching the filter of the given manipulate.Context.
+// It returns a manipulate.ErrObjectNotFound if no object matches the filter.
+// Calling it without any filter requires ContextOptionAllowIncrementAll.
+func Increment(manipulator manipulate.Manipulator, mctx manipulate.Context, identity elemental.Identity, counter string, inc int) error {
+
+	m, ok := manipulator.(*mongoManipulator)
+	if !ok {
+		panic("you can only pass a mongo manipulator to Increment")
+	}
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.increment.%s", identity.Category))
+	defer sp.Finish()
+
+	if f := mctx.Filter(); f == nil || len(f.Operators()) == 0 {
+		if _, ok := mctx.(opaquer).Opaque()[opaqueKeyAllowIncrementAll]; !ok {
+			err := manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("refusing to increment all objects without ContextOptionAllowIncrementAll")}
+			sp.SetTag("error", true)
+			sp.LogFields(log.Error(err))
+			return err
+		}
+	}
+
+	c, close := m.makeSession(identity, mctx)
+	defer close()
+
+	filter, err := m.makeFilterForMany(mctx, identity)
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	ops := makeIncrementOperations(bsonFieldName(counter, m.attributeSpecifiers[identity]), inc)
+
+	out, err := RunQuery(
+		mctx,
+		func() (interface{}, error) { return c.UpdateAll(filter, ops) },
+		RetryInfo{
+			Operation:        elemental.OperationUpdate,
+			Identity:         identity,
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	)
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	if info, ok := out.(*mgo.ChangeInfo); !ok || info == nil || info.Matched == 0 {
+		return manipulate.ErrObjectNotFound{Err: fmt.Errorf("unable to find any object to increment")}
+	}
+
+	return nil
+}
+
+// Touch sets the updateTime attribute of the object of the given identity
+// with the given ID to the current date of the server, without modifying any
+// other attribute. The attribute is stored in the updatetime field, unless
+// it is translated to another name with OptionTranslateKeysFromModelManager.
+// It returns a manipulate.ErrObjectNotFound if the object does not exist.
+// The forced read filter is applied, but not the sharding filter.
+func Touch(manipulator manipulate.Manipulator, mctx manipulate.Context, identity elemental.Identity, id string) error {
+
+	m, ok := manipulator.(*mongoManipulator)
+	if !ok {
+		panic("you can only pass a mongo manipulator to Touch")
+	}
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.touch.%s", identity.Category))
+	sp.LogFields(log.String("object_id", id))
+	defer sp.Finish()
+
+	c, close := m.makeSession(identity, mctx)
+	defer close()
+
+	var filter bson.D
+	if oid, ok := objectid.Parse(id); ok {
+		filter = append(filter, bson.DocElem{Name: "_id", Value: oid})
+	} else {
+		filter = append(filter, bson.DocElem{Name: "_id", Value: id})
+	}
+
+	if m.forcedReadFilter != nil {
+		filter = bson.D{{Name: "$and", Value: []bson.D{m.forcedReadFilter, filter}}}
+	}
+
+	field := bsonFieldName("updateTime", m.attributeSpecifiers[identity])
+
+	if _, err := RunQuery(
+		mctx,
+		func() (interface{}, error) {
+			return nil, c.Update(filter, bson.M{"$currentDate": bson.M{field: true}})
+		},
+		RetryInfo{
+			Operation:        elemental.OperationUpdate,
+			Identity:         identity,
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Explain returns the query plan of the query RetrieveMany would run
+// for the given manipulate.Context and elemental.Identifiables. The query is built
+// exactly like RetrieveMany does, including ordering, pagination, fields selection,
+// sharding and forced read filters, so the plan matches the real query.
+//
+// This is a debugging tool specific to the mongo backend and is not portable
+// across manipulators.
+func Explain(manipulator manipulate.Manipulator, mctx manipulate.Context, dest elemental.Identifiables) (bson.M, error) {
+
+	m, ok := manipulator.(*mongoManipulator)
+	if !ok {
+		panic("you can only pass a mongo manipulator to Explain")
+	}
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.explain.%s", dest.Identity().Category))
+	defer sp.Finish()
+
+	if err := manipulate.ValidatePagination(mctx); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return nil, err
+	}
+
+	c, close := m.makeSession(dest.Identity(), mctx)
+	defer close()
+
+	q, _, err := m.makeRetrieveManyQuery(c, mctx, dest)
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return nil, err
+	}
+
+	out := bson.M{}
+
+	if _, err := RunQuery(
+		mctx,
+		func() (interface{}, error) { return nil, q.Explain(&out) },
+		RetryInfo{
+			Operation:        elemental.OperationRetrieveMany,
+			Identity:         dest.Identity(),
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// RunAggregation runs the given aggregation pipeline on the collection
+// storing the objects of the given identity and decodes the result into dest,
+// which must be a pointer to a slice.
+//
+// If the given manipulate.Context contains a filter, or if the manipulator
+// uses a sharder or a forced read filter, a $match stage is prepended
+// to the pipeline. The read consistency and the deadline of the context are honored
+// and the query goes through the retry logic of RunQuery.
+//
+// This is specific to the mongo backend and is not portable across manipulators.
+func RunAggregation(manipulator manipulate.Manipulator, mctx manipulate.Context, identity elemental.Identity, pipeline []bson.M, dest interface{}) error {
+
+	m, ok := manipulator.(*mongoManipulator)
+	if !ok {
+		panic("you can only pass a mongo manipulator to RunAggregation")
+	}
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.aggregate.%s", identity.Category))
+	defer sp.Finish()
+
+	c, close := m.makeSession(identity, mctx)
+	defer close()
+
+	filter, err := m.makeFilterForMany(mctx, identity)
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	stages := make([]interface{}, 0, len(pipeline)+1)
+	if len(filter) > 0 {
+		stages = append(stages, bson.M{"$match": filter})
+	}
+	for _, stage := range pipeline {
+		stages = append(stages, stage)
+	}
+
+	p := c.Pipe(stages).SetMaxTime(defaultGlobalContextTimeout)
+	if d, ok := mctx.Context().Deadline(); ok {
+		p = p.SetMaxTime(time.Until(d))
+	}
+
+	if _, err := RunQuery(
+		mctx,
+		func() (interface{}, error) { return nil, p.All(dest) },
+		RetryInfo{
+			Operation:        elemental.OperationRetrieveMany,
+			Identity:         identity,
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Distinct retrieves the distinct values of the given field for all the objects of the
+// given identity matching the filter of the given manipulate.Context and decodes
+// them into dest, which must be a pointer to a slice.
+// The field name is resolved the same way as the fields selected with
+// manipulate.ContextOptionFields.
+func Distinct(manipulator manipulate.Manipulator, mctx manipulate.Context, identity elemental.Identity, field string, dest interface{}) error {
+
+	m, ok := manipulator.(*mongoManipulator)
+	if !ok {
+		panic("you can only pass a mongo manipulator to Distinct")
+	}
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.distinct.%s", identity.Category))
+	defer sp.Finish()
+
+	c, close := m.makeSession(identity, mctx)
+	defer close()
+
+	filter, err := m.makeFilterForMany(mctx, identity)
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	key := bsonFieldName(field, m.attributeSpecifiers[identity])
+
+	q := c.Find(filter).SetMaxTime(defaultGlobalContextTimeout)
+	if d, ok := mctx.Context().Deadline(); ok {
+		q = q.SetMaxTime(time.Until(d))
+	}
+
+	if _, err := RunQuery(
+		mctx,
+		func() (interface{}, error) { return nil, q.Distinct(key, dest) },
+		RetryInfo{
+			Operation:        elemental.OperationRetrieveMany,
+			Identity:         identity,
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// BatchCreate creates all the given objects in a single round trip using
+// the mongo bulk API. As with Create, an identifier is assigned to each
+// object and the finalizer, the sharder and the attribute encrypter are
+// applied. All the objects must be of the same identity.
+//
+// By default, the bulk is unordered: mongo tries to insert every object
+// even if some of them fail. Use ContextOptionOrderedBulk to stop at the
+// first failure, like creating the objects one by one would.
+//
+// ContextOptionTTL is honored like in Create. When the bulk is retried
+// after a communication error, the documents already written by the
+// previous attempt are not reported as duplicates. The objects are always
+// returned decrypted, even if the bulk fails.
+func BatchCreate(manipulator manipulate.Manipulator, mctx manipulate.Context, objects ...elemental.Identifiable) (err error) {
+
+	m, ok := manipulator.(*mongoManipulator)
+	if !ok {
+		panic("you can only pass a mongo manipulator to BatchCreate")
+	}
+
+	if len(objects) == 0 {
+		return nil
+	}
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	identity := objects[0].Identity()
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.batch_create.%s", identity.Category))
+	sp.LogFields(log.Int("objects", len(objects)))
+	defer sp.Finish()
+
+	c, close := m.makeSession(identity, mctx)
+	defer close()
+
+	finalizer := mctx.Finalizer()
+	docs := make([]interface{}, len(objects))
+	encryptables := make([]elemental.AttributeEncryptable, 0, len(objects))
+
+	decrypt := func() (derr error) {
+		for _, a := range encryptables {
+			if err := a.DecryptAttributes(m.attributeEncrypter); err != nil && derr == nil {
+				derr = manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("batch create: unable to decrypt attributes: %w", err)}
+			}
+		}
+		encryptables = nil
+		return derr
+	}
+
+	// Whatever happens, the caller gets its objects back decrypted.
+	defer func() {
+		if derr := decrypt(); derr != nil && err == nil {
+			err = derr
+		}
+	}()
+
+	var expiration time.Time
+	if ttl, _ := mctx.(opaquer).Opaque()[opaqueKeyTTL].(time.Duration); ttl > 0 {
+		expiration = time.Now().Add(ttl)
+	}
+
+	for i, object := range objects {
+
+		if !object.Identity().IsEqual(identity) {
+			return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("batch create: all objects must be of identity '%s'", identity.Name)}
+		}
+
+		object.SetIdentifier(bson.NewObjectId().Hex())
+
+		if finalizer != nil {
+			if err := finalizer(object); err != nil {
+				sp.SetTag("error", true)
+				sp.LogFields(log.Error(err))
+				return err
+			}
+		}
+
+		if m.sharder != nil {
+			if err := m.sharder.Shard(m, mctx, object); err != nil {
+				return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("unable to execute sharder.Shard: %w", err)}
+			}
+		}
+
+		if m.attributeEncrypter != nil {
+			if a, ok := object.(elemental.AttributeEncryptable); ok {
+				if err := a.EncryptAttributes(m.attributeEncrypter); err != nil {
+					return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("batch create: unable to encrypt attributes: %w", err)}
+				}
+				encryptables = append(encryptables, a)
+			}
+		}
+
+		docs[i] = object
+
+		if !expiration.IsZero() {
+			d, err := makeExpiringDocument(object, expiration)
+			if err != nil {
+				return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("batch create: unable to set expiration: %w", err)}
+			}
+			docs[i] = d
+		}
+	}
+
+	_, ordered := mctx.(opaquer).Opaque()[opaqueKeyOrderedBulk]
+
+	var attempted bool
+
+	if _, err := RunQuery(
+		mctx,
+		func() (interface{}, error) {
+
+			retrying := attempted
+			attempted = true
+
+			pending := docs
+			for {
+				bulk := c.Bulk()
+				if !ordered {
+					bulk.Unordered()
+				}
+				bulk.Insert(pending...)
+
+				_, err := bulk.Run()
+				if err == nil {
+					return nil, nil
+				}
+
+				berr, ok := err.(*mgo.BulkError)
+				if !ok || !retrying {
+					return nil, err
+				}
+
+				offset, err := resumeBulkInsert(berr.Cases(), ordered, len(pending))
+				if err != nil {
+					return nil, err
+				}
+				if offset < 0 {
+					return nil, nil
+				}
+
+				pending = pending[offset:]
+			}
+		},
+		RetryInfo{
+			Operation:        elemental.OperationCreate,
+			Identity:         identity,
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	if err := decrypt(); err != nil {
+		return err
+	}
+
+	if m.sharder != nil {
+		for _, object := range objects {
+			if err := m.sharder.OnShardedWrite(m, mctx, elemental.OperationCreate, object); err != nil {
+				return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("unable to execute sharder.OnShardedWrite on create: %w", err)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// BatchRetrieve retrieves all the given objects in a single round trip using
+// a $in query on their identifiers, and populates each object with the matching
+// document. As with Retrieve, the filter and the fields of the given
+// manipulate.Context, the sharder, the forced read filter and the attribute
+// encrypter are applied. All the objects must be of the same identity.
+//
+// The objects that are found are populated even if some others are not,
+// in which case a manipulate.ErrObjectNotFound listing the missing
+// identifiers is returned. A single object is retrieved using Retrieve.
+func BatchRetrieve(manipulator manipulate.Manipulator, mctx manipulate.Context, objects ...elemental.Identifiable) error {
+
+	m, ok := manipulator.(*mongoManipulator)
+	if !ok {
+		panic("you can only pass a mongo manipulator to BatchRetrieve")
+	}
+
+	if len(objects) == 0 {
+		return nil
+	}
+
+	if len(objects) == 1 {
+		return m.Retrieve(mctx, objects[0])
+	}
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	identity := objects[0].Identity()
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.batch_retrieve.%s", identity.Category))
+	sp.LogFields(log.Int("objects", len(objects)))
+	defer sp.Finish()
+
+	ids := make([]interface{}, len(objects))
+	for i, object := range objects {
+
+		if !object.Identity().IsEqual(identity) {
+			return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("batch retrieve: all objects must be of identity '%s'", identity.Name)}
+		}
+
+		if oid, ok := objectid.Parse(object.Identifier()); ok {
+			ids[i] = oid
+		} else {
+			ids[i] = object.Identifier()
+		}
+	}
+
+	var attrSpec elemental.AttributeSpecifiable
+	if m.attributeSpecifiers != nil {
+		attrSpec = m.attributeSpecifiers[identity]
+	}
+
+	filter := bson.D{}
+
+	if f := mctx.Filter(); f != nil {
+		var opts []CompilerOption
+		if attrSpec != nil {
+			opts = append(opts, CompilerOptionTranslateKeysFromSpec(attrSpec))
+		}
+		filter = CompileFilter(f, opts...)
+	}
+
+	filter = append(filter, bson.DocElem{Name: "_id", Value: bson.D{{Name: "$in", Value: ids}}})
+
+	if m.sharder != nil {
+		sq, err := m.sharder.FilterMany(m, mctx, identity)
+		if err != nil {
+			return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("cannot compute sharding filter: %w", err)}
+		}
+		if sq != nil {
+			filter = bson.D{{Name: "$and", Value: []bson.D{sq, filter}}}
+		}
+	}
+
+	if m.forcedReadFilter != nil {
+		filter = bson.D{{Name: "$and", Value: []bson.D{m.forcedReadFilter, filter}}}
+	}
+
+	c, close := m.makeSession(identity, mctx)
+	defer close()
+
+	q := c.Find(filter)
+	if sels := makeFieldsSelector(mctx.Fields(), attrSpec); sels != nil {
+		q = q.Select(sels)
+	}
+
+	q = q.SetMaxTime(defaultGlobalContextTimeout)
+	if d, ok := mctx.Context().Deadline(); ok {
+		q = q.SetMaxTime(time.Until(d))
+	}
+
+	var docs []bson.Raw
+
+	if _, err := RunQuery(
+		mctx,
+		func() (interface{}, error) {
+			if exp := explainIfNeeded(q, filter, identity, elemental.OperationRetrieve, m.explain); exp != nil {
+				if err := exp(); err != nil {
+					return nil, manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("batch retrieve: unable to explain: %w", err)}
+				}
+			}
+			docs = nil
+			return nil, iterAll(mctx.Context(), q.Iter(), &docs)
+		},
+		RetryInfo{
+			Operation:        elemental.OperationRetrieve,
+			Identity:         identity,
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	found, missing, err := scatterDocuments(docs, objects)
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	for _, object := range found {
+
+		// backport all default values that are empty.
+		if a, ok := object.(elemental.AttributeSpecifiable); ok {
+			elemental.ResetDefaultForZeroValues(a)
+		}
+
+		if m.attributeEncrypter != nil {
+			if a, ok := object.(elemental.AttributeEncryptable); ok {
+				if err := a.DecryptAttributes(m.attributeEncrypter); err != nil {
+					return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("batch retrieve: unable to decrypt attributes: %w", err)}
+				}
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return manipulate.ErrObjectNotFound{Err: fmt.Errorf("cannot find the objects with the following IDs: %s", strings.Join(missing, ", "))}
+	}
+
+	return nil
+}
+
+// TextSearch retrieves the objects of the identity of dest matching the given
+// phrase using a $text query, along with the filter of the given manipulate.Context.
+// Unless an order is set in the context, the objects are sorted by relevance.
+// The limit, the pagination and the fields selection of the context are honored,
+// and the lazy fields are excluded like with RetrieveMany.
+//
+// A text index must exist on the collection for the query to succeed. It can be
+// created using EnsureIndex with an mgo.Index having keys like "$text:name".
+// See https://docs.mongodb.com/manual/core/index-text/.
+//
+// This is specific to the mongo backend and is not portable across manipulators:
+// other manipulators get a manipulate.ErrNotImplemented. An empty phrase returns
+// a manipulate.ErrCannotBuildQuery.
+func TextSearch(manipulator manipulate.Manipulator, mctx manipulate.Context, phrase string, dest elemental.Identifiables) error {
+
+	m, ok := manipulator.(*mongoManipulator)
+	if !ok {
+		return manipulate.ErrNotImplemented{Err: fmt.Errorf("manipulator %T does not support text search", manipulator)}
+	}
+
+	if phrase == "" {
+		return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("text search: phrase must not be empty")}
+	}
+
+	if mctx == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
+		defer cancel()
+		mctx = manipulate.NewContext(ctx)
+	}
+
+	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.text_search.%s", dest.Identity().Category))
+	defer sp.Finish()
+
+	if err := manipulate.ValidatePagination(mctx); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	c, close := m.makeSession(dest.Identity(), mctx)
+	defer close()
+
+	filter, err := m.makeFilterForMany(mctx, dest.Identity())
+	if err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	filter = bson.D{{Name: "$and", Value: []bson.D{makeTextFilter(phrase), filter}}}
+
+	q := c.Find(filter)
+
+	if limit := mctx.Limit(); limit > 0 {
+		q = q.Limit(limit)
+	} else if pageSize := mctx.PageSize(); pageSize > 0 {
+		q = q.Limit(pageSize)
+	}
+
+	if p := mctx.Page(); p > 0 {
+		q = q.Skip((p - 1) * mctx.PageSize())
+	}
+
+	attrSpec := m.attributeSpecifiers[dest.Identity()]
+
+	sels := makeFieldsSelector(mctx.Fields(), attrSpec)
+	if _, ok := mctx.(opaquer).Opaque()[opaqueKeyIncludeLazy]; sels == nil && !ok {
+		sels = makeExclusionSelector(m.lazyFields[dest.Identity()], attrSpec)
+	}
+
+	if order := applyOrdering(mctx.Order(), attrSpec); len(order) > 0 {
+		q = q.Sort(order...)
+	} else {
+		if sels == nil {
+			sels = bson.M{}
+		}
+		sels[textScoreField] = bson.M{"$meta": "textScore"}
+		q = q.Sort("$textScore:" + textScoreField)
+	}
+
+	if sels != nil {
+		q = q.Select(sels)
+	}
+
+	q = q.SetMaxTime(defaultGlobalContextTimeout)
+	if d, ok := mctx.Context().Deadline(); ok {
+		q = q.SetMaxTime(time.Until(d))
+	}
+
+	if _, err := RunQuery(
+		mctx,
+		func() (interface{}, error) { return nil, iterAll(mctx.Context(), q.Iter(), dest) },
+		RetryInfo{
+			Operation:        elemental.OperationRetrieveMany,
+			Identity:         dest.Identity(),
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/globalsign/mgo/bson"
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
 	"go.aporeto.io/manipulate"
 	"go.aporeto.io/manipulate/maniptest"
 )
@@ -76,6 +77,48 @@ func TestDoesDatabaseExists(t *testing.T) {
 	})
 }
 
+func TestEnsureTTLIndex(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call EnsureTTLIndex", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = EnsureTTLIndex(m, elemental.MakeIdentity("a", "a")) }, ShouldPanicWith, "you can only pass a mongo manipulator to EnsureTTLIndex")
+			})
+		})
+	})
+}
+
+func TestTouch(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call Touch", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = Touch(m, nil, elemental.MakeIdentity("a", "a"), "x") }, ShouldPanicWith, "you can only pass a mongo manipulator to Touch")
+			})
+		})
+	})
+}
+
+func TestExplain(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call Explain", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _, _ = Explain(m, nil, &testmodel.ListsList{}) }, ShouldPanicWith, "you can only pass a mongo manipulator to Explain")
+			})
+		})
+	})
+}
+
 func TestDropDatabase(t *testing.T) {
 
 	Convey("Given I a test manipulator", t, func() {
@@ -118,6 +161,20 @@ func TestEnsureIndex(t *testing.T) {
 	})
 }
 
+func TestEnsureIndexes(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call EnsureIndexes", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = EnsureIndexes(m, nil) }, ShouldPanicWith, "you can only pass a mongo manipulator to EnsureIndexes")
+			})
+		})
+	})
+}
+
 func TestDeleteIndex(t *testing.T) {
 
 	Convey("Given I a test manipulator", t, func() {
@@ -174,6 +231,139 @@ func TestSetConsistencyMode(t *testing.T) {
 	})
 }
 
+func TestIncrement(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call Increment", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = Increment(m, nil, elemental.MakeIdentity("a", "a"), "counter", 1) }, ShouldPanicWith, "you can only pass a mongo manipulator to Increment")
+			})
+		})
+	})
+
+	Convey("Given I a mongo manipulator", t, func() {
+
+		m := &mongoManipulator{}
+
+		Convey("When I call Increment without filter", func() {
+
+			err := Increment(m, nil, elemental.MakeIdentity("a", "a"), "counter", 1)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotBuildQuery{})
+				So(err.Error(), ShouldEqual, "Unable to build query: refusing to increment all objects without ContextOptionAllowIncrementAll")
+			})
+		})
+
+		Convey("When I call Increment with an empty filter", func() {
+
+			err := Increment(
+				m,
+				manipulate.NewContext(context.Background(), manipulate.ContextOptionFilter(elemental.NewFilterComposer().Done())),
+				elemental.MakeIdentity("a", "a"),
+				"counter",
+				1,
+			)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotBuildQuery{})
+				So(err.Error(), ShouldEqual, "Unable to build query: refusing to increment all objects without ContextOptionAllowIncrementAll")
+			})
+		})
+	})
+}
+
+func TestRunAggregation(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call RunAggregation", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = RunAggregation(m, nil, elemental.MakeIdentity("a", "a"), nil, nil) }, ShouldPanicWith, "you can only pass a mongo manipulator to RunAggregation")
+			})
+		})
+	})
+}
+
+func TestDistinct(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call Distinct", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = Distinct(m, nil, elemental.MakeIdentity("a", "a"), "name", nil) }, ShouldPanicWith, "you can only pass a mongo manipulator to Distinct")
+			})
+		})
+	})
+}
+
+func TestBatchCreate(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call BatchCreate", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = BatchCreate(m, nil) }, ShouldPanicWith, "you can only pass a mongo manipulator to BatchCreate")
+			})
+		})
+	})
+}
+
+func TestBatchRetrieve(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call BatchRetrieve", func() {
+			Convey("Then it should panic", func() {
+				So(func() { _ = BatchRetrieve(m, nil) }, ShouldPanicWith, "you can only pass a mongo manipulator to BatchRetrieve")
+			})
+		})
+	})
+}
+
+func TestTextSearch(t *testing.T) {
+
+	Convey("Given I a test manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		Convey("When I call TextSearch", func() {
+
+			err := TextSearch(m, nil, "hello", nil)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrNotImplemented{})
+			})
+		})
+	})
+
+	Convey("Given I a mongo manipulator", t, func() {
+
+		m := &mongoManipulator{}
+
+		Convey("When I call TextSearch with an empty phrase", func() {
+
+			err := TextSearch(m, nil, "", nil)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldHaveSameTypeAs, manipulate.ErrCannotBuildQuery{})
+				So(err.Error(), ShouldEqual, "Unable to build query: text search: phrase must not be empty")
+			})
+		})
+	})
+}
+
 func TestRunQuery(t *testing.T) {
 
 	testIdentity := elemental.MakeIdentity("test", "tests")
@@ -368,6 +558,86 @@ func TestRunQuery(t *testing.T) {
 		})
 	})
 
+	Convey("Given I have query function that returns a write conflict and works at second try", t, func() {
+
+		var try int
+		var lastErr error
+
+		rf := func(i manipulate.RetryInfo) error {
+			try = i.Try()
+			lastErr = i.Err()
+			return nil
+		}
+
+		f := func() (interface{}, error) {
+			if try == 1 {
+				return "hello", nil
+			}
+			return nil, &mgo.QueryError{Code: 112, Message: "WriteConflict"}
+		}
+
+		Convey("When I call RunQuery", func() {
+
+			out, err := RunQuery(
+				manipulate.NewContext(
+					context.Background(),
+					manipulate.ContextOptionRetryFunc(rf),
+				),
+				f,
+				RetryInfo{
+					Operation: elemental.OperationUpdate,
+					Identity:  testIdentity,
+				},
+			)
+
+			Convey("Then the query should have been retried", func() {
+				So(err, ShouldBeNil)
+				So(out, ShouldResemble, "hello")
+				So(try, ShouldEqual, 1)
+				So(lastErr.Error(), ShouldEqual, "Cannot communicate: WriteConflict")
+			})
+		})
+	})
+
+	Convey("Given I have query function that always returns a net.Error and a max number of retries", t, func() {
+
+		var calls int
+		var tries []int
+
+		rf := func(i manipulate.RetryInfo) error {
+			tries = append(tries, i.Try())
+			return nil
+		}
+
+		f := func() (interface{}, error) {
+			calls++
+			return nil, &net.OpError{Err: fmt.Errorf("hello")}
+		}
+
+		Convey("When I call RunQuery", func() {
+
+			_, err := RunQuery(
+				manipulate.NewContext(
+					context.Background(),
+					manipulate.ContextOptionRetryFunc(rf),
+					ContextOptionMaxRetries(2),
+				),
+				f,
+				RetryInfo{
+					Operation: elemental.OperationCreate,
+					Identity:  testIdentity,
+				},
+			)
+
+			Convey("Then the last error should be returned once the retries are exhausted", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Cannot communicate: : hello")
+				So(calls, ShouldEqual, 3)
+				So(tries, ShouldResemble, []int{0, 1})
+			})
+		})
+	})
+
 	Convey("Given I have query function that returns a net.Error and and a retry func that returns an error", t, func() {
 
 		f := func() (interface{}, error) {
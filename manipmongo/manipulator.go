@@ -16,6 +16,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/globalsign/mgo"
@@ -31,14 +32,17 @@ const defaultGlobalContextTimeout = 60 * time.Second
 
 // MongoStore represents a MongoDB session.
 type mongoManipulator struct {
-	rootSession         *mgo.Session
-	dbName              string
-	sharder             Sharder
-	defaultRetryFunc    manipulate.RetryFunc
-	forcedReadFilter    bson.D
-	attributeEncrypter  elemental.AttributeEncrypter
-	explain             map[elemental.Identity]map[elemental.Operation]struct{}
-	attributeSpecifiers map[elemental.Identity]elemental.AttributeSpecifiable
+	rootSession          *mgo.Session
+	dbName               string
+	sharder              Sharder
+	defaultRetryFunc     manipulate.RetryFunc
+	forcedReadFilter     bson.D
+	attributeEncrypter   elemental.AttributeEncrypter
+	explain              map[elemental.Identity]map[elemental.Operation]struct{}
+	attributeSpecifiers  map[elemental.Identity]elemental.AttributeSpecifiable
+	lazyFields           map[elemental.Identity][]string
+	disableBackoffJitter bool
+	metricsRecorder      manipulate.MetricsRecorder
 }
 
 // New returns a new manipulator backed by MongoDB.
@@ -51,7 +55,7 @@ func New(url string, db string, options ...Option) (manipulate.TransactionalMani
 
 	dialInfo, err := mgo.ParseURL(url)
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse mongo url '%s': %s", url, err)
+		return nil, fmt.Errorf("cannot parse mongo url '%s': %w", url, err)
 	}
 
 	dialInfo.Database = db
@@ -80,26 +84,34 @@ func New(url string, db string, options ...Option) (manipulate.TransactionalMani
 
 	session, err := mgo.DialWithInfo(dialInfo)
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect to mongo url '%s': %s", url, err)
+		return nil, fmt.Errorf("cannot connect to mongo url '%s': %w", url, err)
 	}
 
 	session.SetSocketTimeout(cfg.socketTimeout)
+	if cfg.syncTimeout > 0 {
+		session.SetSyncTimeout(cfg.syncTimeout)
+	}
 	session.SetMode(convertReadConsistency(cfg.readConsistency), true)
 	session.SetSafe(convertWriteConsistency(cfg.writeConsistency))
 
 	return &mongoManipulator{
-		dbName:              db,
-		rootSession:         session,
-		sharder:             cfg.sharder,
-		defaultRetryFunc:    cfg.defaultRetryFunc,
-		forcedReadFilter:    cfg.forcedReadFilter,
-		attributeEncrypter:  cfg.attributeEncrypter,
-		explain:             cfg.explain,
-		attributeSpecifiers: cfg.attributeSpecifiers,
+		dbName:               db,
+		rootSession:          session,
+		sharder:              cfg.sharder,
+		defaultRetryFunc:     cfg.defaultRetryFunc,
+		forcedReadFilter:     cfg.forcedReadFilter,
+		attributeEncrypter:   cfg.attributeEncrypter,
+		explain:              cfg.explain,
+		attributeSpecifiers:  cfg.attributeSpecifiers,
+		lazyFields:           cfg.lazyFields,
+		disableBackoffJitter: cfg.disableBackoffJitter,
+		metricsRecorder:      cfg.metricsRecorder,
 	}, nil
 }
 
-func (m *mongoManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.Identifiables) error {
+func (m *mongoManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.Identifiables) (err error) {
+
+	defer m.recordOperation(elemental.OperationRetrieveMany, dest.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
@@ -110,99 +122,18 @@ func (m *mongoManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.
 	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.retrieve_many.%s", dest.Identity().Category))
 	defer sp.Finish()
 
-	c, close := m.makeSession(dest.Identity(), mctx.ReadConsistency(), mctx.WriteConsistency())
-	defer close()
-
-	var attrSpec elemental.AttributeSpecifiable
-	if m.attributeSpecifiers != nil {
-		attrSpec = m.attributeSpecifiers[dest.Identity()]
-	}
-
-	var order []string
-	if o := mctx.Order(); len(o) > 0 {
-		order = applyOrdering(o, attrSpec)
-	} else if orderer, ok := dest.(elemental.DefaultOrderer); ok {
-		order = applyOrdering(orderer.DefaultOrder(), attrSpec)
-	}
-
-	// Filtering
-	filter := bson.D{}
-	if f := mctx.Filter(); f != nil {
-		var opts []CompilerOption
-		if attrSpec != nil {
-			opts = append(opts, CompilerOptionTranslateKeysFromSpec(attrSpec))
-		}
-		filter = CompileFilter(f, opts...)
-	}
-
-	var ands []bson.D
-
-	if m.sharder != nil {
-		sq, err := m.sharder.FilterMany(m, mctx, dest.Identity())
-		if err != nil {
-			return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("cannot compute sharding filter: %w", err)}
-		}
-		if sq != nil {
-			ands = append(ands, sq)
-		}
-	}
-
-	if m.forcedReadFilter != nil {
-		ands = append(ands, m.forcedReadFilter)
-	}
-
-	if after := mctx.After(); after != "" {
-
-		if len(order) > 1 {
-			return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("cannot use multiple ordering fields when using 'after'")}
-		}
-
-		var o string
-		if len(order) == 1 {
-			o = order[0]
-		}
-
-		f, err := prepareNextFilter(c, o, after)
-		if err != nil {
-			return err
-		}
-
-		ands = append(ands, f)
-	}
-
-	if len(ands) > 0 {
-		filter = bson.D{{Name: "$and", Value: append(ands, filter)}}
-	}
-
-	// Query building
-	q := c.Find(filter)
-
-	// limiting
-	if limit := mctx.Limit(); limit > 0 {
-		q = q.Limit(limit)
-	} else if pageSize := mctx.PageSize(); pageSize > 0 {
-		q = q.Limit(pageSize)
-	}
-
-	// Old pagination
-	if p := mctx.Page(); p > 0 {
-		q = q.Skip((p - 1) * mctx.PageSize())
-	}
-
-	// Ordering
-	if len(order) > 0 {
-		q = q.Sort(order...)
+	if err := manipulate.ValidatePagination(mctx); err != nil {
+		sp.SetTag("error", true)
+		sp.LogFields(log.Error(err))
+		return err
 	}
 
-	// Fields selection
-	if sels := makeFieldsSelector(mctx.Fields(), attrSpec); sels != nil {
-		q = q.Select(sels)
-	}
+	c, close := m.makeSession(dest.Identity(), mctx)
+	defer close()
 
-	// Query timing limiting
-	q = q.SetMaxTime(defaultGlobalContextTimeout)
-	if d, ok := mctx.Context().Deadline(); ok {
-		q = q.SetMaxTime(time.Until(d))
+	q, filter, err := m.makeRetrieveManyQuery(c, mctx, dest)
+	if err != nil {
+		return err
 	}
 
 	if _, err := RunQuery(
@@ -213,12 +144,13 @@ func (m *mongoManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.
 					return nil, manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("retrievemany: unable to explain: %w", err)}
 				}
 			}
-			return nil, q.All(dest)
+			return nil, iterAll(mctx.Context(), q.Iter(), dest)
 		},
 		RetryInfo{
 			Operation:        elemental.OperationRetrieveMany,
 			Identity:         dest.Identity(),
 			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
 		},
 	); err != nil {
 		sp.SetTag("error", true)
@@ -257,7 +189,9 @@ func (m *mongoManipulator) RetrieveMany(mctx manipulate.Context, dest elemental.
 	return nil
 }
 
-func (m *mongoManipulator) Retrieve(mctx manipulate.Context, object elemental.Identifiable) error {
+func (m *mongoManipulator) Retrieve(mctx manipulate.Context, object elemental.Identifiable) (err error) {
+
+	defer m.recordOperation(elemental.OperationRetrieve, object.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
@@ -265,7 +199,7 @@ func (m *mongoManipulator) Retrieve(mctx manipulate.Context, object elemental.Id
 		mctx = manipulate.NewContext(ctx)
 	}
 
-	c, close := m.makeSession(object.Identity(), mctx.ReadConsistency(), mctx.WriteConsistency())
+	c, close := m.makeSession(object.Identity(), mctx)
 	defer close()
 
 	var attrSpec elemental.AttributeSpecifiable
@@ -331,6 +265,7 @@ func (m *mongoManipulator) Retrieve(mctx manipulate.Context, object elemental.Id
 			Operation:        elemental.OperationRetrieve,
 			Identity:         object.Identity(),
 			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
 		},
 	); err != nil {
 		sp.SetTag("error", true)
@@ -354,7 +289,9 @@ func (m *mongoManipulator) Retrieve(mctx manipulate.Context, object elemental.Id
 	return nil
 }
 
-func (m *mongoManipulator) Create(mctx manipulate.Context, object elemental.Identifiable) error {
+func (m *mongoManipulator) Create(mctx manipulate.Context, object elemental.Identifiable) (err error) {
+
+	defer m.recordOperation(elemental.OperationCreate, object.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
@@ -362,7 +299,7 @@ func (m *mongoManipulator) Create(mctx manipulate.Context, object elemental.Iden
 		mctx = manipulate.NewContext(ctx)
 	}
 
-	c, close := m.makeSession(object.Identity(), mctx.ReadConsistency(), mctx.WriteConsistency())
+	c, close := m.makeSession(object.Identity(), mctx)
 	defer close()
 
 	oid := bson.NewObjectId()
@@ -396,6 +333,8 @@ func (m *mongoManipulator) Create(mctx manipulate.Context, object elemental.Iden
 		}
 	}
 
+	ttl, _ := mctx.(opaquer).Opaque()[opaqueKeyTTL].(time.Duration)
+
 	if operations, upsert := mctx.(opaquer).Opaque()[opaqueKeyUpsert]; upsert {
 
 		object.SetIdentifier("")
@@ -405,26 +344,7 @@ func (m *mongoManipulator) Create(mctx manipulate.Context, object elemental.Iden
 			return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("upsert operations must be of type bson.M")}
 		}
 
-		baseOps := bson.M{
-			"$set":         object,
-			"$setOnInsert": bson.M{"_id": oid},
-		}
-
-		if len(ops) > 0 {
-
-			if soi, ok := ops["$setOnInsert"]; ok {
-				for k, v := range soi.(bson.M) {
-					baseOps["$setOnInsert"].(bson.M)[k] = v
-				}
-			}
-
-			for k, v := range ops {
-				if k == "$setOnInsert" {
-					continue
-				}
-				baseOps[k] = v
-			}
-		}
+		baseOps := makeUpsertOperations(object, oid, ops, ttl)
 
 		filter := CompileFilter(mctx.Filter())
 		if m.sharder != nil {
@@ -444,6 +364,7 @@ func (m *mongoManipulator) Create(mctx manipulate.Context, object elemental.Iden
 				Operation:        elemental.OperationCreate,
 				Identity:         object.Identity(),
 				defaultRetryFunc: m.defaultRetryFunc,
+				disableJitter:    m.disableBackoffJitter,
 			},
 		)
 		if err != nil {
@@ -460,13 +381,24 @@ func (m *mongoManipulator) Create(mctx manipulate.Context, object elemental.Iden
 		}
 
 	} else {
+
+		var doc interface{} = object
+		if ttl > 0 {
+			d, err := makeExpiringDocument(object, time.Now().Add(ttl))
+			if err != nil {
+				return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("create: unable to set expiration: %w", err)}
+			}
+			doc = d
+		}
+
 		_, err := RunQuery(
 			mctx,
-			func() (interface{}, error) { return nil, c.Insert(object) },
+			func() (interface{}, error) { return nil, c.Insert(doc) },
 			RetryInfo{
 				Operation:        elemental.OperationCreate,
 				Identity:         object.Identity(),
 				defaultRetryFunc: m.defaultRetryFunc,
+				disableJitter:    m.disableBackoffJitter,
 			},
 		)
 
@@ -492,7 +424,9 @@ func (m *mongoManipulator) Create(mctx manipulate.Context, object elemental.Iden
 	return nil
 }
 
-func (m *mongoManipulator) Update(mctx manipulate.Context, object elemental.Identifiable) error {
+func (m *mongoManipulator) Update(mctx manipulate.Context, object elemental.Identifiable) (err error) {
+
+	defer m.recordOperation(elemental.OperationUpdate, object.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
@@ -510,7 +444,7 @@ func (m *mongoManipulator) Update(mctx manipulate.Context, object elemental.Iden
 		}
 	}
 
-	c, close := m.makeSession(object.Identity(), mctx.ReadConsistency(), mctx.WriteConsistency())
+	c, close := m.makeSession(object.Identity(), mctx)
 	defer close()
 
 	var filter bson.D
@@ -519,7 +453,25 @@ func (m *mongoManipulator) Update(mctx manipulate.Context, object elemental.Iden
 	sp.LogFields(log.String("object_id", object.Identifier()))
 	defer sp.Finish()
 
-	if oid, ok := objectid.Parse(object.Identifier()); ok {
+	upsertKeys, upsert := mctx.(opaquer).Opaque()[opaqueKeyUpsertKeys].([]string)
+
+	if upsert {
+
+		selector, err := makeUpsertSelector(object, upsertKeys, m.attributeSpecifiers[object.Identity()])
+		if err != nil {
+			return manipulate.ErrCannotBuildQuery{Err: err}
+		}
+		filter = selector
+
+		// The document may be inserted, so it must
+		// get its shard keys like Create does.
+		if m.sharder != nil {
+			if err := m.sharder.Shard(m, mctx, object); err != nil {
+				return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("unable to execute sharder.Shard: %w", err)}
+			}
+		}
+
+	} else if oid, ok := objectid.Parse(object.Identifier()); ok {
 		filter = append(filter, bson.DocElem{Name: "_id", Value: oid})
 	} else {
 		filter = append(filter, bson.DocElem{Name: "_id", Value: object.Identifier()})
@@ -544,13 +496,53 @@ func (m *mongoManipulator) Update(mctx manipulate.Context, object elemental.Iden
 		}
 	}
 
-	if _, err := RunQuery(
+	var inserted bool
+
+	if upsert {
+
+		// $set cannot modify the _id of an existing document, so the
+		// identifier is cleared while the query runs, and restored if
+		// it fails.
+		id := object.Identifier()
+		object.SetIdentifier("")
+
+		ttl, _ := mctx.(opaquer).Opaque()[opaqueKeyTTL].(time.Duration)
+		ops := makeUpsertOperations(object, bson.NewObjectId(), nil, ttl)
+
+		var info *mgo.ChangeInfo
+		doc := bson.M{}
+
+		if _, err := RunQuery(
+			mctx,
+			func() (interface{}, error) {
+				var err error
+				info, err = c.Find(filter).Apply(mgo.Change{Update: ops, Upsert: true, ReturnNew: true}, &doc)
+				return nil, err
+			},
+			RetryInfo{
+				Operation:        elemental.OperationUpdate,
+				Identity:         object.Identity(),
+				defaultRetryFunc: m.defaultRetryFunc,
+				disableJitter:    m.disableBackoffJitter,
+			},
+		); err != nil {
+			object.SetIdentifier(id)
+			sp.SetTag("error", true)
+			sp.LogFields(log.Error(err))
+			return err
+		}
+
+		id, inserted = upsertResult(info, doc)
+		object.SetIdentifier(id)
+
+	} else if _, err := RunQuery(
 		mctx,
 		func() (interface{}, error) { return nil, c.Update(filter, bson.M{"$set": object}) },
 		RetryInfo{
 			Operation:        elemental.OperationUpdate,
 			Identity:         object.Identity(),
 			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
 		},
 	); err != nil {
 		sp.SetTag("error", true)
@@ -564,10 +556,18 @@ func (m *mongoManipulator) Update(mctx manipulate.Context, object elemental.Iden
 		}
 	}
 
+	if inserted && m.sharder != nil {
+		if err := m.sharder.OnShardedWrite(m, mctx, elemental.OperationCreate, object); err != nil {
+			return manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("unable to execute sharder.OnShardedWrite on upsert: %w", err)}
+		}
+	}
+
 	return nil
 }
 
-func (m *mongoManipulator) Delete(mctx manipulate.Context, object elemental.Identifiable) error {
+func (m *mongoManipulator) Delete(mctx manipulate.Context, object elemental.Identifiable) (err error) {
+
+	defer m.recordOperation(elemental.OperationDelete, object.Identity(), time.Now(), &err)
 
 	if mctx == nil {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
@@ -575,7 +575,7 @@ func (m *mongoManipulator) Delete(mctx manipulate.Context, object elemental.Iden
 		mctx = manipulate.NewContext(ctx)
 	}
 
-	c, close := m.makeSession(object.Identity(), mctx.ReadConsistency(), mctx.WriteConsistency())
+	c, close := m.makeSession(object.Identity(), mctx)
 	defer close()
 
 	var filter bson.D
@@ -611,6 +611,7 @@ func (m *mongoManipulator) Delete(mctx manipulate.Context, object elemental.Iden
 			Operation:        elemental.OperationDelete,
 			Identity:         object.Identity(),
 			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
 		},
 	); err != nil {
 		sp.SetTag("error", true)
@@ -632,7 +633,12 @@ func (m *mongoManipulator) Delete(mctx manipulate.Context, object elemental.Iden
 	return nil
 }
 
-func (m *mongoManipulator) DeleteMany(mctx manipulate.Context, identity elemental.Identity) error {
+// DeleteMany deletes all the objects matching the filter in the given
+// context and sets the number of removed objects in mctx.Count().
+// Calling it without any filter requires ContextOptionAllowDeleteAll.
+func (m *mongoManipulator) DeleteMany(mctx manipulate.Context, identity elemental.Identity) (err error) {
+
+	defer m.recordOperation(elemental.OperationDelete, identity, time.Now(), &err)
 
 	if mctx == nil {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
@@ -643,10 +649,23 @@ func (m *mongoManipulator) DeleteMany(mctx manipulate.Context, identity elementa
 	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.delete_many.%s", identity.Name))
 	defer sp.Finish()
 
-	c, close := m.makeSession(identity, mctx.ReadConsistency(), mctx.WriteConsistency())
+	if f := mctx.Filter(); f == nil || len(f.Operators()) == 0 {
+		if _, ok := mctx.(opaquer).Opaque()[opaqueKeyAllowDeleteAll]; !ok {
+			err := manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("refusing to delete all objects without ContextOptionAllowDeleteAll")}
+			sp.SetTag("error", true)
+			sp.LogFields(log.Error(err))
+			return err
+		}
+	}
+
+	c, close := m.makeSession(identity, mctx)
 	defer close()
 
-	filter := CompileFilter(mctx.Filter())
+	filter := bson.D{}
+	if f := mctx.Filter(); f != nil {
+		filter = CompileFilter(f)
+	}
+
 	if m.sharder != nil {
 		sq, err := m.sharder.FilterMany(m, mctx, identity)
 		if err != nil {
@@ -661,24 +680,32 @@ func (m *mongoManipulator) DeleteMany(mctx manipulate.Context, identity elementa
 		filter = bson.D{{Name: "$and", Value: []bson.D{m.forcedReadFilter, filter}}}
 	}
 
-	if _, err := RunQuery(
+	out, err := RunQuery(
 		mctx,
 		func() (interface{}, error) { return c.RemoveAll(filter) },
 		RetryInfo{
 			Operation:        elemental.OperationDelete, // we miss DeleteMany
 			Identity:         identity,
 			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
 		},
-	); err != nil {
+	)
+	if err != nil {
 		sp.SetTag("error", true)
 		sp.LogFields(log.Error(err))
 		return err
 	}
 
+	if info, ok := out.(*mgo.ChangeInfo); ok && info != nil {
+		mctx.SetCount(info.Removed)
+	}
+
 	return nil
 }
 
-func (m *mongoManipulator) Count(mctx manipulate.Context, identity elemental.Identity) (int, error) {
+func (m *mongoManipulator) Count(mctx manipulate.Context, identity elemental.Identity) (_ int, err error) {
+
+	defer m.recordOperation(elemental.OperationInfo, identity, time.Now(), &err)
 
 	if mctx == nil {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultGlobalContextTimeout)
@@ -686,7 +713,7 @@ func (m *mongoManipulator) Count(mctx manipulate.Context, identity elemental.Ide
 		mctx = manipulate.NewContext(ctx)
 	}
 
-	c, close := m.makeSession(identity, mctx.ReadConsistency(), mctx.WriteConsistency())
+	c, close := m.makeSession(identity, mctx)
 	defer close()
 
 	filter := bson.D{}
@@ -709,6 +736,15 @@ func (m *mongoManipulator) Count(mctx manipulate.Context, identity elemental.Ide
 		filter = bson.D{{Name: "$and", Value: []bson.D{m.forcedReadFilter, filter}}}
 	}
 
+	var attrSpec elemental.AttributeSpecifiable
+	if m.attributeSpecifiers != nil {
+		attrSpec = m.attributeSpecifiers[identity]
+	}
+
+	if gf := makeGeoFilter(mctx, attrSpec); gf != nil {
+		filter = bson.D{{Name: "$and", Value: []bson.D{gf, filter}}}
+	}
+
 	sp := tracing.StartTrace(mctx, fmt.Sprintf("manipmongo.count.%s", identity.Category))
 	defer sp.Finish()
 
@@ -732,6 +768,7 @@ func (m *mongoManipulator) Count(mctx manipulate.Context, identity elemental.Ide
 			Operation:        elemental.OperationInfo,
 			Identity:         identity,
 			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
 		},
 	)
 	if err != nil {
@@ -743,11 +780,18 @@ func (m *mongoManipulator) Count(mctx manipulate.Context, identity elemental.Ide
 	return out.(int), nil
 }
 
+// Commit does nothing. The mgo driver does not support multi-document
+// transactions, so every operation is applied as soon as it is performed,
+// regardless of the TransactionID of its context.
 func (m *mongoManipulator) Commit(id manipulate.TransactionID) error { return nil }
 
+// Abort does nothing and returns true. See Commit.
 func (m *mongoManipulator) Abort(id manipulate.TransactionID) bool { return true }
 
-func (m *mongoManipulator) Ping(timeout time.Duration) error {
+// Ping verifies that the mongo server is reachable.
+// It returns an ErrCannotCommunicate if the server does not
+// answer before the given context is done.
+func (m *mongoManipulator) Ping(ctx context.Context) error {
 
 	errChannel := make(chan error, 1)
 
@@ -756,26 +800,215 @@ func (m *mongoManipulator) Ping(timeout time.Duration) error {
 	}()
 
 	select {
-	case <-time.After(timeout):
-		return fmt.Errorf("timeout")
+	case <-ctx.Done():
+		return manipulate.ErrCannotCommunicate{Err: fmt.Errorf("ping: %w", ctx.Err())}
 	case err := <-errChannel:
-		return err
+		if err != nil {
+			return manipulate.ErrCannotCommunicate{Err: fmt.Errorf("ping: %w", err)}
+		}
+		return nil
 	}
 }
 
-func (m *mongoManipulator) makeSession(
-	identity elemental.Identity,
-	readConsistency manipulate.ReadConsistency,
-	writeConsistency manipulate.WriteConsistency,
-) (*mgo.Collection, func()) {
+// ServerInfo returns the version of the mongo server and the
+// capabilities it supports.
+func (m *mongoManipulator) ServerInfo(ctx context.Context) (manipulate.ServerInfo, error) {
+
+	out, err := RunQuery(
+		manipulate.NewContext(ctx),
+		func() (interface{}, error) {
+			session := m.rootSession.Copy()
+			defer session.Close()
+			return session.BuildInfo()
+		},
+		RetryInfo{
+			Operation:        elemental.OperationInfo,
+			Identity:         elemental.EmptyIdentity,
+			defaultRetryFunc: m.defaultRetryFunc,
+			disableJitter:    m.disableBackoffJitter,
+		},
+	)
+	if err != nil {
+		return manipulate.ServerInfo{}, err
+	}
+
+	info := out.(mgo.BuildInfo)
+
+	return manipulate.ServerInfo{
+		Backend:      "mongo",
+		Version:      info.Version,
+		Capabilities: makeCapabilities(info),
+	}, nil
+}
+
+// makeRetrieveManyQuery builds the query used by RetrieveMany from
+// the given context. It also returns the filter of the query.
+func (m *mongoManipulator) makeRetrieveManyQuery(c *mgo.Collection, mctx manipulate.Context, dest elemental.Identifiables) (*mgo.Query, bson.D, error) {
+
+	var attrSpec elemental.AttributeSpecifiable
+	if m.attributeSpecifiers != nil {
+		attrSpec = m.attributeSpecifiers[dest.Identity()]
+	}
+
+	var order []string
+	if o := mctx.Order(); len(o) > 0 {
+		order = applyOrdering(o, attrSpec)
+	} else if orderer, ok := dest.(elemental.DefaultOrderer); ok {
+		order = applyOrdering(orderer.DefaultOrder(), attrSpec)
+	}
+
+	// Filtering
+	filter := bson.D{}
+	if f := mctx.Filter(); f != nil {
+		var opts []CompilerOption
+		if attrSpec != nil {
+			opts = append(opts, CompilerOptionTranslateKeysFromSpec(attrSpec))
+		}
+		filter = CompileFilter(f, opts...)
+	}
+
+	var ands []bson.D
+
+	if m.sharder != nil {
+		sq, err := m.sharder.FilterMany(m, mctx, dest.Identity())
+		if err != nil {
+			return nil, nil, manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("cannot compute sharding filter: %w", err)}
+		}
+		if sq != nil {
+			ands = append(ands, sq)
+		}
+	}
+
+	if m.forcedReadFilter != nil {
+		ands = append(ands, m.forcedReadFilter)
+	}
+
+	if gf := makeGeoFilter(mctx, attrSpec); gf != nil {
+		ands = append(ands, gf)
+	}
+
+	if after := mctx.After(); after != "" {
+
+		if len(order) > 1 {
+			return nil, nil, manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("cannot use multiple ordering fields when using 'after'")}
+		}
+
+		var o string
+		if len(order) == 1 {
+			o = order[0]
+		}
+
+		f, err := prepareNextFilter(c, o, after)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ands = append(ands, f)
+	}
+
+	if len(ands) > 0 {
+		filter = bson.D{{Name: "$and", Value: append(ands, filter)}}
+	}
+
+	// Query building
+	q := c.Find(filter)
+
+	// limiting
+	if limit := mctx.Limit(); limit > 0 {
+		q = q.Limit(limit)
+	} else if pageSize := mctx.PageSize(); pageSize > 0 {
+		q = q.Limit(pageSize)
+	}
+
+	// Old pagination
+	if p := mctx.Page(); p > 0 {
+		q = q.Skip((p - 1) * mctx.PageSize())
+	}
+
+	// Ordering
+	if len(order) == 1 && (mctx.After() != "" || mctx.Limit() > 0) {
+		// When paginating with after, the _id breaks the ties of
+		// the ordering field. See makeNextFilter.
+		if strings.TrimPrefix(order[0], "-") != "_id" {
+			order = append(order, "_id")
+		}
+	}
+
+	if len(order) > 0 {
+		q = q.Sort(order...)
+	}
+
+	// Fields selection
+	if sels := makeFieldsSelector(mctx.Fields(), attrSpec); sels != nil {
+		q = q.Select(sels)
+	} else if _, ok := mctx.(opaquer).Opaque()[opaqueKeyIncludeLazy]; !ok {
+		if sels := makeExclusionSelector(m.lazyFields[dest.Identity()], attrSpec); sels != nil {
+			q = q.Select(sels)
+		}
+	}
+
+	// Query timing limiting
+	q = q.SetMaxTime(defaultGlobalContextTimeout)
+	if d, ok := mctx.Context().Deadline(); ok {
+		q = q.SetMaxTime(time.Until(d))
+	}
+
+	return q, filter, nil
+}
+
+// recordOperation reports the given operation to the metrics recorder.
+func (m *mongoManipulator) recordOperation(operation elemental.Operation, identity elemental.Identity, start time.Time, err *error) {
+
+	if m.metricsRecorder != nil {
+		m.metricsRecorder.RecordOperation(operation, identity, time.Since(start), *err)
+	}
+}
+
+func (m *mongoManipulator) makeSession(identity elemental.Identity, mctx manipulate.Context) (*mgo.Collection, func()) {
 
 	session := m.rootSession.Copy()
 
-	if mrc := convertReadConsistency(readConsistency); mrc != -1 {
+	if mrc := convertReadConsistency(mctx.ReadConsistency()); mrc != -1 {
 		session.SetMode(mrc, true)
 	}
 
-	session.SetSafe(convertWriteConsistency(writeConsistency))
+	if tags := readPreferenceTags(mctx, session.Mode()); len(tags) > 0 {
+		session.SelectServers(tags...)
+	}
+
+	session.SetSafe(convertWriteConsistency(mctx.WriteConsistency()))
 
 	return session.DB(m.dbName).C(identity.Name), session.Close
 }
+
+// makeFilterForMany builds the filter used by operations
+// targeting multiple objects from the filter of the given context,
+// along with the sharding filter and the forced read filter if any.
+func (m *mongoManipulator) makeFilterForMany(mctx manipulate.Context, identity elemental.Identity) (bson.D, error) {
+
+	filter := bson.D{}
+
+	if f := mctx.Filter(); f != nil {
+		var opts []CompilerOption
+		if attrSpec := m.attributeSpecifiers[identity]; attrSpec != nil {
+			opts = append(opts, CompilerOptionTranslateKeysFromSpec(attrSpec))
+		}
+		filter = CompileFilter(f, opts...)
+	}
+
+	if m.sharder != nil {
+		sq, err := m.sharder.FilterMany(m, mctx, identity)
+		if err != nil {
+			return nil, manipulate.ErrCannotBuildQuery{Err: fmt.Errorf("cannot compute sharding filter: %w", err)}
+		}
+		if sq != nil {
+			filter = bson.D{{Name: "$and", Value: []bson.D{sq, filter}}}
+		}
+	}
+
+	if m.forcedReadFilter != nil {
+		filter = bson.D{{Name: "$and", Value: []bson.D{m.forcedReadFilter, filter}}}
+	}
+
+	return filter, nil
+}
@@ -24,21 +24,25 @@ import (
 type Option func(*config)
 
 type config struct {
-	username            string
-	password            string
-	authsource          string
-	tlsConfig           *tls.Config
-	poolLimit           int
-	connectTimeout      time.Duration
-	socketTimeout       time.Duration
-	readConsistency     manipulate.ReadConsistency
-	writeConsistency    manipulate.WriteConsistency
-	sharder             Sharder
-	defaultRetryFunc    manipulate.RetryFunc
-	forcedReadFilter    bson.D
-	attributeEncrypter  elemental.AttributeEncrypter
-	explain             map[elemental.Identity]map[elemental.Operation]struct{}
-	attributeSpecifiers map[elemental.Identity]elemental.AttributeSpecifiable
+	username             string
+	password             string
+	authsource           string
+	tlsConfig            *tls.Config
+	poolLimit            int
+	connectTimeout       time.Duration
+	socketTimeout        time.Duration
+	syncTimeout          time.Duration
+	readConsistency      manipulate.ReadConsistency
+	writeConsistency     manipulate.WriteConsistency
+	sharder              Sharder
+	defaultRetryFunc     manipulate.RetryFunc
+	forcedReadFilter     bson.D
+	attributeEncrypter   elemental.AttributeEncrypter
+	explain              map[elemental.Identity]map[elemental.Operation]struct{}
+	attributeSpecifiers  map[elemental.Identity]elemental.AttributeSpecifiable
+	lazyFields           map[elemental.Identity][]string
+	disableBackoffJitter bool
+	metricsRecorder      manipulate.MetricsRecorder
 }
 
 func newConfig() *config {
@@ -48,6 +52,7 @@ func newConfig() *config {
 		socketTimeout:    60 * time.Second,
 		readConsistency:  manipulate.ReadConsistencyDefault,
 		writeConsistency: manipulate.WriteConsistencyDefault,
+		metricsRecorder:  manipulate.NewNoopMetricsRecorder(),
 	}
 }
 
@@ -88,6 +93,15 @@ func OptionSocketTimeout(socketTimeout time.Duration) Option {
 	}
 }
 
+// OptionSyncTimeout sets the amount of time an operation waits
+// for a suitable server to be available. If not set, it is the
+// same as the connection timeout.
+func OptionSyncTimeout(syncTimeout time.Duration) Option {
+	return func(c *config) {
+		c.syncTimeout = syncTimeout
+	}
+}
+
 // OptionDefaultReadConsistencyMode sets the default read consistency mode.
 func OptionDefaultReadConsistencyMode(consistency manipulate.ReadConsistency) Option {
 	return func(c *config) {
@@ -176,7 +190,61 @@ func OptionTranslateKeysFromModelManager(manager elemental.ModelManager) Option
 	}
 }
 
-const opaqueKeyUpsert = "manipmongo.upsert"
+// OptionDisableBackoffJitter disables the randomization of the time
+// waited between two retries. By default, a random duration up to the
+// one given by the backoff curve is used, so clients failing at the same
+// time, for instance when the primary steps down, do not retry all together.
+func OptionDisableBackoffJitter() Option {
+	return func(c *config) {
+		c.disableBackoffJitter = true
+	}
+}
+
+// OptionLazyFields sets attributes of the given identity that will not be
+// returned by RetrieveMany, unless they are explicitly requested using
+// manipulate.ContextOptionFields or ContextOptionIncludeLazyFields is used.
+// This is useful to keep listing objects embedding large data fast.
+// Retrieve always returns all the attributes.
+// This option can be used multiple times to configure several identities.
+func OptionLazyFields(identity elemental.Identity, fields ...string) Option {
+	return func(c *config) {
+		if c.lazyFields == nil {
+			c.lazyFields = map[elemental.Identity][]string{}
+		}
+		c.lazyFields[identity] = append(c.lazyFields[identity], fields...)
+	}
+}
+
+// OptionMetricsRecorder sets the manipulate.MetricsRecorder
+// notified of every operation performed by the manipulator.
+// The default records nothing.
+func OptionMetricsRecorder(recorder manipulate.MetricsRecorder) Option {
+
+	if recorder == nil {
+		panic("recorder must not be nil")
+	}
+
+	return func(c *config) {
+		c.metricsRecorder = recorder
+	}
+}
+
+const (
+	opaqueKeyUpsert            = "manipmongo.upsert"
+	opaqueKeyUpsertKeys        = "manipmongo.upsertkeys"
+	opaqueKeyOrderedBulk       = "manipmongo.orderedbulk"
+	opaqueKeyAllowDeleteAll    = "manipmongo.allowdeleteall"
+	opaqueKeyAllowIncrementAll = "manipmongo.allowincrementall"
+	opaqueKeyIncludeLazy       = "manipmongo.includelazy"
+	opaqueKeyTTL               = "manipmongo.ttl"
+	opaqueKeyMaxRetries        = "manipmongo.maxretries"
+	opaqueKeyReadTags          = "manipmongo.readtags"
+	opaqueKeyGeoWithin         = "manipmongo.geowithin"
+)
+
+// ExpirationField is the name of the field holding the
+// expiration date of objects created with ContextOptionTTL.
+const ExpirationField = "_expiration"
 
 type opaquer interface {
 	Opaque() map[string]interface{}
@@ -206,3 +274,152 @@ func ContextOptionUpsert(operations bson.M) manipulate.ContextOption {
 		c.(opaquer).Opaque()[opaqueKeyUpsert] = operations
 	}
 }
+
+// ContextOptionUpsertKeys tells to use upsert for an Update operation.
+// Instead of selecting the document by its identifier, Update will
+// select it using the values of the given attributes of the object, and will
+// insert it if no document matches. Once done, the identifier of the
+// resulting document will be set on the object. If the upsert fails, the
+// object keeps its identifier.
+// As the document may be inserted, the sharder shards the object like Create
+// does, and ContextOptionTTL sets its expiration date if it is inserted.
+// If no key is given, ContextOptionUpsertKeys will panic.
+func ContextOptionUpsertKeys(keys ...string) manipulate.ContextOption {
+
+	if len(keys) == 0 {
+		panic("at least one upsert key must be given")
+	}
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyUpsertKeys] = keys
+	}
+}
+
+// ContextOptionOrderedBulk tells BatchCreate to use an ordered bulk.
+// Mongo will then stop inserting the objects at the first failure,
+// instead of trying to insert all of them.
+func ContextOptionOrderedBulk() manipulate.ContextOption {
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyOrderedBulk] = true
+	}
+}
+
+// ContextOptionAllowDeleteAll allows DeleteMany to run without any filter
+// in the manipulate.Context. Without this option, calling DeleteMany with
+// a nil or empty filter will return a manipulate.ErrCannotBuildQuery
+// in order to prevent accidentally wiping an entire collection.
+func ContextOptionAllowDeleteAll() manipulate.ContextOption {
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyAllowDeleteAll] = true
+	}
+}
+
+// ContextOptionAllowIncrementAll allows Increment to run without any filter
+// in the manipulate.Context. Without this option, calling Increment with
+// a nil or empty filter will return a manipulate.ErrCannotBuildQuery
+// in order to prevent accidentally modifying an entire collection.
+func ContextOptionAllowIncrementAll() manipulate.ContextOption {
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyAllowIncrementAll] = true
+	}
+}
+
+// ContextOptionIncludeLazyFields tells RetrieveMany to return the
+// attributes configured with OptionLazyFields.
+func ContextOptionIncludeLazyFields() manipulate.ContextOption {
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyIncludeLazy] = true
+	}
+}
+
+// ContextOptionTTL tells Create and BatchCreate to set an expiration date on
+// the created objects, after which they will be deleted by mongo. The index
+// needed for this must be created using EnsureTTLIndex. When used with
+// ContextOptionUpsert, the expiration date is only set if the document is
+// inserted.
+// If the given ttl is not positive, ContextOptionTTL will panic.
+func ContextOptionTTL(ttl time.Duration) manipulate.ContextOption {
+
+	if ttl <= 0 {
+		panic("ttl must be positive")
+	}
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyTTL] = ttl
+	}
+}
+
+// ContextOptionMaxRetries sets the maximum number of times a query
+// failing with a communication error will be retried. Once reached, the
+// last error is returned, even if the deadline of the context has not
+// passed yet. Zero disables the retries.
+// If the given number is negative, ContextOptionMaxRetries will panic.
+func ContextOptionMaxRetries(n int) manipulate.ContextOption {
+
+	if n < 0 {
+		panic("max retries must not be negative")
+	}
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyMaxRetries] = n
+	}
+}
+
+// ContextOptionReadPreferenceTags sets the tag sets used to select the
+// servers the reads are sent to, like a given data center. The tag sets
+// are tried in order until one matches at least one server.
+// They are ignored when the read consistency is strong, as reads
+// then always go to the primary.
+// If no tag set is given, ContextOptionReadPreferenceTags will panic.
+func ContextOptionReadPreferenceTags(tags ...bson.D) manipulate.ContextOption {
+
+	if len(tags) == 0 {
+		panic("at least one tag set must be given")
+	}
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyReadTags] = tags
+	}
+}
+
+// ContextOptionGeoWithin restricts RetrieveMany and Count to the documents
+// whose GeoJSON point stored in the given key is located within maxMeters
+// of the given longitude and latitude. This is compiled to a $geoWithin
+// query using $centerSphere, which is and-ed with the filter of the context.
+// While not mandatory, a 2dsphere index on the key should be created to
+// avoid scanning the whole collection, using EnsureIndex with an
+// mgo.Index having the key "$2dsphere:<key>". The memory manipulator
+// returns a manipulate.ErrNotImplemented when this option is set.
+// ContextOptionGeoWithin will panic if the key is empty, if the coordinates
+// are out of range or if maxMeters is not positive.
+func ContextOptionGeoWithin(key string, longitude float64, latitude float64, maxMeters float64) manipulate.ContextOption {
+
+	if key == "" {
+		panic("key must not be empty")
+	}
+
+	if longitude < -180 || longitude > 180 {
+		panic("longitude must be between -180 and 180")
+	}
+
+	if latitude < -90 || latitude > 90 {
+		panic("latitude must be between -90 and 90")
+	}
+
+	if maxMeters <= 0 {
+		panic("maxMeters must be positive")
+	}
+
+	return func(c manipulate.Context) {
+		c.(opaquer).Opaque()[opaqueKeyGeoWithin] = geoWithin{
+			key:       key,
+			longitude: longitude,
+			latitude:  latitude,
+			maxMeters: maxMeters,
+		}
+	}
+}
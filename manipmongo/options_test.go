@@ -20,6 +20,7 @@ import (
 	"github.com/globalsign/mgo/bson"
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
 	"go.aporeto.io/manipulate"
 )
 
@@ -92,6 +93,13 @@ func Test_Options(t *testing.T) {
 		So(c.socketTimeout, ShouldEqual, 12*time.Second)
 	})
 
+	Convey("Calling OptionSyncTimeout should work", t, func() {
+		c := newConfig()
+		So(c.syncTimeout, ShouldEqual, 0)
+		OptionSyncTimeout(12 * time.Second)(c)
+		So(c.syncTimeout, ShouldEqual, 12*time.Second)
+	})
+
 	Convey("Calling OptionDefaultReadConsistencyMode should work", t, func() {
 		c := newConfig()
 		OptionDefaultReadConsistencyMode(manipulate.ReadConsistencyNearest)(c)
@@ -139,6 +147,34 @@ func Test_Options(t *testing.T) {
 		So(c.explain, ShouldEqual, m)
 	})
 
+	Convey("Calling OptionDisableBackoffJitter should work", t, func() {
+		c := newConfig()
+		OptionDisableBackoffJitter()(c)
+		So(c.disableBackoffJitter, ShouldBeTrue)
+	})
+
+	Convey("Calling OptionMetricsRecorder should work", t, func() {
+		r := manipulate.NewNoopMetricsRecorder()
+		c := newConfig()
+		OptionMetricsRecorder(r)(c)
+		So(c.metricsRecorder, ShouldEqual, r)
+	})
+
+	Convey("Calling OptionMetricsRecorder with a nil recorder should panic", t, func() {
+		So(func() { OptionMetricsRecorder(nil) }, ShouldPanicWith, "recorder must not be nil")
+	})
+
+	Convey("Calling OptionLazyFields should work", t, func() {
+		c := newConfig()
+		OptionLazyFields(testmodel.ListIdentity, "description")(c)
+		OptionLazyFields(testmodel.ListIdentity, "slice")(c)
+		OptionLazyFields(testmodel.TaskIdentity, "description")(c)
+		So(c.lazyFields, ShouldResemble, map[elemental.Identity][]string{
+			testmodel.ListIdentity: {"description", "slice"},
+			testmodel.TaskIdentity: {"description"},
+		})
+	})
+
 	Convey("Calling OptionTranslateKeysFromModelManager should panic if provided nil manager", t, func() {
 		c := newConfig()
 		So(func() { OptionTranslateKeysFromModelManager(nil)(c) }, ShouldPanic)
@@ -165,4 +201,87 @@ func Test_ContextOptions(t *testing.T) {
 		b := bson.M{"$setOnInsert": bson.M{"_id": 1}}
 		So(func() { ContextOptionUpsert(b)(nil) }, ShouldPanicWith, "cannot use $setOnInsert on _id in upsert operations")
 	})
+
+	Convey("Calling ContextOptionUpsertKeys should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionUpsertKeys("name", "namespace")(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyUpsertKeys], ShouldResemble, []string{"name", "namespace"})
+	})
+
+	Convey("Calling ContextOptionUpsertKeys without keys should panic", t, func() {
+		So(func() { ContextOptionUpsertKeys() }, ShouldPanicWith, "at least one upsert key must be given")
+	})
+
+	Convey("Calling ContextOptionOrderedBulk should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionOrderedBulk()(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyOrderedBulk], ShouldEqual, true)
+	})
+
+	Convey("Calling ContextOptionIncludeLazyFields should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionIncludeLazyFields()(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyIncludeLazy], ShouldEqual, true)
+	})
+
+	Convey("Calling ContextOptionTTL should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionTTL(time.Hour)(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyTTL], ShouldEqual, time.Hour)
+	})
+
+	Convey("Calling ContextOptionTTL with a zero ttl should panic", t, func() {
+		So(func() { ContextOptionTTL(0) }, ShouldPanicWith, "ttl must be positive")
+	})
+
+	Convey("Calling ContextOptionMaxRetries should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionMaxRetries(3)(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyMaxRetries], ShouldEqual, 3)
+	})
+
+	Convey("Calling ContextOptionMaxRetries with a negative number should panic", t, func() {
+		So(func() { ContextOptionMaxRetries(-1) }, ShouldPanicWith, "max retries must not be negative")
+	})
+
+	Convey("Calling ContextOptionAllowDeleteAll should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionAllowDeleteAll()(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyAllowDeleteAll], ShouldEqual, true)
+	})
+
+	Convey("Calling ContextOptionAllowIncrementAll should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionAllowIncrementAll()(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyAllowIncrementAll], ShouldEqual, true)
+	})
+
+	Convey("Calling ContextOptionReadPreferenceTags should work", t, func() {
+		tags := []bson.D{{{Name: "dc", Value: "east"}}, {}}
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionReadPreferenceTags(tags...)(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyReadTags], ShouldResemble, tags)
+	})
+
+	Convey("Calling ContextOptionReadPreferenceTags without tags should panic", t, func() {
+		So(func() { ContextOptionReadPreferenceTags() }, ShouldPanicWith, "at least one tag set must be given")
+	})
+
+	Convey("Calling ContextOptionGeoWithin should work", t, func() {
+		mctx := manipulate.NewContext(context.Background())
+		ContextOptionGeoWithin("location", 2.35, 48.85, 1000)(mctx)
+		So(mctx.(opaquer).Opaque()[opaqueKeyGeoWithin], ShouldResemble, geoWithin{
+			key:       "location",
+			longitude: 2.35,
+			latitude:  48.85,
+			maxMeters: 1000,
+		})
+	})
+
+	Convey("Calling ContextOptionGeoWithin with invalid arguments should panic", t, func() {
+		So(func() { ContextOptionGeoWithin("", 0, 0, 1) }, ShouldPanicWith, "key must not be empty")
+		So(func() { ContextOptionGeoWithin("location", 181, 0, 1) }, ShouldPanicWith, "longitude must be between -180 and 180")
+		So(func() { ContextOptionGeoWithin("location", 0, -91, 1) }, ShouldPanicWith, "latitude must be between -90 and 90")
+		So(func() { ContextOptionGeoWithin("location", 0, 0, 0) }, ShouldPanicWith, "maxMeters must be positive")
+	})
 }
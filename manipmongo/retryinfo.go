@@ -26,6 +26,7 @@ type RetryInfo struct {
 	mctx manipulate.Context
 
 	defaultRetryFunc manipulate.RetryFunc
+	disableJitter    bool
 }
 
 // Try returns the try number.
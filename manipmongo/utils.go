@@ -12,11 +12,14 @@
 package manipmongo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
@@ -76,42 +79,129 @@ func prepareNextFilter(collection *mgo.Collection, orderingField string, next st
 		id = next
 	}
 
-	if orderingField == "" {
+	field := strings.TrimPrefix(orderingField, "-")
+	if field == "" || field == "_id" {
+		return makeNextFilter(orderingField, nil, id), nil
+	}
+
+	doc := bson.M{}
+	if err := collection.FindId(id).Select(bson.M{field: 1}).One(&doc); err != nil {
+		return nil, HandleQueryError(err)
+	}
+
+	return makeNextFilter(orderingField, doc[field], id), nil
+}
+
+// makeNextFilter returns the filter matching the documents that come after
+// the document with the given id and the given value for the ordering field.
+// As several documents can share the same value, the ties are broken using the
+// _id, so the query must also be sorted by _id for the pagination to be stable.
+func makeNextFilter(orderingField string, value interface{}, id interface{}) bson.D {
+
+	comp := "$gt"
+	if strings.HasPrefix(orderingField, "-") {
+		orderingField = strings.TrimPrefix(orderingField, "-")
+		comp = "$lt"
+	}
+
+	if orderingField == "" || orderingField == "_id" {
 		return bson.D{
 			{
 				Name: "_id",
 				Value: bson.D{
 					{
-						Name:  "$gt",
+						Name:  comp,
 						Value: id,
 					},
 				},
 			},
-		}, nil
-	}
-
-	comp := "$gt"
-	if strings.HasPrefix(orderingField, "-") {
-		orderingField = strings.TrimPrefix(orderingField, "-")
-		comp = "$lt"
-	}
-
-	doc := bson.M{}
-	if err := collection.FindId(id).Select(bson.M{orderingField: 1}).One(&doc); err != nil {
-		return nil, HandleQueryError(err)
+		}
 	}
 
 	return bson.D{
 		{
-			Name: orderingField,
-			Value: bson.D{
+			Name: "$or",
+			Value: []bson.D{
 				{
-					Name:  comp,
-					Value: doc[orderingField],
+					{
+						Name: orderingField,
+						Value: bson.D{
+							{
+								Name:  comp,
+								Value: value,
+							},
+						},
+					},
+				},
+				{
+					{
+						Name:  orderingField,
+						Value: value,
+					},
+					{
+						Name: "_id",
+						Value: bson.D{
+							{
+								Name:  "$gt",
+								Value: id,
+							},
+						},
+					},
 				},
 			},
 		},
-	}, nil
+	}
+}
+
+// scatterDocuments unmarshals each of the given documents into the objects
+// having the same identifier. It returns the objects that have been populated
+// and the identifiers of the ones for which there was no document.
+func scatterDocuments(docs []bson.Raw, objects []elemental.Identifiable) ([]elemental.Identifiable, []string, error) {
+
+	index := make(map[string][]elemental.Identifiable, len(objects))
+	for _, object := range objects {
+		index[object.Identifier()] = append(index[object.Identifier()], object)
+	}
+
+	found := make([]elemental.Identifiable, 0, len(objects))
+
+	for _, doc := range docs {
+
+		var idDoc struct {
+			ID interface{} `bson:"_id"`
+		}
+
+		if err := doc.Unmarshal(&idDoc); err != nil {
+			return nil, nil, manipulate.ErrCannotUnmarshal{Err: err}
+		}
+
+		var id string
+		switch v := idDoc.ID.(type) {
+		case bson.ObjectId:
+			id = v.Hex()
+		default:
+			id = fmt.Sprintf("%v", v)
+		}
+
+		for _, object := range index[id] {
+			if err := doc.Unmarshal(object); err != nil {
+				return nil, nil, manipulate.ErrCannotUnmarshal{Err: err}
+			}
+			found = append(found, object)
+		}
+
+		delete(index, id)
+	}
+
+	missing := make([]string, 0, len(index))
+	for _, object := range objects {
+		if _, ok := index[object.Identifier()]; ok {
+			missing = append(missing, object.Identifier())
+			delete(index, object.Identifier())
+		}
+	}
+
+	return found, missing, nil
 }
 
 // HandleQueryError handles the provided upstream error returned by Mongo by returning a corresponding manipulate error type.
@@ -122,11 +212,11 @@ func HandleQueryError(err error) error {
 	}
 
 	if err == mgo.ErrNotFound {
-		return manipulate.ErrObjectNotFound{Err: fmt.Errorf("cannot find the object for the given ID")}
+		return manipulate.ErrObjectNotFound{Err: causeError{message: "cannot find the object for the given ID", cause: err}}
 	}
 
 	if mgo.IsDup(err) {
-		return manipulate.ErrConstraintViolation{Err: fmt.Errorf("duplicate key")}
+		return manipulate.ErrConstraintViolation{Err: causeError{message: "duplicate key", cause: err}}
 	}
 
 	if isConnectionError(err) {
@@ -139,15 +229,18 @@ func HandleQueryError(err error) error {
 
 	// see https://github.com/mongodb/mongo/blob/master/src/mongo/base/error_codes.err
 	switch getErrorCode(err) {
-	case 6, 7, 71, 74, 91, 109, 189, 202, 216, 262, 10107, 13436, 13435, 11600, 11602:
+	case 6, 7, 71, 74, 91, 109, 112, 117, 189, 202, 216, 251, 262, 10107, 13436, 13435, 11600, 11602:
 		// HostUnreachable
 		// HostNotFound,
 		// ReplicaSetNotFound,
 		// NodeNotFound,
 		// ConfigurationInProgress,
+		// WriteConflict
+		// ConflictingOperationInProgress
 		// ShutdownInProgress
 		// PrimarySteppedDown,
 		// NetworkInterfaceExceededTimeLimit
+		// NoSuchTransaction
 		// ElectionInProgress
 		// ExceededTimeLimit
 		// NotMaster
@@ -161,6 +254,17 @@ func HandleQueryError(err error) error {
 	}
 }
 
+// A causeError replaces the message of an error
+// while keeping it reachable with errors.Is and errors.As.
+type causeError struct {
+	message string
+	cause   error
+}
+
+func (e causeError) Error() string { return e.message }
+
+func (e causeError) Unwrap() error { return e.cause }
+
 func getErrorCode(err error) int {
 
 	switch e := err.(type) {
@@ -271,21 +375,30 @@ func makeFieldsSelector(fields []string, spec elemental.AttributeSpecifiable) bs
 			continue
 		}
 
-		f = strings.ToLower(strings.TrimPrefix(f, descendingOrderPrefix))
-		if spec != nil {
-			// if a spec has been provided, use it to look up the BSON field name if there is an entry for the attribute.
-			// if no entry was found for the attribute in the provided spec default to whatever value was provided for
-			// the attribute.
-			if as := spec.SpecificationForAttribute(f); as.BSONFieldName != "" {
-				f = as.BSONFieldName
-			}
-		} else {
-			if f == "id" {
-				f = "_id"
-			}
+		sels[bsonFieldName(strings.TrimPrefix(f, descendingOrderPrefix), spec)] = 1
+	}
+
+	if len(sels) == 0 {
+		return nil
+	}
+
+	return sels
+}
+
+func makeExclusionSelector(fields []string, spec elemental.AttributeSpecifiable) bson.M {
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	sels := bson.M{}
+	for _, f := range fields {
+
+		if f == "" {
+			continue
 		}
 
-		sels[f] = 1
+		sels[bsonFieldName(f, spec)] = 0
 	}
 
 	if len(sels) == 0 {
@@ -295,6 +408,122 @@ func makeFieldsSelector(fields []string, spec elemental.AttributeSpecifiable) bs
 	return sels
 }
 
+// bsonFieldName returns the name of the field storing the
+// given attribute in the database.
+func bsonFieldName(attribute string, spec elemental.AttributeSpecifiable) string {
+
+	f := strings.ToLower(attribute)
+
+	if spec != nil {
+		// if a spec has been provided, use it to look up the BSON field name if there is an entry for the attribute.
+		// if no entry was found for the attribute in the provided spec default to whatever value was provided for
+		// the attribute.
+		if as := spec.SpecificationForAttribute(f); as.BSONFieldName != "" {
+			f = as.BSONFieldName
+		}
+	} else {
+		if f == "id" {
+			f = "_id"
+		}
+	}
+
+	return f
+}
+
+func makeUpsertSelector(object interface{}, keys []string, spec elemental.AttributeSpecifiable) (bson.D, error) {
+
+	data, err := bson.Marshal(object)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode object: %w", err)
+	}
+
+	doc := bson.M{}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to decode object: %w", err)
+	}
+
+	selector := make(bson.D, len(keys))
+	for i, k := range keys {
+
+		f := bsonFieldName(k, spec)
+
+		v, ok := doc[f]
+		if !ok {
+			return nil, fmt.Errorf("upsert key '%s' is not set in the object", k)
+		}
+
+		selector[i] = bson.DocElem{Name: f, Value: v}
+	}
+
+	return selector, nil
+}
+
+// makeUpsertOperations returns the operations upserting the given object,
+// merged with the given additional operations. An inserted document gets
+// the given oid, and expires after the given ttl if it is positive.
+func makeUpsertOperations(object interface{}, oid bson.ObjectId, ops bson.M, ttl time.Duration) bson.M {
+
+	out := bson.M{
+		"$set":         object,
+		"$setOnInsert": bson.M{"_id": oid},
+	}
+
+	if soi, ok := ops["$setOnInsert"]; ok {
+		for k, v := range soi.(bson.M) {
+			out["$setOnInsert"].(bson.M)[k] = v
+		}
+	}
+
+	for k, v := range ops {
+		if k == "$setOnInsert" {
+			continue
+		}
+		out[k] = v
+	}
+
+	if ttl > 0 {
+		out["$setOnInsert"].(bson.M)[ExpirationField] = time.Now().Add(ttl)
+	}
+
+	return out
+}
+
+// upsertResult returns the identifier of the document
+// returned by an upsert, and whether it has been inserted.
+func upsertResult(info *mgo.ChangeInfo, doc bson.M) (string, bool) {
+
+	var id string
+	switch oid := doc["_id"].(type) {
+	case bson.ObjectId:
+		id = oid.Hex()
+	case string:
+		id = oid
+	}
+
+	return id, info != nil && info.UpsertedId != nil
+}
+
+// Capabilities that can be reported by a mongo manipulator.
+const (
+	CapabilityChangeStreams = "changestreams"
+	CapabilityTransactions  = "transactions"
+)
+
+func makeCapabilities(info mgo.BuildInfo) []string {
+
+	capabilities := []string{}
+
+	if info.VersionAtLeast(3, 6) {
+		capabilities = append(capabilities, CapabilityChangeStreams)
+	}
+
+	if info.VersionAtLeast(4, 0) {
+		capabilities = append(capabilities, CapabilityTransactions)
+	}
+
+	return capabilities
+}
+
 func convertReadConsistency(c manipulate.ReadConsistency) mgo.Mode {
 	switch c {
 	case manipulate.ReadConsistencyEventual:
@@ -312,6 +541,72 @@ func convertReadConsistency(c manipulate.ReadConsistency) mgo.Mode {
 	}
 }
 
+// readPreferenceTags returns the read preference tags set
+// in the given context, unless the given mode is strong.
+func readPreferenceTags(mctx manipulate.Context, mode mgo.Mode) []bson.D {
+
+	if mode == mgo.Strong {
+		return nil
+	}
+
+	o, ok := mctx.(opaquer)
+	if !ok {
+		return nil
+	}
+
+	tags, _ := o.Opaque()[opaqueKeyReadTags].([]bson.D)
+
+	return tags
+}
+
+// earthRadius is the radius of the earth in meters
+// used by mongo to compute distances on a sphere.
+const earthRadius = 6378100.0
+
+// A geoWithin holds the parameters given to ContextOptionGeoWithin.
+type geoWithin struct {
+	key       string
+	longitude float64
+	latitude  float64
+	maxMeters float64
+}
+
+// makeGeoFilter returns the filter matching the documents located within
+// the area set in the given context by ContextOptionGeoWithin, or nil if
+// there is none.
+func makeGeoFilter(mctx manipulate.Context, spec elemental.AttributeSpecifiable) bson.D {
+
+	o, ok := mctx.(opaquer)
+	if !ok {
+		return nil
+	}
+
+	geo, ok := o.Opaque()[opaqueKeyGeoWithin].(geoWithin)
+	if !ok {
+		return nil
+	}
+
+	return bson.D{
+		{
+			Name: bsonFieldName(geo.key, spec),
+			Value: bson.D{
+				{
+					Name: "$geoWithin",
+					Value: bson.D{
+						{
+							Name: "$centerSphere",
+							Value: []interface{}{
+								[]float64{geo.longitude, geo.latitude},
+								geo.maxMeters / earthRadius,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func convertWriteConsistency(c manipulate.WriteConsistency) *mgo.Safe {
 	switch c {
 	case manipulate.WriteConsistencyNone:
@@ -386,3 +681,129 @@ func explain(query *mgo.Query, operation elemental.Operation, identity elemental
 
 	return nil
 }
+
+// makeExpiringDocument returns the bson document of the given
+// object with the given expiration date set.
+func makeExpiringDocument(object interface{}, expiration time.Time) (bson.D, error) {
+
+	data, err := bson.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.D{}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return append(doc, bson.DocElem{Name: ExpirationField, Value: expiration}), nil
+}
+
+// makeIncrementOperations returns the update document
+// incrementing the given field by inc.
+func makeIncrementOperations(field string, inc int) bson.M {
+	return bson.M{"$inc": bson.M{field: inc}}
+}
+
+// resumeBulkInsert inspects the failures of a bulk insert retried after
+// an attempt whose outcome is unknown. A duplicate key on _id means the
+// document has been written by that previous attempt, and is not a failure.
+// It returns the first actual failure, if any, and the offset from which an
+// ordered bulk, that stops at its first failure, must be run again. The
+// offset is -1 when there is nothing left to insert.
+func resumeBulkInsert(cases []mgo.BulkErrorCase, ordered bool, n int) (int, error) {
+
+	offset := -1
+
+	for _, c := range cases {
+
+		if !isIDDuplicate(c.Err) {
+			return -1, c.Err
+		}
+
+		if ordered {
+			if c.Index < 0 {
+				return -1, c.Err
+			}
+			if c.Index+1 < n {
+				offset = c.Index + 1
+			}
+		}
+	}
+
+	return offset, nil
+}
+
+// isIDDuplicate returns true if the given error is
+// a duplicate key error on the _id index.
+func isIDDuplicate(err error) bool {
+	return err != nil && mgo.IsDup(err) && strings.Contains(err.Error(), "index: _id_ ")
+}
+
+// textScoreField is the name of the field used by TextSearch
+// to project the relevance of the documents.
+const textScoreField = "_textscore"
+
+// makeTextFilter returns the filter matching the documents
+// containing the given phrase using the text index of the collection.
+func makeTextFilter(phrase string) bson.D {
+
+	return bson.D{
+		{
+			Name: "$text",
+			Value: bson.D{
+				{
+					Name:  "$search",
+					Value: phrase,
+				},
+			},
+		},
+	}
+}
+
+// A cursor is the subset of *mgo.Iter used by iterAll.
+type cursor interface {
+	Next(result interface{}) bool
+	Close() error
+}
+
+// iterAll works like mgo.Iter.All, but checks the given context
+// before decoding each document. If the context is done, the cursor
+// is closed and the context error is returned.
+func iterAll(ctx context.Context, iter cursor, result interface{}) error {
+
+	resultv := reflect.ValueOf(result)
+	if resultv.Kind() != reflect.Ptr || resultv.Elem().Kind() != reflect.Slice {
+		panic("result argument must be a slice address")
+	}
+
+	slicev := resultv.Elem()
+	slicev = slicev.Slice(0, slicev.Cap())
+	elemt := slicev.Type().Elem()
+
+	var i int
+	for {
+
+		if err := ctx.Err(); err != nil {
+			_ = iter.Close()
+			return err
+		}
+
+		if slicev.Len() == i {
+			elemp := reflect.New(elemt)
+			if !iter.Next(elemp.Interface()) {
+				break
+			}
+			slicev = reflect.Append(slicev, elemp.Elem())
+			slicev = slicev.Slice(0, slicev.Cap())
+		} else if !iter.Next(slicev.Index(i).Addr().Interface()) {
+			break
+		}
+
+		i++
+	}
+
+	resultv.Elem().Set(slicev.Slice(0, i))
+
+	return iter.Close()
+}
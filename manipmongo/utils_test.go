@@ -12,17 +12,20 @@
 package manipmongo
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/golang/mock/gomock"
 	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
 	"go.aporeto.io/manipulate"
 	"go.aporeto.io/manipulate/manipmongo/internal"
 )
@@ -179,6 +182,27 @@ func Test_HandleQueryError(t *testing.T) {
 			},
 			"Cannot communicate: boom",
 		},
+		{
+			"err 112",
+			args{
+				&mgo.QueryError{Code: 112, Message: "WriteConflict"},
+			},
+			"Cannot communicate: WriteConflict",
+		},
+		{
+			"err 117",
+			args{
+				&mgo.LastError{Code: 117, Err: "boom"},
+			},
+			"Cannot communicate: boom",
+		},
+		{
+			"err 251",
+			args{
+				&mgo.LastError{Code: 251, Err: "boom"},
+			},
+			"Cannot communicate: boom",
+		},
 		{
 			"err 11600",
 			args{
@@ -219,6 +243,37 @@ func Test_HandleQueryError(t *testing.T) {
 	}
 }
 
+func Test_HandleQueryError_cause(t *testing.T) {
+
+	t.Run("not found", func(t *testing.T) {
+		err := HandleQueryError(mgo.ErrNotFound)
+		if !errors.Is(err, mgo.ErrNotFound) {
+			t.Errorf("HandleQueryError() = %v, should wrap mgo.ErrNotFound", err)
+		}
+	})
+
+	t.Run("duplicate key", func(t *testing.T) {
+		var lerr *mgo.LastError
+		if err := HandleQueryError(&mgo.LastError{Code: 11000}); !errors.As(err, &lerr) || lerr.Code != 11000 {
+			t.Errorf("HandleQueryError() = %v, should wrap the *mgo.LastError", err)
+		}
+	})
+
+	t.Run("communication error", func(t *testing.T) {
+		var qerr *mgo.QueryError
+		if err := HandleQueryError(&mgo.QueryError{Code: 6, Message: "boom"}); !errors.As(err, &qerr) || qerr.Code != 6 {
+			t.Errorf("HandleQueryError() = %v, should wrap the *mgo.QueryError", err)
+		}
+	})
+
+	t.Run("net error", func(t *testing.T) {
+		var nerr net.Error
+		if err := HandleQueryError(&net.OpError{Op: "read", Err: fmt.Errorf("boom")}); !errors.As(err, &nerr) {
+			t.Errorf("HandleQueryError() = %v, should wrap the net.Error", err)
+		}
+	})
+}
+
 func Test_makeFieldsSelector(t *testing.T) {
 	type args struct {
 		fields    []string
@@ -253,7 +308,7 @@ func Test_makeFieldsSelector(t *testing.T) {
 		{
 			"id",
 			args{
-				[]string{"ID"},
+				[]string{"id"},
 				nil,
 			},
 			bson.M{
@@ -361,6 +416,84 @@ func Test_makeFieldsSelector(t *testing.T) {
 	}
 }
 
+func Test_makeExclusionSelector(t *testing.T) {
+	type args struct {
+		fields    []string
+		setupSpec func(t *testing.T, ctrl *gomock.Controller) elemental.AttributeSpecifiable
+	}
+	tests := []struct {
+		name string
+		args args
+		want bson.M
+	}{
+		{
+			"simple",
+			args{
+				[]string{"MyField1", "myfield2", ""},
+				nil,
+			},
+			bson.M{
+				"myfield1": 0,
+				"myfield2": 0,
+			},
+		},
+		{
+			"nil",
+			args{
+				nil,
+				nil,
+			},
+			nil,
+		},
+		{
+			"only empty",
+			args{
+				[]string{""},
+				nil,
+			},
+			nil,
+		},
+		{
+			"translate fields from provided spec",
+			args{
+				fields: []string{"FieldA"},
+				setupSpec: func(t *testing.T, ctrl *gomock.Controller) elemental.AttributeSpecifiable {
+
+					spec := internal.NewMockAttributeSpecifiable(ctrl)
+					spec.
+						EXPECT().
+						SpecificationForAttribute("fielda").
+						Return(
+							elemental.AttributeSpecification{
+								BSONFieldName: "a",
+							},
+						)
+
+					return spec
+				},
+			},
+			bson.M{
+				"a": 0,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var spec elemental.AttributeSpecifiable
+			if tt.args.setupSpec != nil {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+				spec = tt.args.setupSpec(t, ctrl)
+			}
+
+			if got := makeExclusionSelector(tt.args.fields, spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeExclusionSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_applyOrdering(t *testing.T) {
 	type args struct {
 		order     []string
@@ -736,6 +869,114 @@ func Test_convertReadConsistency(t *testing.T) {
 	}
 }
 
+func Test_readPreferenceTags(t *testing.T) {
+
+	tags := []bson.D{{{Name: "dc", Value: "east"}}}
+
+	tests := []struct {
+		name string
+		mctx manipulate.Context
+		mode mgo.Mode
+		want []bson.D
+	}{
+		{
+			"no tags",
+			manipulate.NewContext(context.Background()),
+			mgo.Nearest,
+			nil,
+		},
+		{
+			"tags with nearest",
+			manipulate.NewContext(context.Background(), ContextOptionReadPreferenceTags(tags...)),
+			mgo.Nearest,
+			tags,
+		},
+		{
+			"tags with secondary preferred",
+			manipulate.NewContext(context.Background(), ContextOptionReadPreferenceTags(tags...)),
+			mgo.SecondaryPreferred,
+			tags,
+		},
+		{
+			"tags with strong",
+			manipulate.NewContext(context.Background(), ContextOptionReadPreferenceTags(tags...)),
+			mgo.Strong,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readPreferenceTags(tt.mctx, tt.mode); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readPreferenceTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_makeGeoFilter(t *testing.T) {
+
+	centerSphere := func(key string) bson.D {
+		return bson.D{{Name: key, Value: bson.D{{Name: "$geoWithin", Value: bson.D{{
+			Name:  "$centerSphere",
+			Value: []interface{}{[]float64{2.35, 48.85}, 1000 / earthRadius},
+		}}}}}}
+	}
+
+	tests := []struct {
+		name      string
+		mctx      manipulate.Context
+		setupSpec func(t *testing.T, ctrl *gomock.Controller) elemental.AttributeSpecifiable
+		want      bson.D
+	}{
+		{
+			"no geo",
+			manipulate.NewContext(context.Background()),
+			nil,
+			nil,
+		},
+		{
+			"geo",
+			manipulate.NewContext(context.Background(), ContextOptionGeoWithin("Location", 2.35, 48.85, 1000)),
+			nil,
+			centerSphere("location"),
+		},
+		{
+			"geo with spec",
+			manipulate.NewContext(context.Background(), ContextOptionGeoWithin("Location", 2.35, 48.85, 1000)),
+			func(t *testing.T, ctrl *gomock.Controller) elemental.AttributeSpecifiable {
+
+				spec := internal.NewMockAttributeSpecifiable(ctrl)
+				spec.
+					EXPECT().
+					SpecificationForAttribute("location").
+					Return(
+						elemental.AttributeSpecification{
+							BSONFieldName: "loc",
+						},
+					)
+
+				return spec
+			},
+			centerSphere("loc"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var spec elemental.AttributeSpecifiable
+			if tt.setupSpec != nil {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+				spec = tt.setupSpec(t, ctrl)
+			}
+
+			if got := makeGeoFilter(tt.mctx, spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeGeoFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_convertWriteConsistency(t *testing.T) {
 	type args struct {
 		c manipulate.WriteConsistency
@@ -1109,3 +1350,548 @@ func Test_explainIfNeeded(t *testing.T) {
 		})
 	}
 }
+
+func Test_makeUpsertSelector(t *testing.T) {
+
+	type object struct {
+		ID   bson.ObjectId `bson:"_id,omitempty"`
+		Name string        `bson:"name"`
+		Zone int           `bson:"zone"`
+	}
+
+	oid := bson.NewObjectId()
+
+	type args struct {
+		object interface{}
+		keys   []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    bson.D
+		wantErr bool
+	}{
+		{
+			"single key",
+			args{
+				&object{Name: "a", Zone: 1},
+				[]string{"Name"},
+			},
+			bson.D{{Name: "name", Value: "a"}},
+			false,
+		},
+		{
+			"multiple keys",
+			args{
+				&object{Name: "a", Zone: 1},
+				[]string{"name", "zone"},
+			},
+			bson.D{{Name: "name", Value: "a"}, {Name: "zone", Value: 1}},
+			false,
+		},
+		{
+			"id key",
+			args{
+				&object{ID: oid, Name: "a"},
+				[]string{"ID"},
+			},
+			bson.D{{Name: "_id", Value: oid}},
+			false,
+		},
+		{
+			"missing key",
+			args{
+				&object{Name: "a"},
+				[]string{"nope"},
+			},
+			nil,
+			true,
+		},
+		{
+			"unencodable object",
+			args{
+				"not a document",
+				[]string{"name"},
+			},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := makeUpsertSelector(tt.args.object, tt.args.keys, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("makeUpsertSelector() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeUpsertSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_makeUpsertOperations(t *testing.T) {
+
+	oid := bson.NewObjectId()
+	object := &testmodel.List{Name: "a"}
+
+	tests := []struct {
+		name string
+		ops  bson.M
+		ttl  time.Duration
+		want bson.M
+	}{
+		{
+			"no operations",
+			nil,
+			0,
+			bson.M{
+				"$set":         object,
+				"$setOnInsert": bson.M{"_id": oid},
+			},
+		},
+		{
+			"additional operations",
+			bson.M{
+				"$inc":         bson.M{"count": 1},
+				"$setOnInsert": bson.M{"createTime": "now"},
+			},
+			0,
+			bson.M{
+				"$set":         object,
+				"$inc":         bson.M{"count": 1},
+				"$setOnInsert": bson.M{"_id": oid, "createTime": "now"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := makeUpsertOperations(object, oid, tt.ops, tt.ttl); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeUpsertOperations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("ttl", func(t *testing.T) {
+		got := makeUpsertOperations(object, oid, nil, time.Hour)
+		exp, ok := got["$setOnInsert"].(bson.M)[ExpirationField].(time.Time)
+		if !ok {
+			t.Fatalf("makeUpsertOperations() did not set %s: %v", ExpirationField, got)
+		}
+		if d := time.Until(exp); d <= 0 || d > time.Hour {
+			t.Errorf("makeUpsertOperations() expiration in %s, want about 1h", d)
+		}
+	})
+}
+
+func Test_upsertResult(t *testing.T) {
+
+	oid := bson.NewObjectId()
+
+	tests := []struct {
+		name         string
+		info         *mgo.ChangeInfo
+		doc          bson.M
+		wantID       string
+		wantInserted bool
+	}{
+		{
+			"inserted",
+			&mgo.ChangeInfo{UpsertedId: oid},
+			bson.M{"_id": oid},
+			oid.Hex(),
+			true,
+		},
+		{
+			"updated",
+			&mgo.ChangeInfo{Updated: 1, Matched: 1},
+			bson.M{"_id": oid},
+			oid.Hex(),
+			false,
+		},
+		{
+			"updated with a string identifier",
+			&mgo.ChangeInfo{Updated: 1, Matched: 1},
+			bson.M{"_id": "custom"},
+			"custom",
+			false,
+		},
+		{
+			"no info",
+			nil,
+			bson.M{"_id": oid},
+			oid.Hex(),
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, inserted := upsertResult(tt.info, tt.doc)
+			if id != tt.wantID {
+				t.Errorf("upsertResult() id = %v, want %v", id, tt.wantID)
+			}
+			if inserted != tt.wantInserted {
+				t.Errorf("upsertResult() inserted = %v, want %v", inserted, tt.wantInserted)
+			}
+		})
+	}
+}
+
+func Test_makeNextFilter(t *testing.T) {
+	type args struct {
+		orderingField string
+		value         interface{}
+		id            interface{}
+	}
+	tests := []struct {
+		name string
+		args args
+		want bson.D
+	}{
+		{
+			"no ordering field",
+			args{"", nil, "id"},
+			bson.D{{Name: "_id", Value: bson.D{{Name: "$gt", Value: "id"}}}},
+		},
+		{
+			"ordering on _id",
+			args{"_id", nil, "id"},
+			bson.D{{Name: "_id", Value: bson.D{{Name: "$gt", Value: "id"}}}},
+		},
+		{
+			"reverse ordering on _id",
+			args{"-_id", nil, "id"},
+			bson.D{{Name: "_id", Value: bson.D{{Name: "$lt", Value: "id"}}}},
+		},
+		{
+			"ordering field",
+			args{"name", "a", "id"},
+			bson.D{{Name: "$or", Value: []bson.D{
+				{{Name: "name", Value: bson.D{{Name: "$gt", Value: "a"}}}},
+				{{Name: "name", Value: "a"}, {Name: "_id", Value: bson.D{{Name: "$gt", Value: "id"}}}},
+			}}},
+		},
+		{
+			"reverse ordering field",
+			args{"-name", "a", "id"},
+			bson.D{{Name: "$or", Value: []bson.D{
+				{{Name: "name", Value: bson.D{{Name: "$lt", Value: "a"}}}},
+				{{Name: "name", Value: "a"}, {Name: "_id", Value: bson.D{{Name: "$gt", Value: "id"}}}},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := makeNextFilter(tt.args.orderingField, tt.args.value, tt.args.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeNextFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_makeTextFilter(t *testing.T) {
+
+	want := bson.D{{Name: "$text", Value: bson.D{{Name: "$search", Value: "hello world"}}}}
+	if got := makeTextFilter("hello world"); !reflect.DeepEqual(got, want) {
+		t.Errorf("makeTextFilter() = %v, want %v", got, want)
+	}
+}
+
+func Test_makeCapabilities(t *testing.T) {
+	tests := []struct {
+		name string
+		info mgo.BuildInfo
+		want []string
+	}{
+		{
+			"old version",
+			mgo.BuildInfo{VersionArray: []int{3, 4, 0}},
+			[]string{},
+		},
+		{
+			"3.6",
+			mgo.BuildInfo{VersionArray: []int{3, 6, 2}},
+			[]string{CapabilityChangeStreams},
+		},
+		{
+			"4.2",
+			mgo.BuildInfo{VersionArray: []int{4, 2, 0}},
+			[]string{CapabilityChangeStreams, CapabilityTransactions},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := makeCapabilities(tt.info); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeCapabilities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_makeExpiringDocument(t *testing.T) {
+
+	type object struct {
+		Name        string `bson:"name"`
+		Description string `bson:"description"`
+	}
+
+	expiration := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := makeExpiringDocument(&object{Name: "a", Description: "b"}, expiration)
+	if err != nil {
+		t.Fatalf("makeExpiringDocument() unexpected error: %s", err)
+	}
+
+	want := bson.D{
+		{Name: "name", Value: "a"},
+		{Name: "description", Value: "b"},
+		{Name: ExpirationField, Value: expiration},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("makeExpiringDocument() = %v, want %v", got, want)
+	}
+
+	if _, err := makeExpiringDocument("not a document", expiration); err == nil {
+		t.Errorf("makeExpiringDocument() expected an error")
+	}
+}
+
+func Test_makeIncrementOperations(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		field string
+		inc   int
+		want  bson.M
+	}{
+		{
+			"positive",
+			"counter",
+			2,
+			bson.M{"$inc": bson.M{"counter": 2}},
+		},
+		{
+			"negative",
+			"counter",
+			-1,
+			bson.M{"$inc": bson.M{"counter": -1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := makeIncrementOperations(tt.field, tt.inc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeIncrementOperations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resumeBulkInsert(t *testing.T) {
+
+	idDup := &mgo.LastError{Code: 11000, Err: "E11000 duplicate key error collection: db.list index: _id_ dup key: { : ObjectId('5f0f0f0f0f0f0f0f0f0f0f0f') }"}
+	nameDup := &mgo.LastError{Code: 11000, Err: "E11000 duplicate key error collection: db.list index: name_1 dup key: { : \"a\" }"}
+	other := &mgo.LastError{Code: 121, Err: "Document failed validation"}
+
+	type args struct {
+		cases   []mgo.BulkErrorCase
+		ordered bool
+		n       int
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantOffset int
+		wantErr    error
+	}{
+		{
+			"unordered with only _id duplicates",
+			args{[]mgo.BulkErrorCase{{Index: 0, Err: idDup}, {Index: 2, Err: idDup}}, false, 3},
+			-1,
+			nil,
+		},
+		{
+			"unordered with another duplicate",
+			args{[]mgo.BulkErrorCase{{Index: 0, Err: idDup}, {Index: 2, Err: nameDup}}, false, 3},
+			-1,
+			nameDup,
+		},
+		{
+			"unordered with another failure",
+			args{[]mgo.BulkErrorCase{{Index: 1, Err: other}}, false, 3},
+			-1,
+			other,
+		},
+		{
+			"ordered stopped on an _id duplicate",
+			args{[]mgo.BulkErrorCase{{Index: 1, Err: idDup}}, true, 3},
+			2,
+			nil,
+		},
+		{
+			"ordered stopped on the last _id duplicate",
+			args{[]mgo.BulkErrorCase{{Index: 2, Err: idDup}}, true, 3},
+			-1,
+			nil,
+		},
+		{
+			"ordered stopped on an unknown index",
+			args{[]mgo.BulkErrorCase{{Index: -1, Err: idDup}}, true, 3},
+			-1,
+			idDup,
+		},
+		{
+			"ordered stopped on another failure",
+			args{[]mgo.BulkErrorCase{{Index: 0, Err: other}}, true, 3},
+			-1,
+			other,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, err := resumeBulkInsert(tt.args.cases, tt.args.ordered, tt.args.n)
+			if offset != tt.wantOffset {
+				t.Errorf("resumeBulkInsert() offset = %v, want %v", offset, tt.wantOffset)
+			}
+			if err != tt.wantErr {
+				t.Errorf("resumeBulkInsert() err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type fakeCursor struct {
+	remaining int
+	read      int
+	closed    bool
+	onNext    func(read int)
+}
+
+func (c *fakeCursor) Next(result interface{}) bool {
+
+	if c.remaining == 0 {
+		return false
+	}
+
+	c.remaining--
+	c.read++
+	*(result.(*string)) = fmt.Sprintf("doc-%d", c.read)
+
+	if c.onNext != nil {
+		c.onNext(c.read)
+	}
+
+	return true
+}
+
+func (c *fakeCursor) Close() error {
+	c.closed = true
+	return nil
+}
+
+func Test_iterAll(t *testing.T) {
+
+	t.Run("all documents", func(t *testing.T) {
+
+		c := &fakeCursor{remaining: 3}
+		var dest []string
+
+		if err := iterAll(context.Background(), c, &dest); err != nil {
+			t.Fatalf("iterAll() unexpected error: %s", err)
+		}
+
+		if want := []string{"doc-1", "doc-2", "doc-3"}; !reflect.DeepEqual(dest, want) {
+			t.Errorf("iterAll() dest = %v, want %v", dest, want)
+		}
+
+		if !c.closed {
+			t.Errorf("iterAll() did not close the cursor")
+		}
+	})
+
+	t.Run("cancelled during iteration", func(t *testing.T) {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := &fakeCursor{
+			remaining: 1000000,
+			onNext: func(read int) {
+				if read == 10 {
+					cancel()
+				}
+			},
+		}
+		var dest []string
+
+		if err := iterAll(ctx, c, &dest); !errors.Is(err, context.Canceled) {
+			t.Fatalf("iterAll() error = %v, want %v", err, context.Canceled)
+		}
+
+		if c.read != 10 {
+			t.Errorf("iterAll() read %d documents, want 10", c.read)
+		}
+
+		if !c.closed {
+			t.Errorf("iterAll() did not close the cursor")
+		}
+	})
+
+	t.Run("invalid result", func(t *testing.T) {
+
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("iterAll() expected a panic")
+			}
+		}()
+
+		_ = iterAll(context.Background(), &fakeCursor{}, []string{})
+	})
+}
+
+func Test_scatterDocuments(t *testing.T) {
+
+	id1 := bson.NewObjectId().Hex()
+	id2 := bson.NewObjectId().Hex()
+	id3 := bson.NewObjectId().Hex()
+
+	makeDoc := func(id string, name string) bson.Raw {
+		data, err := bson.Marshal(&testmodel.List{ID: id, Name: name})
+		if err != nil {
+			t.Fatalf("unable to marshal document: %s", err)
+		}
+		return bson.Raw{Kind: 0x03, Data: data}
+	}
+
+	o1 := &testmodel.List{ID: id1}
+	o2 := &testmodel.List{ID: id2}
+	o3 := &testmodel.List{ID: id3}
+
+	found, missing, err := scatterDocuments(
+		[]bson.Raw{makeDoc(id2, "two"), makeDoc(id1, "one")},
+		[]elemental.Identifiable{o1, o2, o3},
+	)
+	if err != nil {
+		t.Fatalf("scatterDocuments() error = %v", err)
+	}
+
+	if o1.Name != "one" || o2.Name != "two" || o3.Name != "" {
+		t.Errorf("scatterDocuments() populated names %q, %q, %q, want %q, %q, %q", o1.Name, o2.Name, o3.Name, "one", "two", "")
+	}
+
+	if want := []elemental.Identifiable{o2, o1}; !reflect.DeepEqual(found, want) {
+		t.Errorf("scatterDocuments() found = %v, want %v", found, want)
+	}
+
+	if want := []string{id3}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("scatterDocuments() missing = %v, want %v", missing, want)
+	}
+
+	if _, _, err := scatterDocuments(
+		[]bson.Raw{{Kind: 0x03, Data: []byte("not bson")}},
+		[]elemental.Identifiable{o1},
+	); !errors.As(err, &manipulate.ErrCannotUnmarshal{}) {
+		t.Errorf("scatterDocuments() error = %v, want a manipulate.ErrCannotUnmarshal", err)
+	}
+}
@@ -13,6 +13,7 @@ package manipulate
 
 import (
 	"context"
+	"fmt"
 
 	"go.aporeto.io/elemental"
 )
@@ -73,6 +74,69 @@ type BufferedManipulator interface {
 	Manipulator
 }
 
+// ServerInfo contains information about the backend
+// a Manipulator is connected to.
+type ServerInfo struct {
+
+	// Backend is the type of the backend, like "mongo" or "http".
+	Backend string
+
+	// Version is the version of the backend, if known.
+	Version string
+
+	// Capabilities lists the optional features supported by the backend.
+	Capabilities []string
+}
+
+// HasCapability returns true if the ServerInfo lists the given capability.
+func (i ServerInfo) HasCapability(capability string) bool {
+
+	for _, c := range i.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A ServerInfoManipulator is a Manipulator that can retrieve information about
+// the backend it is connected to, allowing to detect the available features.
+type ServerInfoManipulator interface {
+
+	// ServerInfo returns information about the backend.
+	ServerInfo(ctx context.Context) (ServerInfo, error)
+
+	Manipulator
+}
+
+// A PingableManipulator is a Manipulator that can verify that
+// the backend it is connected to is reachable.
+type PingableManipulator interface {
+
+	// Ping returns an error if the backend cannot be reached.
+	Ping(ctx context.Context) error
+
+	Manipulator
+}
+
+// Ping verifies that the backend of the given Manipulator is reachable.
+// It returns an ErrNotImplemented if the Manipulator is not a
+// PingableManipulator. This is meant to be used in health checks.
+func Ping(ctx context.Context, m Manipulator) error {
+
+	if m == nil {
+		panic("manipulator must not be nil")
+	}
+
+	p, ok := m.(PingableManipulator)
+	if !ok {
+		return ErrNotImplemented{Err: fmt.Errorf("manipulator %T does not support ping", m)}
+	}
+
+	return p.Ping(ctx)
+}
+
 // SubscriberStatus is the type of a subscriber status.
 type SubscriberStatus int
 
@@ -0,0 +1,81 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServerInfo_HasCapability(t *testing.T) {
+
+	Convey("Given I have a server info", t, func() {
+
+		info := ServerInfo{
+			Backend:      "mongo",
+			Version:      "4.2.0",
+			Capabilities: []string{"changestreams", "transactions"},
+		}
+
+		Convey("Then HasCapability should work", func() {
+			So(info.HasCapability("transactions"), ShouldBeTrue)
+			So(info.HasCapability("changestreams"), ShouldBeTrue)
+			So(info.HasCapability("nope"), ShouldBeFalse)
+			So(ServerInfo{}.HasCapability("transactions"), ShouldBeFalse)
+		})
+	})
+}
+
+type pingableManipulator struct {
+	Manipulator
+	err error
+	ctx context.Context
+}
+
+func (m *pingableManipulator) Ping(ctx context.Context) error {
+	m.ctx = ctx
+	return m.err
+}
+
+func TestPing(t *testing.T) {
+
+	Convey("Calling Ping with a nil manipulator should panic", t, func() {
+		So(func() { _ = Ping(context.Background(), nil) }, ShouldPanicWith, "manipulator must not be nil")
+	})
+
+	Convey("Calling Ping on a pingable manipulator should work", t, func() {
+
+		ctx := context.Background()
+		m := &pingableManipulator{}
+
+		So(Ping(ctx, m), ShouldBeNil)
+		So(m.ctx, ShouldEqual, ctx)
+	})
+
+	Convey("Calling Ping on a failing pingable manipulator should return the error", t, func() {
+
+		m := &pingableManipulator{err: errors.New("boom")}
+
+		So(Ping(context.Background(), m), ShouldEqual, m.err)
+	})
+
+	Convey("Calling Ping on a manipulator that is not pingable should fail", t, func() {
+
+		err := Ping(context.Background(), &failingManipulator{})
+
+		So(err, ShouldHaveSameTypeAs, ErrNotImplemented{})
+		So(err.Error(), ShouldEqual, "Not implemented: manipulator *manipulate.failingManipulator does not support ping")
+	})
+}
@@ -46,6 +46,7 @@ type vortexManipulator struct {
 	upstreamReconciler      Reconciler
 	downstreamReconciler    Reconciler
 	disableUpstreamCommit   bool
+	logger                  *zap.Logger
 
 	sync.RWMutex
 }
@@ -97,6 +98,7 @@ func New(
 		defaultQueueDuration:    cfg.defaultQueueDuration,
 		subscribers:             []*vortexSubscriber{},
 		commitIdentityEvent:     map[string]struct{}{},
+		logger:                  cfg.logger,
 	}
 
 	if m.enableLog {
@@ -208,9 +210,55 @@ func (m *vortexManipulator) RetrieveMany(mctx manipulate.Context, dest elemental
 		}
 	}
 
+	// If the caller asked to bypass the cache, we read from upstream
+	// and refresh the local cache with the result.
+	if mctx.BypassCache() && m.upstreamManipulator != nil {
+		return m.refreshMany(mctx, dest)
+	}
+
 	return m.downstreamManipulator.RetrieveMany(mctx, dest)
 }
 
+// refreshMany retrieves the objects from upstream and stores them in
+// the local cache. When the result is not paginated, the cached objects
+// missing from it have been deleted upstream, so they are evicted.
+func (m *vortexManipulator) refreshMany(mctx manipulate.Context, dest elemental.Identifiables) error {
+
+	if err := m.upstreamManipulator.RetrieveMany(mctx, dest); err != nil {
+		return err
+	}
+
+	fresh := make(map[string]struct{}, len(dest.List()))
+	for _, o := range dest.List() {
+		fresh[o.Identifier()] = struct{}{}
+		if err := m.downstreamManipulator.Create(mctx, o); err != nil {
+			return fmt.Errorf("unable to update local cache from backend: %s", err)
+		}
+	}
+
+	if mctx.Page() != 0 || mctx.Limit() != 0 || mctx.After() != "" {
+		return nil
+	}
+
+	cached := m.model.Identifiables(dest.Identity())
+	if err := m.downstreamManipulator.RetrieveMany(mctx.Derive(), cached); err != nil {
+		return fmt.Errorf("unable to retrieve local cache: %s", err)
+	}
+
+	for _, o := range cached.List() {
+
+		if _, ok := fresh[o.Identifier()]; ok {
+			continue
+		}
+
+		if err := m.downstreamManipulator.Delete(mctx, o); err != nil && !manipulate.IsObjectNotFoundError(err) {
+			return fmt.Errorf("unable to evict deleted object from local cache: %s", err)
+		}
+	}
+
+	return nil
+}
+
 func (m *vortexManipulator) Retrieve(mctx manipulate.Context, object elemental.Identifiable) error {
 
 	m.RLock()
@@ -239,6 +287,24 @@ func (m *vortexManipulator) Retrieve(mctx manipulate.Context, object elemental.I
 		return nil
 	}
 
+	// If the caller asked to bypass the cache, we read from upstream
+	// and refresh the local cache with the result.
+	if mctx.BypassCache() && m.upstreamManipulator != nil {
+
+		if err := m.upstreamManipulator.Retrieve(mctx, object); err != nil {
+			if manipulate.IsObjectNotFoundError(err) {
+				_ = m.downstreamManipulator.Delete(mctx, object)
+			}
+			return err
+		}
+
+		if err := m.downstreamManipulator.Create(mctx, object); err != nil {
+			return fmt.Errorf("unable to update local cache from backend: %s", err)
+		}
+
+		return nil
+	}
+
 	if err := m.downstreamManipulator.Retrieve(mctx, object); err != nil {
 
 		// If we can't find it locally, and its strong consistency retrieve
@@ -626,13 +692,13 @@ func (m *vortexManipulator) backgroundSync(ctx context.Context) {
 
 			if err := m.commitUpstream(retryCtx, t.Method, t.mctx, t.Object); err != nil {
 				m.RUnlock()
-				zap.L().Error("failed to commit object upstream", zap.Error(err))
+				m.log().Error("failed to commit object upstream", zap.Error(err))
 				continue
 			}
 
 			// Update the local copy of the object now.
 			if err := m.commitLocal(t.Method, t.mctx, t.Object); err != nil {
-				zap.L().Error("failed to commit object downstream", zap.Error(err))
+				m.log().Error("failed to commit object downstream", zap.Error(err))
 			}
 
 			m.RUnlock()
@@ -716,7 +782,7 @@ func (m *vortexManipulator) pushEvent(evt *elemental.Event) {
 			select {
 			case s.subscriberEventChannel <- evt.Duplicate():
 			default:
-				zap.L().Error("Subscriber event channel is full")
+				m.log().Error("Subscriber event channel is full")
 			}
 		}
 	}
@@ -731,7 +797,7 @@ func (m *vortexManipulator) pushStatus(status manipulate.SubscriberStatus) {
 		select {
 		case s.subscriberStatusChannel <- status:
 		default:
-			zap.L().Error("Subscriber status channel is full", zap.Int("status", int(status)))
+			m.log().Error("Subscriber status channel is full", zap.Int("status", int(status)))
 		}
 	}
 }
@@ -745,7 +811,7 @@ func (m *vortexManipulator) pushErrors(err error) {
 		select {
 		case s.subscriberErrorChannel <- err:
 		default:
-			zap.L().Error("Subscriber error channel is full", zap.Error(err))
+			m.log().Error("Subscriber error channel is full", zap.Error(err))
 		}
 	}
 }
@@ -835,3 +901,13 @@ func (m *vortexManipulator) warmUp(ctx context.Context) error {
 
 	return nil
 }
+
+// log returns the logger to use.
+func (m *vortexManipulator) log() *zap.Logger {
+
+	if m.logger != nil {
+		return m.logger
+	}
+
+	return zap.L()
+}
@@ -24,6 +24,7 @@ import (
 	"go.aporeto.io/manipulate"
 	"go.aporeto.io/manipulate/manipmemory"
 	"go.aporeto.io/manipulate/maniptest"
+	"go.uber.org/zap"
 )
 
 func newObject(name string, tags []string) *testmodel.List {
@@ -361,6 +362,74 @@ func Test_RetrieveMany(t *testing.T) {
 			So(len(objects), ShouldEqual, 2)
 		})
 
+		Convey("When I request a retrieve many while bypassing the cache, it should go to the backend and refresh the cache", func() {
+
+			fresh := newObject("fresh", []string{"a=b"})
+			fresh.ID = "ID1"
+
+			var called int
+			m.MockRetrieveMany(t, func(mctx manipulate.Context, dest elemental.Identifiables) error {
+				called++
+				*dest.(*testmodel.ListsList) = testmodel.ListsList{fresh}
+				return nil
+			})
+
+			mctx := manipulate.NewContext(ctx, manipulate.ContextOptionBypassCache(true))
+			objects := testmodel.ListsList{}
+			err := v.RetrieveMany(mctx, &objects)
+			So(err, ShouldBeNil)
+			So(called, ShouldEqual, 1)
+			So(len(objects), ShouldEqual, 1)
+
+			o := newObject("", []string{})
+			o.ID = "ID1"
+			So(v.Retrieve(nil, o), ShouldBeNil)
+			So(o.Name, ShouldEqual, "fresh")
+
+			n, err := d.Count(nil, testmodel.ListIdentity)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 1)
+		})
+
+		Convey("When I request a paginated retrieve many while bypassing the cache, it should not evict anything", func() {
+
+			fresh := newObject("fresh", []string{"a=b"})
+			fresh.ID = "ID1"
+
+			m.MockRetrieveMany(t, func(mctx manipulate.Context, dest elemental.Identifiables) error {
+				*dest.(*testmodel.ListsList) = testmodel.ListsList{fresh}
+				return nil
+			})
+
+			mctx := manipulate.NewContext(ctx, manipulate.ContextOptionBypassCache(true), manipulate.ContextOptionPage(1, 1))
+			objects := testmodel.ListsList{}
+			So(v.RetrieveMany(mctx, &objects), ShouldBeNil)
+
+			n, err := d.Count(nil, testmodel.ListIdentity)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 2)
+		})
+
+		Convey("When I request a retrieve many while bypassing the cache, it should still run the hook", func() {
+
+			objConfig.RetrieveManyHook = func(manipulate.Manipulator, manipulate.Context, elemental.Identifiables) (bool, error) {
+				return false, fmt.Errorf("hooked")
+			}
+
+			var called int
+			m.MockRetrieveMany(t, func(mctx manipulate.Context, dest elemental.Identifiables) error {
+				called++
+				return nil
+			})
+
+			mctx := manipulate.NewContext(ctx, manipulate.ContextOptionBypassCache(true))
+			objects := testmodel.ListsList{}
+			err := v.RetrieveMany(mctx, &objects)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "hooked")
+			So(called, ShouldEqual, 0)
+		})
+
 		Convey("When I request a retrieve many with no parent second page, it should retrieve no data", func() {
 			mctx := manipulate.NewContext(ctx, manipulate.ContextOptionPage(2, 100))
 			objects := testmodel.ListsList{}
@@ -456,6 +525,57 @@ func Test_Retrieve(t *testing.T) {
 			})
 		})
 
+		Convey("When I read a cached object while bypassing the cache", func() {
+
+			var called int
+			m.MockRetrieve(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+				called++
+				object.(*testmodel.List).Name = "fresh"
+				return nil
+			})
+
+			o := newObject("", []string{})
+			o.ID = "ID1"
+
+			mctx := manipulate.NewContext(ctx, manipulate.ContextOptionBypassCache(true))
+			err := v.Retrieve(mctx, o)
+
+			So(err, ShouldBeNil)
+			So(called, ShouldEqual, 1)
+			So(o.Name, ShouldEqual, "fresh")
+
+			Convey("... and the cache must have been refreshed", func() {
+				o := newObject("", []string{})
+				o.ID = "ID1"
+				err := v.Retrieve(nil, o)
+				So(err, ShouldBeNil)
+				So(o.Name, ShouldEqual, "fresh")
+				So(called, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I read a cached object that is gone from the backend while bypassing the cache", func() {
+
+			m.MockRetrieve(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+				return manipulate.ErrObjectNotFound{Err: fmt.Errorf("gone")}
+			})
+
+			o := newObject("", []string{})
+			o.ID = "ID1"
+
+			mctx := manipulate.NewContext(ctx, manipulate.ContextOptionBypassCache(true))
+			err := v.Retrieve(mctx, o)
+
+			So(manipulate.IsObjectNotFoundError(err), ShouldBeTrue)
+
+			Convey("... and the object must have been evicted from the cache", func() {
+				o := newObject("", []string{})
+				o.ID = "ID1"
+				err := v.Retrieve(nil, o)
+				So(err, ShouldNotBeNil)
+			})
+		})
+
 		// That doesn't seem possible now that memdb index are set to AllowingMissing
 
 		// Convey("When I read an invalid object, with consistency and the backend succeeds but cache fails", func() {
@@ -1584,3 +1704,25 @@ func Test_updateFilter(t *testing.T) {
 	})
 
 }
+
+func TestManipulator_log(t *testing.T) {
+
+	Convey("Given I have a manipulator without logger", t, func() {
+
+		m := &vortexManipulator{}
+
+		Convey("Then it should use the global logger", func() {
+			So(m.log(), ShouldEqual, zap.L())
+		})
+	})
+
+	Convey("Given I have a manipulator with a logger", t, func() {
+
+		logger := zap.NewNop()
+		m := &vortexManipulator{logger: logger}
+
+		Convey("Then it should use it", func() {
+			So(m.log(), ShouldEqual, logger)
+		})
+	})
+}
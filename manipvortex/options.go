@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"go.aporeto.io/manipulate"
+	"go.uber.org/zap"
 )
 
 type config struct {
@@ -31,6 +32,7 @@ type config struct {
 	upstreamReconciler    Reconciler
 	downstreamReconciler  Reconciler
 	disableUpstreamCommit bool
+	logger                *zap.Logger
 }
 
 func newConfig() *config {
@@ -132,3 +134,12 @@ func OptionDisableCommitUpstream(disabled bool) Option {
 		cfg.disableUpstreamCommit = disabled
 	}
 }
+
+// OptionLogger sets the logger used by the manipulator to report
+// the errors happening in the background. The default is the global
+// zap logger at the time the errors are logged.
+func OptionLogger(logger *zap.Logger) Option {
+	return func(cfg *config) {
+		cfg.logger = logger
+	}
+}
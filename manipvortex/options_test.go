@@ -19,6 +19,7 @@ import (
 
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/manipulate/maniptest"
+	"go.uber.org/zap"
 )
 
 func Test_newOptions(t *testing.T) {
@@ -38,6 +39,7 @@ func Test_newOptions(t *testing.T) {
 			So(cfg.defaultPageSize, ShouldEqual, 10000)
 			So(cfg.upstreamReconciler, ShouldBeNil)
 			So(cfg.downstreamReconciler, ShouldBeNil)
+			So(cfg.logger, ShouldBeNil)
 		})
 	})
 }
@@ -115,5 +117,11 @@ func Test_Options(t *testing.T) {
 			OptionDisableCommitUpstream(true)(cfg)
 			So(cfg.disableUpstreamCommit, ShouldBeTrue)
 		})
+
+		Convey("OptionLogger should work", func() {
+			logger := zap.NewNop()
+			OptionLogger(logger)(cfg)
+			So(cfg.logger, ShouldEqual, logger)
+		})
 	})
 }
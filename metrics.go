@@ -0,0 +1,40 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"time"
+
+	"go.aporeto.io/elemental"
+)
+
+// A MetricsRecorder records metrics about the operations
+// performed by a Manipulator, like their count, latency or errors.
+// The manipulators supporting it call it once each operation
+// completed, successfully or not. It must be safe for concurrent use.
+type MetricsRecorder interface {
+
+	// RecordOperation records that the given operation on objects of the given
+	// identity completed after the given duration, with the given error, if any.
+	RecordOperation(operation elemental.Operation, identity elemental.Identity, duration time.Duration, err error)
+}
+
+type noopMetricsRecorder struct{}
+
+// NewNoopMetricsRecorder returns a MetricsRecorder that records nothing.
+// This is the default of the manipulators supporting a MetricsRecorder.
+func NewNoopMetricsRecorder() MetricsRecorder {
+	return noopMetricsRecorder{}
+}
+
+func (noopMetricsRecorder) RecordOperation(elemental.Operation, elemental.Identity, time.Duration, error) {
+}
@@ -0,0 +1,36 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+func TestNewNoopMetricsRecorder(t *testing.T) {
+
+	Convey("Given I have a noop metrics recorder", t, func() {
+
+		r := NewNoopMetricsRecorder()
+
+		Convey("Then recording an operation should work", func() {
+			So(func() {
+				r.RecordOperation(elemental.OperationCreate, testmodel.ListIdentity, time.Second, errors.New("boom"))
+			}, ShouldNotPanic)
+		})
+	})
+}
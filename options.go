@@ -198,3 +198,20 @@ func ContextOptionOpaque(o map[string]interface{}) ContextOption {
 		c.(*mcontext).opaque = o
 	}
 }
+
+// ContextOptionBypassCache asks caching manipulators to skip their
+// cache and to read the data from their backend, refreshing the cache
+// with the result. This is useful to get a guaranteed fresh read
+// after a write made elsewhere.
+//
+// When retrieving many objects without pagination, the objects missing
+// from the backend result are also evicted from the cache. Paginated
+// results cannot tell which objects have been deleted, so such objects
+// stay cached until they are refreshed another way.
+//
+// This is a no-op for non caching manipulators.
+func ContextOptionBypassCache(bypass bool) ContextOption {
+	return func(c Context) {
+		c.(*mcontext).bypassCache = bypass
+	}
+}
@@ -151,4 +151,9 @@ func TestManipulate_ContextOption(t *testing.T) {
 		ContextOptionOpaque(m)(mctx.(*mcontext))
 		So(mctx.(*mcontext).opaque, ShouldEqual, m)
 	})
+
+	Convey("Calling ContextOptionBypassCache should work", t, func() {
+		ContextOptionBypassCache(true)(mctx.(*mcontext))
+		So(mctx.BypassCache(), ShouldBeTrue)
+	})
 }
@@ -0,0 +1,56 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"fmt"
+
+	"go.aporeto.io/elemental"
+)
+
+type readOnlyManipulator struct {
+	Manipulator
+}
+
+// NewReadOnlyManipulator returns a Manipulator that only allows the
+// read operations of the given Manipulator. Create, Update, Delete
+// and DeleteMany return an ErrReadOnly without reaching it.
+func NewReadOnlyManipulator(manipulator Manipulator) Manipulator {
+
+	if manipulator == nil {
+		panic("manipulator must not be nil")
+	}
+
+	return &readOnlyManipulator{
+		Manipulator: manipulator,
+	}
+}
+
+func (m *readOnlyManipulator) Create(_ Context, object elemental.Identifiable) error {
+	return readOnlyError(elemental.OperationCreate, object.Identity())
+}
+
+func (m *readOnlyManipulator) Update(_ Context, object elemental.Identifiable) error {
+	return readOnlyError(elemental.OperationUpdate, object.Identity())
+}
+
+func (m *readOnlyManipulator) Delete(_ Context, object elemental.Identifiable) error {
+	return readOnlyError(elemental.OperationDelete, object.Identity())
+}
+
+func (m *readOnlyManipulator) DeleteMany(_ Context, identity elemental.Identity) error {
+	return readOnlyError(elemental.OperationDelete, identity)
+}
+
+func readOnlyError(operation elemental.Operation, identity elemental.Identity) error {
+	return ErrReadOnly{Err: fmt.Errorf("%s on %s is not allowed", operation, identity.Name)}
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+func TestReadOnlyManipulator(t *testing.T) {
+
+	Convey("Calling NewReadOnlyManipulator with a nil manipulator should panic", t, func() {
+		So(func() { NewReadOnlyManipulator(nil) }, ShouldPanicWith, "manipulator must not be nil")
+	})
+
+	Convey("Given I have a read only manipulator", t, func() {
+
+		rm := newRecordingManipulator(errors.New("backend"))
+		m := NewReadOnlyManipulator(rm)
+
+		Convey("When I perform write operations", func() {
+
+			errCreate := m.Create(nil, testmodel.NewList())
+			errUpdate := m.Update(nil, testmodel.NewList())
+			errDelete := m.Delete(nil, testmodel.NewList())
+			errDeleteMany := m.DeleteMany(nil, testmodel.ListIdentity)
+
+			Convey("Then they should be rejected", func() {
+				So(IsReadOnlyError(errCreate), ShouldBeTrue)
+				So(IsReadOnlyError(errUpdate), ShouldBeTrue)
+				So(IsReadOnlyError(errDelete), ShouldBeTrue)
+				So(IsReadOnlyError(errDeleteMany), ShouldBeTrue)
+				So(errCreate.Error(), ShouldEqual, "Read only: create on list is not allowed")
+			})
+
+			Convey("Then the backend should not have been reached", func() {
+				So(rm.operations(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I perform read operations", func() {
+
+			errRetrieve := m.Retrieve(nil, testmodel.NewList())
+			errRetrieveMany := m.RetrieveMany(nil, &testmodel.ListsList{})
+			_, errCount := m.Count(nil, testmodel.ListIdentity)
+
+			Convey("Then they should reach the backend", func() {
+				So(errRetrieve, ShouldEqual, rm.err)
+				So(errRetrieveMany, ShouldEqual, rm.err)
+				So(errCount, ShouldEqual, rm.err)
+			})
+		})
+	})
+}
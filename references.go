@@ -0,0 +1,157 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.aporeto.io/elemental"
+)
+
+// A Reference describes a field of an object
+// holding the identifier of other objects.
+type Reference struct {
+
+	// Field is the name of the struct field holding the identifier
+	// of the referenced object. It can be a string or a []string.
+	// The lookup is case insensitive.
+	Field string
+
+	// Identity is the identity of the referenced objects.
+	Identity elemental.Identity
+}
+
+// ResolvedReferences holds the objects retrieved by ResolveReferences,
+// indexed by identity and identifier.
+type ResolvedReferences map[elemental.Identity]map[string]elemental.Identifiable
+
+// Get returns the resolved object with the given identity and identifier,
+// or nil if it has not been found.
+func (r ResolvedReferences) Get(identity elemental.Identity, id string) elemental.Identifiable {
+	return r[identity][id]
+}
+
+// ResolveReferences retrieves all the objects referenced by the given objects
+// through the given references. Instead of retrieving each referenced object
+// one by one, it issues a single RetrieveMany per referenced identity, using
+// a filter on the identifiers.
+//
+// The given manipulate.Context is derived for each lookup, with its filter,
+// parent, pagination, ordering and fields reset. If it is nil, a new one
+// using context.Background() is used. The elemental.ModelManager is used to
+// create the destination of each lookup.
+//
+// Only one level of references is resolved: references held by the resolved
+// objects are not followed. Any manipulator supporting a Contains filter on
+// the ID attribute, like manipmongo and manipmemory, can be used.
+func ResolveReferences(
+	mctx Context,
+	manipulator Manipulator,
+	manager elemental.ModelManager,
+	objects elemental.Identifiables,
+	references ...Reference,
+) (ResolvedReferences, error) {
+
+	if manipulator == nil {
+		panic("manipulator must not be nil")
+	}
+
+	if manager == nil {
+		panic("manager must not be nil")
+	}
+
+	if mctx == nil {
+		mctx = NewContext(context.Background())
+	}
+
+	ids := map[elemental.Identity][]interface{}{}
+	seen := map[elemental.Identity]map[string]struct{}{}
+
+	for _, o := range objects.List() {
+		for _, ref := range references {
+
+			values, err := referenceValues(o, ref.Field)
+			if err != nil {
+				return nil, ErrCannotBuildQuery{Err: err}
+			}
+
+			if seen[ref.Identity] == nil {
+				seen[ref.Identity] = map[string]struct{}{}
+			}
+
+			for _, v := range values {
+				if _, ok := seen[ref.Identity][v]; ok || v == "" {
+					continue
+				}
+				seen[ref.Identity][v] = struct{}{}
+				ids[ref.Identity] = append(ids[ref.Identity], v)
+			}
+		}
+	}
+
+	out := ResolvedReferences{}
+
+	for identity, values := range ids {
+
+		dest := manager.Identifiables(identity)
+		if dest == nil {
+			return nil, ErrCannotBuildQuery{Err: fmt.Errorf("unknown identity '%s'", identity.Name)}
+		}
+
+		smctx := mctx.Derive(
+			ContextOptionFilter(elemental.NewFilterComposer().WithKey("ID").Contains(values...).Done()),
+			ContextOptionParent(nil),
+			ContextOptionPage(0, 0),
+			ContextOptionAfter("", 0),
+			ContextOptionOrder(),
+			ContextOptionFields(nil),
+		)
+
+		if err := manipulator.RetrieveMany(smctx, dest); err != nil {
+			return nil, fmt.Errorf("unable to resolve references to '%s': %w", identity.Name, err)
+		}
+
+		resolved := make(map[string]elemental.Identifiable, len(values))
+		for _, o := range dest.List() {
+			resolved[o.Identifier()] = o
+		}
+
+		out[identity] = resolved
+	}
+
+	return out, nil
+}
+
+func referenceValues(o elemental.Identifiable, field string) ([]string, error) {
+
+	v := reflect.Indirect(reflect.ValueOf(o))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("object of identity '%s' is not a struct", o.Identity().Name)
+	}
+
+	fv := v.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, field) })
+	if !fv.IsValid() {
+		return nil, fmt.Errorf("object of identity '%s' has no field '%s'", o.Identity().Name, field)
+	}
+
+	switch r := fv.Interface().(type) {
+	case string:
+		return []string{r}, nil
+	case []string:
+		return r, nil
+	default:
+		return nil, fmt.Errorf("field '%s' of identity '%s' must be a string or a []string", field, o.Identity().Name)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+type referenceManipulator struct {
+	Manipulator
+	lists   map[string]*testmodel.List
+	queries int
+	mctx    Context
+	err     error
+}
+
+func (m *referenceManipulator) RetrieveMany(mctx Context, dest elemental.Identifiables) error {
+
+	m.queries++
+	m.mctx = mctx
+
+	if m.err != nil {
+		return m.err
+	}
+
+	for _, v := range mctx.Filter().Values()[0] {
+		if l, ok := m.lists[v.(string)]; ok {
+			*dest.(*testmodel.ListsList) = append(*dest.(*testmodel.ListsList), l)
+		}
+	}
+
+	return nil
+}
+
+func TestResolveReferences(t *testing.T) {
+
+	Convey("Given I have a manipulator and some tasks referencing lists", t, func() {
+
+		m := &referenceManipulator{
+			lists: map[string]*testmodel.List{
+				"l1": {ID: "l1", Name: "list1"},
+				"l2": {ID: "l2", Name: "list2"},
+			},
+		}
+
+		tasks := testmodel.TasksList{
+			{ID: "t1", ParentID: "l1"},
+			{ID: "t2", ParentID: "l2"},
+			{ID: "t3", ParentID: "l1"},
+			{ID: "t4", ParentID: "l3"},
+			{ID: "t5"},
+		}
+
+		mctx := NewContext(
+			context.Background(),
+			ContextOptionFilter(elemental.NewFilterComposer().WithKey("name").Equals("x").Done()),
+			ContextOptionPage(2, 10),
+			ContextOptionNamespace("/a"),
+		)
+
+		Convey("When I call ResolveReferences", func() {
+
+			refs, err := ResolveReferences(
+				mctx,
+				m,
+				testmodel.Manager(),
+				tasks,
+				Reference{Field: "parentID", Identity: testmodel.ListIdentity},
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then a single query should have been issued", func() {
+				So(m.queries, ShouldEqual, 1)
+				So(m.mctx.Filter().String(), ShouldEqual, `ID contains ["l1", "l2", "l3"]`)
+				So(m.mctx.Page(), ShouldEqual, 0)
+				So(m.mctx.PageSize(), ShouldEqual, 0)
+				So(m.mctx.Namespace(), ShouldEqual, "/a")
+			})
+
+			Convey("Then the references should be resolved", func() {
+				So(len(refs[testmodel.ListIdentity]), ShouldEqual, 2)
+				So(refs.Get(testmodel.ListIdentity, "l1").(*testmodel.List).Name, ShouldEqual, "list1")
+				So(refs.Get(testmodel.ListIdentity, "l2").(*testmodel.List).Name, ShouldEqual, "list2")
+				So(refs.Get(testmodel.ListIdentity, "l3"), ShouldBeNil)
+			})
+
+			Convey("Then the original context should be untouched", func() {
+				So(mctx.Filter().String(), ShouldEqual, `name == "x"`)
+				So(mctx.Page(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I call ResolveReferences with a nil context", func() {
+
+			refs, err := ResolveReferences(
+				nil,
+				m,
+				testmodel.Manager(),
+				tasks,
+				Reference{Field: "parentID", Identity: testmodel.ListIdentity},
+			)
+
+			Convey("Then the references should be resolved", func() {
+				So(err, ShouldBeNil)
+				So(m.queries, ShouldEqual, 1)
+				So(m.mctx.Filter().String(), ShouldEqual, `ID contains ["l1", "l2", "l3"]`)
+				So(len(refs[testmodel.ListIdentity]), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I call ResolveReferences with no objects", func() {
+
+			refs, err := ResolveReferences(
+				mctx,
+				m,
+				testmodel.Manager(),
+				testmodel.TasksList{},
+				Reference{Field: "ParentID", Identity: testmodel.ListIdentity},
+			)
+
+			Convey("Then no query should have been issued", func() {
+				So(err, ShouldBeNil)
+				So(len(refs), ShouldEqual, 0)
+				So(m.queries, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I call ResolveReferences with an unknown field", func() {
+
+			_, err := ResolveReferences(
+				mctx,
+				m,
+				testmodel.Manager(),
+				tasks,
+				Reference{Field: "nope", Identity: testmodel.ListIdentity},
+			)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(IsCannotBuildQueryError(err), ShouldBeTrue)
+				So(m.queries, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I call ResolveReferences with a field that is not a string", func() {
+
+			_, err := ResolveReferences(
+				mctx,
+				m,
+				testmodel.Manager(),
+				tasks,
+				Reference{Field: "Status", Identity: testmodel.ListIdentity},
+			)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(IsCannotBuildQueryError(err), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the manipulator returns an error", func() {
+
+			m.err = ErrCannotCommunicate{Err: errors.New("boom")}
+
+			_, err := ResolveReferences(
+				mctx,
+				m,
+				testmodel.Manager(),
+				tasks,
+				Reference{Field: "ParentID", Identity: testmodel.ListIdentity},
+			)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(errors.As(err, &ErrCannotCommunicate{}), ShouldBeTrue)
+			})
+		})
+
+		Convey("When I call ResolveReferences with a nil manipulator", func() {
+			So(func() { _, _ = ResolveReferences(mctx, nil, testmodel.Manager(), tasks) }, ShouldPanicWith, "manipulator must not be nil")
+		})
+
+		Convey("When I call ResolveReferences with a nil manager", func() {
+			So(func() { _, _ = ResolveReferences(mctx, m, nil, tasks) }, ShouldPanicWith, "manager must not be nil")
+		})
+	})
+}
@@ -13,7 +13,10 @@ package manipulate
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 )
 
 // Retry only calls manipulateFunc for backward compatibility.
@@ -23,3 +26,109 @@ func Retry(ctx context.Context, manipulateFunc func() error, onRetryFunc func(in
 	fmt.Println("DEPRECATED: manipulate.Retry is deprecated. Retry mechanism is now part of Manipulator implementations. You can safely remove this wrapper.")
 	return manipulateFunc()
 }
+
+// NewExponentialRetryFunc returns a RetryFunc that lets the manipulator retry
+// a failed operation up to maxRetries times. Before each retry, it waits for
+// base * 2^try, capped to max. Once maxRetries is reached, it returns the
+// error that caused the retry, which interrupts the retry procedure.
+//
+// The wait is interrupted if the context of the operation is done.
+// Note that manipulators still apply their own backoff after the RetryFunc returns.
+func NewExponentialRetryFunc(maxRetries int, base time.Duration, max time.Duration) RetryFunc {
+
+	if maxRetries < 0 {
+		panic("maxRetries must not be negative")
+	}
+
+	if base <= 0 {
+		panic("base must be positive")
+	}
+
+	if max < base {
+		panic("max must be greater or equal to base")
+	}
+
+	return func(info RetryInfo) error {
+
+		try := info.Try()
+		if try >= maxRetries {
+			return info.Err()
+		}
+
+		wait := max
+		if try < 62 && base <= max>>uint(try) {
+			wait = base << uint(try)
+		}
+
+		ctx := context.Background()
+		if mctx := info.Context(); mctx != nil && mctx.Context() != nil {
+			ctx = mctx.Context()
+		}
+
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return info.Err()
+		}
+	}
+}
+
+// NewCircuitBreakerRetryFunc returns a RetryFunc that stops the retries once
+// threshold communication failures happened, without more than cooldown between
+// two of them. The circuit then opens for the given cooldown: every operation
+// failing during that time returns an ErrCannotCommunicate right away instead
+// of being retried. Once the cooldown passed, the retries are allowed again.
+//
+// The returned RetryFunc is meant to be shared by all the operations
+// hitting the same backend, so they protect it together when it is struggling.
+// Errors that are not an ErrCannotCommunicate are not counted.
+func NewCircuitBreakerRetryFunc(threshold int, cooldown time.Duration) RetryFunc {
+
+	if threshold <= 0 {
+		panic("threshold must be positive")
+	}
+
+	if cooldown <= 0 {
+		panic("cooldown must be positive")
+	}
+
+	var lock sync.Mutex
+	var failures int
+	var lastFailure time.Time
+	var openUntil time.Time
+
+	return func(info RetryInfo) error {
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		now := time.Now()
+
+		if now.Before(openUntil) {
+			return ErrCannotCommunicate{Err: fmt.Errorf("circuit breaker open: %w", info.Err())}
+		}
+
+		if !errors.As(info.Err(), &ErrCannotCommunicate{}) {
+			return nil
+		}
+
+		if now.Sub(lastFailure) > cooldown {
+			failures = 0
+		}
+
+		failures++
+		lastFailure = now
+
+		if failures >= threshold {
+			failures = 0
+			openUntil = now.Add(cooldown)
+			return ErrCannotCommunicate{Err: fmt.Errorf("circuit breaker open: %w", info.Err())}
+		}
+
+		return nil
+	}
+}
@@ -13,7 +13,9 @@ package manipulate
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -39,3 +41,159 @@ func TestManipulate_Retry(t *testing.T) {
 		})
 	})
 }
+
+type testRetryInfo struct {
+	err  error
+	try  int
+	mctx Context
+}
+
+func (i testRetryInfo) Err() error       { return i.err }
+func (i testRetryInfo) Try() int         { return i.try }
+func (i testRetryInfo) Context() Context { return i.mctx }
+
+func TestNewExponentialRetryFunc(t *testing.T) {
+
+	Convey("Calling NewExponentialRetryFunc with invalid arguments should panic", t, func() {
+		So(func() { NewExponentialRetryFunc(-1, time.Second, time.Second) }, ShouldPanicWith, "maxRetries must not be negative")
+		So(func() { NewExponentialRetryFunc(1, 0, time.Second) }, ShouldPanicWith, "base must be positive")
+		So(func() { NewExponentialRetryFunc(1, time.Second, time.Millisecond) }, ShouldPanicWith, "max must be greater or equal to base")
+	})
+
+	Convey("Given I have an exponential retry func", t, func() {
+
+		rerr := errors.New("boom")
+		f := NewExponentialRetryFunc(3, 10*time.Millisecond, 25*time.Millisecond)
+		mctx := NewContext(context.Background())
+
+		Convey("When I call it for the first tries", func() {
+
+			waits := make([]time.Duration, 3)
+			errs := make([]error, 3)
+			for i := 0; i < 3; i++ {
+				now := time.Now()
+				errs[i] = f(testRetryInfo{err: rerr, try: i, mctx: mctx})
+				waits[i] = time.Since(now)
+			}
+
+			Convey("Then it should allow the retries", func() {
+				So(errs, ShouldResemble, []error{nil, nil, nil})
+			})
+
+			Convey("Then it should have waited exponentially up to max", func() {
+				So(waits[0], ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+				So(waits[1], ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+				So(waits[2], ShouldBeGreaterThanOrEqualTo, 25*time.Millisecond)
+				So(waits[2], ShouldBeLessThan, 40*time.Millisecond)
+			})
+		})
+
+		Convey("When I call it once the max retries is reached", func() {
+
+			now := time.Now()
+			err := f(testRetryInfo{err: rerr, try: 3, mctx: mctx})
+
+			Convey("Then it should return the error without waiting", func() {
+				So(err, ShouldEqual, rerr)
+				So(time.Since(now), ShouldBeLessThan, 10*time.Millisecond)
+			})
+		})
+
+		Convey("When I call it with a canceled context", func() {
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := f(testRetryInfo{err: rerr, try: 0, mctx: NewContext(ctx)})
+
+			Convey("Then it should return the error", func() {
+				So(err, ShouldEqual, rerr)
+			})
+		})
+
+		Convey("When I call it with a large try number", func() {
+
+			f := NewExponentialRetryFunc(100, time.Millisecond, 5*time.Millisecond)
+			now := time.Now()
+			err := f(testRetryInfo{err: rerr, try: 80, mctx: mctx})
+
+			Convey("Then it should wait for max", func() {
+				So(err, ShouldBeNil)
+				So(time.Since(now), ShouldBeLessThan, 50*time.Millisecond)
+			})
+		})
+	})
+}
+
+func TestNewCircuitBreakerRetryFunc(t *testing.T) {
+
+	Convey("Calling NewCircuitBreakerRetryFunc with invalid arguments should panic", t, func() {
+		So(func() { NewCircuitBreakerRetryFunc(0, time.Second) }, ShouldPanicWith, "threshold must be positive")
+		So(func() { NewCircuitBreakerRetryFunc(1, 0) }, ShouldPanicWith, "cooldown must be positive")
+	})
+
+	Convey("Given I have a circuit breaker retry func", t, func() {
+
+		cerr := ErrCannotCommunicate{Err: errors.New("boom")}
+		f := NewCircuitBreakerRetryFunc(3, 50*time.Millisecond)
+
+		Convey("When less failures than the threshold happen", func() {
+
+			err1 := f(testRetryInfo{err: cerr})
+			err2 := f(testRetryInfo{err: cerr})
+
+			Convey("Then the retries should be allowed", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldBeNil)
+			})
+		})
+
+		Convey("When the threshold is reached", func() {
+
+			_ = f(testRetryInfo{err: cerr})
+			_ = f(testRetryInfo{err: cerr})
+			err := f(testRetryInfo{err: cerr})
+
+			Convey("Then the circuit should open", func() {
+				So(IsCannotCommunicateError(err), ShouldBeTrue)
+				So(errors.Is(err, cerr), ShouldBeTrue)
+				So(err.Error(), ShouldEqual, "Cannot communicate: circuit breaker open: Cannot communicate: boom")
+			})
+
+			Convey("Then the following retries should be short circuited", func() {
+				So(IsCannotCommunicateError(f(testRetryInfo{err: cerr})), ShouldBeTrue)
+				So(IsCannotCommunicateError(f(testRetryInfo{err: errors.New("other")})), ShouldBeTrue)
+			})
+
+			Convey("Then the retries should be allowed again after the cooldown", func() {
+				time.Sleep(60 * time.Millisecond)
+				So(f(testRetryInfo{err: cerr}), ShouldBeNil)
+				So(f(testRetryInfo{err: cerr}), ShouldBeNil)
+				So(IsCannotCommunicateError(f(testRetryInfo{err: cerr})), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the failures are spread over more than the cooldown", func() {
+
+			_ = f(testRetryInfo{err: cerr})
+			_ = f(testRetryInfo{err: cerr})
+			time.Sleep(60 * time.Millisecond)
+			err := f(testRetryInfo{err: cerr})
+
+			Convey("Then the circuit should stay closed", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When other errors happen", func() {
+
+			for i := 0; i < 5; i++ {
+				So(f(testRetryInfo{err: errors.New("other")}), ShouldBeNil)
+			}
+
+			Convey("Then the circuit should stay closed", func() {
+				So(f(testRetryInfo{err: cerr}), ShouldBeNil)
+			})
+		})
+	})
+}
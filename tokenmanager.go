@@ -0,0 +1,83 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// A periodicTokenManager is a TokenManager using a
+// function to issue a new token at a fixed interval.
+type periodicTokenManager struct {
+	issuer   func(context.Context) (string, error)
+	interval time.Duration
+}
+
+// NewPeriodicTokenManager returns a TokenManager issuing tokens using the
+// given function, and renewing them at the given interval. This allows to
+// use any authentication provider with the manipulators supporting a
+// TokenManager. If the renewal fails, the error is logged and the token is
+// renewed again at the next interval.
+// If the issuer is nil or if the interval is not positive,
+// NewPeriodicTokenManager will panic.
+func NewPeriodicTokenManager(issuer func(context.Context) (string, error), interval time.Duration) TokenManager {
+
+	if issuer == nil {
+		panic("issuer must not be nil")
+	}
+
+	if interval <= 0 {
+		panic("interval must be positive")
+	}
+
+	return &periodicTokenManager{
+		issuer:   issuer,
+		interval: interval,
+	}
+}
+
+// Issue is part of the implementation of the TokenManager interface.
+func (m *periodicTokenManager) Issue(ctx context.Context) (string, error) {
+	return m.issuer(ctx)
+}
+
+// Run is part of the implementation of the TokenManager interface.
+func (m *periodicTokenManager) Run(ctx context.Context, tokenCh chan string) {
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ticker.C:
+
+			token, err := m.issuer(ctx)
+			if err != nil {
+				zap.L().Error("Unable to renew token", zap.Error(err))
+				continue
+			}
+
+			select {
+			case tokenCh <- token:
+			case <-ctx.Done():
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
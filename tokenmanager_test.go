@@ -0,0 +1,85 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manipulate
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewPeriodicTokenManager(t *testing.T) {
+
+	Convey("Calling NewPeriodicTokenManager with a nil issuer should panic", t, func() {
+		So(func() { NewPeriodicTokenManager(nil, time.Second) }, ShouldPanicWith, "issuer must not be nil")
+	})
+
+	Convey("Calling NewPeriodicTokenManager with a zero interval should panic", t, func() {
+		So(func() { NewPeriodicTokenManager(func(context.Context) (string, error) { return "", nil }, 0) }, ShouldPanicWith, "interval must be positive")
+	})
+
+	Convey("Given I have a periodic token manager", t, func() {
+
+		var n int32
+		tm := NewPeriodicTokenManager(
+			func(context.Context) (string, error) {
+				i := atomic.AddInt32(&n, 1)
+				if i == 2 {
+					return "", fmt.Errorf("boom")
+				}
+				return fmt.Sprintf("token-%d", i), nil
+			},
+			10*time.Millisecond,
+		)
+
+		Convey("When I call Issue", func() {
+
+			token, err := tm.Issue(context.Background())
+
+			Convey("Then the token should be issued by the issuer", func() {
+				So(err, ShouldBeNil)
+				So(token, ShouldEqual, "token-1")
+			})
+		})
+
+		Convey("When I call Run", func() {
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			tokenCh := make(chan string)
+			go tm.Run(ctx, tokenCh)
+
+			var token string
+			select {
+			case token = <-tokenCh:
+			case <-time.After(time.Second):
+			}
+
+			Convey("Then the renewed token should be published", func() {
+				So(token, ShouldEqual, "token-1")
+			})
+
+			Convey("Then the failed renewal should be skipped", func() {
+				select {
+				case token = <-tokenCh:
+				case <-time.After(time.Second):
+				}
+				So(token, ShouldEqual, "token-3")
+			})
+		})
+	})
+}